@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	internal "github.com/natretsel/chirpy/internal/auth"
+	"github.com/natretsel/chirpy/internal/database"
+)
+
+const (
+	// tokenCutoffTTL is how long a user's tokens-valid-after cutoff is
+	// cached. Revocations made by another server instance take up to
+	// this long to reach this one.
+	tokenCutoffTTL = 30 * time.Second
+	// maxTokenCutoffs bounds the cache; once full, stale entries are
+	// dropped, and if that isn't enough the cache starts over.
+	maxTokenCutoffs = 10000
+)
+
+var errAccessTokenRevoked = errors.New("access token revoked")
+
+type tokenCutoff struct {
+	// validAfter is zero if the user never revoked their tokens
+	validAfter time.Time
+	fetchedAt  time.Time
+}
+
+// tokenCutoffs caches each user's tokens-valid-after cutoff so checking
+// an access token doesn't cost a query on every request.
+type tokenCutoffs struct {
+	mu      sync.Mutex
+	entries map[uuid.UUID]tokenCutoff
+}
+
+func newTokenCutoffs() *tokenCutoffs {
+	return &tokenCutoffs{entries: map[uuid.UUID]tokenCutoff{}}
+}
+
+// get returns the user's cutoff, from the cache while it is fresh.
+func (c *tokenCutoffs) get(ctx context.Context, store database.Store, userID uuid.UUID) (time.Time, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[userID]
+	c.mu.Unlock()
+	if ok && time.Since(entry.fetchedAt) < tokenCutoffTTL {
+		return entry.validAfter, nil
+	}
+	validAfter, err := store.GetTokensValidAfter(ctx, userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	c.set(userID, validAfter.Time)
+	return validAfter.Time, nil
+}
+
+// set caches the user's cutoff. A fresh entry with a later cutoff is
+// kept, so a read that started before a revocation can't undo it.
+func (c *tokenCutoffs) set(userID uuid.UUID, validAfter time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.entries[userID]; ok && time.Since(entry.fetchedAt) < tokenCutoffTTL && entry.validAfter.After(validAfter) {
+		return
+	}
+	if len(c.entries) >= maxTokenCutoffs {
+		for id, entry := range c.entries {
+			if time.Since(entry.fetchedAt) >= tokenCutoffTTL {
+				delete(c.entries, id)
+			}
+		}
+		if len(c.entries) >= maxTokenCutoffs {
+			clear(c.entries)
+		}
+	}
+	c.entries[userID] = tokenCutoff{validAfter: validAfter, fetchedAt: time.Now()}
+}
+
+type sessionState struct {
+	revoked   bool
+	fetchedAt time.Time
+}
+
+// sessionStates caches whether sessions were signed out, so that an
+// access token stops working along with the session it was issued
+// under. A signed out session never comes back, so that is kept until
+// the cache fills up.
+type sessionStates struct {
+	mu      sync.Mutex
+	entries map[uuid.UUID]sessionState
+}
+
+func newSessionStates() *sessionStates {
+	return &sessionStates{entries: map[uuid.UUID]sessionState{}}
+}
+
+// revoked reports whether the session was signed out, from the cache
+// while it is fresh.
+func (c *sessionStates) revoked(ctx context.Context, store database.Store, sessionID uuid.UUID) (bool, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[sessionID]
+	c.mu.Unlock()
+	if ok && (entry.revoked || time.Since(entry.fetchedAt) < tokenCutoffTTL) {
+		return entry.revoked, nil
+	}
+	revoked, err := store.IsSessionRevoked(ctx, sessionID)
+	if err != nil {
+		return false, err
+	}
+	c.set(sessionID, revoked)
+	return revoked, nil
+}
+
+// set caches the session's state. A session cached as signed out stays
+// that way, so a read that started before it was can't undo it.
+func (c *sessionStates) set(sessionID uuid.UUID, revoked bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries[sessionID].revoked {
+		return
+	}
+	if len(c.entries) >= maxTokenCutoffs {
+		for id, entry := range c.entries {
+			if !entry.revoked && time.Since(entry.fetchedAt) >= tokenCutoffTTL {
+				delete(c.entries, id)
+			}
+		}
+		if len(c.entries) >= maxTokenCutoffs {
+			clear(c.entries)
+		}
+	}
+	c.entries[sessionID] = sessionState{revoked: revoked, fetchedAt: time.Now()}
+}
+
+// parseAccessToken validates an access token and checks that it wasn't
+// issued before its user's tokens were revoked, nor under a session that
+// was signed out since.
+func (cfg *apiConfig) parseAccessToken(ctx context.Context, token string) (internal.Claims, error) {
+	claims, err := internal.ParseJWT(token, cfg.keys)
+	if err != nil {
+		return internal.Claims{}, err
+	}
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return internal.Claims{}, err
+	}
+	validAfter, err := cfg.tokenCutoffs.get(ctx, cfg.dbQueries, userID)
+	if err != nil {
+		return internal.Claims{}, err
+	}
+	if claims.IssuedAt == nil || !issuedAfterCutoff(claims.IssuedAt.Time, validAfter) {
+		return internal.Claims{}, errAccessTokenRevoked
+	}
+	if sessionID := claims.Session(); sessionID != uuid.Nil {
+		revoked, err := cfg.sessions.revoked(ctx, cfg.dbQueries, sessionID)
+		if err != nil {
+			return internal.Claims{}, err
+		}
+		if revoked {
+			return internal.Claims{}, errAccessTokenRevoked
+		}
+	}
+	return claims, nil
+}
+
+// issuedAfterCutoff reports whether a token issued at issuedAt survives
+// a revocation at validAfter. Issue times are whole milliseconds, so
+// both are compared at that precision, and a token from the same
+// millisecond as the revocation is taken to be from after it.
+func issuedAfterCutoff(issuedAt, validAfter time.Time) bool {
+	return !issuedAt.Truncate(time.Millisecond).Before(validAfter.Truncate(time.Millisecond))
+}
+
+// revokeOtherSessions signs out every session of the user but keep,
+// along with the access tokens issued under them.
+func (cfg *apiConfig) revokeOtherSessions(ctx context.Context, store database.Store, userID, keep uuid.UUID) error {
+	sessionIDs, err := store.RevokeOtherUserSessions(ctx, database.RevokeOtherUserSessionsParams{
+		UserID:   userID,
+		FamilyID: keep,
+	})
+	if err != nil {
+		return err
+	}
+	for _, id := range sessionIDs {
+		cfg.sessions.set(id, true)
+	}
+	return nil
+}
+
+// validateAccessToken is parseAccessToken for callers that only need
+// the user's ID.
+func (cfg *apiConfig) validateAccessToken(ctx context.Context, token string) (uuid.UUID, error) {
+	claims, err := cfg.parseAccessToken(ctx, token)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return uuid.Parse(claims.Subject)
+}
+
+// revokeAccessTokens rejects every access token issued to the user so
+// far. They can get new ones by logging in or refreshing.
+func (cfg *apiConfig) revokeAccessTokens(ctx context.Context, store database.Store, userID uuid.UUID) error {
+	now := time.Now().UTC()
+	err := store.RevokeUserAccessTokens(ctx, database.RevokeUserAccessTokensParams{
+		ID:               userID,
+		TokensValidAfter: sql.NullTime{Time: now, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	cfg.tokenCutoffs.set(userID, now)
+	return nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	internal "github.com/natretsel/chirpy/internal/auth"
+	"github.com/natretsel/chirpy/internal/database"
+)
+
+func TestAccessTokenRevokedInSameMillisecond(t *testing.T) {
+	cfg, srv := newTestServer(t)
+	user := createUser(t, srv, "same-ms@example.com", "pw")
+	claims, err := internal.ParseJWT(user.Token, cfg.keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuedAt := claims.IssuedAt.Time
+	userID := uuid.MustParse(claims.Subject)
+
+	// a revocation later in the millisecond the token was issued in
+	// can't be told apart from one before it, so the token survives
+	cfg.tokenCutoffs.set(userID, issuedAt.Add(999*time.Microsecond))
+	if _, err := cfg.parseAccessToken(context.Background(), user.Token); err != nil {
+		t.Errorf("token from the revocation's millisecond: %v", err)
+	}
+	cfg.tokenCutoffs.set(userID, issuedAt.Add(time.Millisecond))
+	if _, err := cfg.parseAccessToken(context.Background(), user.Token); !errors.Is(err, errAccessTokenRevoked) {
+		t.Errorf("token from the millisecond before the revocation: %v, want %v", err, errAccessTokenRevoked)
+	}
+}
+
+func TestTokenCutoffsKeepLaterCutoff(t *testing.T) {
+	cutoffs := newTokenCutoffs()
+	store := database.NewMemoryStore()
+	userID := uuid.New()
+	revokedAt := time.Now().UTC()
+
+	// a lookup that read the cutoff before the revocation finishes
+	// after it
+	cutoffs.set(userID, revokedAt)
+	cutoffs.set(userID, time.Time{})
+	got, err := cutoffs.get(context.Background(), store, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(revokedAt) {
+		t.Errorf("cutoff = %v, want %v", got, revokedAt)
+	}
+	later := revokedAt.Add(time.Second)
+	cutoffs.set(userID, later)
+	if got, _ := cutoffs.get(context.Background(), store, userID); !got.Equal(later) {
+		t.Errorf("cutoff = %v, want %v", got, later)
+	}
+}
+
+func TestSignOutRevokesAccessTokens(t *testing.T) {
+	_, srv := newTestServer(t)
+	user := createUser(t, srv, "sign-out@example.com", "pw")
+	login := func() loginResponse {
+		t.Helper()
+		resp, body := doRequest(t, srv, http.MethodPost, "/api/login", "", map[string]string{"email": "sign-out@example.com", "password": "pw"})
+		expectStatus(t, resp, body, http.StatusOK)
+		return decodeBody[loginResponse](t, body)
+	}
+	timeline := func(token string, want int) {
+		t.Helper()
+		resp, body := doRequest(t, srv, http.MethodGet, "/api/timeline", bearer(token), nil)
+		expectStatus(t, resp, body, want)
+	}
+
+	// rotating a refresh token leaves its session signed in
+	resp, body := doRequest(t, srv, http.MethodPost, "/api/refresh", bearer(user.RefreshToken), nil)
+	expectStatus(t, resp, body, http.StatusOK)
+	refreshed := decodeBody[loginResponse](t, body)
+	timeline(user.Token, http.StatusOK)
+	timeline(refreshed.Token, http.StatusOK)
+
+	other := login()
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/revoke", bearer(refreshed.RefreshToken), nil)
+	expectStatus(t, resp, body, http.StatusNoContent)
+	timeline(user.Token, http.StatusUnauthorized)
+	timeline(refreshed.Token, http.StatusUnauthorized)
+	timeline(other.Token, http.StatusOK)
+
+	// so does signing out one session from another
+	third := login()
+	resp, body = doRequest(t, srv, http.MethodGet, "/api/sessions", bearer(third.Token), nil)
+	expectStatus(t, resp, body, http.StatusOK)
+	for _, session := range decodeBody[struct {
+		Sessions []Session `json:"sessions"`
+	}](t, body).Sessions {
+		if session.Current {
+			continue
+		}
+		resp, body = doRequest(t, srv, http.MethodDelete, "/api/sessions/"+session.ID.String(), bearer(third.Token), nil)
+		expectStatus(t, resp, body, http.StatusNoContent)
+	}
+	timeline(other.Token, http.StatusUnauthorized)
+	timeline(third.Token, http.StatusOK)
+}
@@ -0,0 +1,54 @@
+// Package chirplen counts the length of a chirp the way the server
+// enforces its limit. Clients should count with the same rules, and
+// can check their implementation against testdata/vectors.json.
+//
+// Length is measured in user-perceived characters (extended grapheme
+// clusters, as defined by Unicode 15.0 UAX #29), so an emoji or a letter
+// with combining accents counts once however many bytes or code points
+// it takes. Each URL counts as URLLength, whatever its actual length.
+// A URL starts with http:// or https:// and runs to the next
+// whitespace, not counting trailing punctuation.
+//
+// Since neither rule is bounded in bytes, a chirp may also take at most
+// MaxBytes bytes of UTF-8, and no URL in it more than MaxURLBytes.
+package chirplen
+
+import (
+	"regexp"
+
+	"github.com/rivo/uniseg"
+)
+
+const (
+	// MaxLength is the most characters a chirp may have.
+	MaxLength = 140
+	// URLLength is what each URL counts for.
+	URLLength = 23
+	// MaxBytes is the most bytes a chirp may take, enough for a full
+	// chirp of emoji with skin tones or flags, at 8 bytes each.
+	MaxBytes = 8 * MaxLength
+	// MaxURLBytes is the most bytes a URL may take.
+	MaxURLBytes = 512
+)
+
+var urlPattern = regexp.MustCompile(`(?i)\bhttps?://[^\s<>"]*[^\s<>".,:;!?'")\]}]`)
+
+// Count returns the length of body.
+func Count(body string) int {
+	n, last := 0, 0
+	for _, loc := range urlPattern.FindAllStringIndex(body, -1) {
+		n += uniseg.GraphemeClusterCount(body[last:loc[0]]) + URLLength
+		last = loc[1]
+	}
+	return n + uniseg.GraphemeClusterCount(body[last:])
+}
+
+// LongestURL returns the length in bytes of the longest URL in body, or
+// 0 if there are none.
+func LongestURL(body string) int {
+	longest := 0
+	for _, loc := range urlPattern.FindAllStringIndex(body, -1) {
+		longest = max(longest, loc[1]-loc[0])
+	}
+	return longest
+}
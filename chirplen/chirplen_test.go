@@ -0,0 +1,50 @@
+package chirplen
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCountVectors(t *testing.T) {
+	data, err := os.ReadFile("testdata/vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vectors []struct {
+		Name   string `json:"name"`
+		Body   string `json:"body"`
+		Length int    `json:"length"`
+	}
+	err = json.Unmarshal(data, &vectors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range vectors {
+		if got := Count(v.Body); got != v.Length {
+			t.Errorf("%s: Count(%q) = %d, want %d", v.Name, v.Body, got, v.Length)
+		}
+	}
+}
+
+func TestCountLimit(t *testing.T) {
+	// a full chirp of emoji fits, one more doesn't
+	body := strings.Repeat("\U0001F426", MaxLength)
+	if got := Count(body); got != MaxLength {
+		t.Errorf("Count of %d emoji = %d", MaxLength, got)
+	}
+	if got := Count(body + "!"); got <= MaxLength {
+		t.Errorf("Count = %d, want over the limit", got)
+	}
+}
+
+func TestLongestURL(t *testing.T) {
+	long := "https://example.com/" + strings.Repeat("a", 100)
+	if got := LongestURL("see " + long + ", or https://example.com."); got != len(long) {
+		t.Errorf("LongestURL = %d, want %d", got, len(long))
+	}
+	if got := LongestURL("no links here"); got != 0 {
+		t.Errorf("LongestURL without URLs = %d", got)
+	}
+}
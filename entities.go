@@ -0,0 +1,73 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const maxHandleLength = 15
+
+var handlePattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// parsedEntity is a hashtag or mention found in a chirp body. Text is
+// the normalised tag or handle without its # or @, and Start and End
+// are character offsets into the body, End exclusive.
+type parsedEntity struct {
+	Text  string
+	Start int
+	End   int
+}
+
+// parseEntities finds the #hashtags and @handles in body. Either sigil
+// only counts at the start of a word, so emails and URL fragments are
+// left alone. Offsets count Unicode code points.
+func parseEntities(body string) (tags, mentions []parsedEntity) {
+	runes := []rune(body)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '#' && runes[i] != '@' {
+			continue
+		}
+		if i > 0 && isTagRune(runes[i-1]) {
+			continue
+		}
+		end := i + 1
+		for end < len(runes) && isTagRune(runes[end]) {
+			end++
+		}
+		if end == i+1 {
+			continue
+		}
+		text := strings.ToLower(string(runes[i+1 : end]))
+		entity := parsedEntity{Text: text, Start: i, End: end}
+		switch {
+		case runes[i] == '#' && strings.ContainsFunc(text, unicode.IsLetter):
+			tags = append(tags, entity)
+		case runes[i] == '@' && validHandle(text):
+			mentions = append(mentions, entity)
+		}
+		i = end - 1
+	}
+	return tags, mentions
+}
+
+func isTagRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+}
+
+// validHandle reports whether handle, already lowercased, is a usable
+// @handle: up to 15 ASCII letters, digits or underscores.
+func validHandle(handle string) bool {
+	return len(handle) <= maxHandleLength && handlePattern.MatchString(handle)
+}
+
+// normaliseTag turns a tag as written in a path or body, with or
+// without its #, into the form stored in chirp_tags.
+func normaliseTag(tag string) (string, bool) {
+	tag = "#" + strings.TrimPrefix(tag, "#")
+	tags, _ := parseEntities(tag)
+	if len(tags) != 1 || tags[0].End != len([]rune(tag)) {
+		return "", false
+	}
+	return tags[0].Text, true
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/natretsel/chirpy/internal/database"
+)
+
+// ChirpEntities are the hashtags and mentions in a chirp body, with
+// character offsets so clients can link them without parsing the body.
+type ChirpEntities struct {
+	Hashtags []HashtagEntity `json:"hashtags"`
+	Mentions []MentionEntity `json:"mentions"`
+}
+
+type HashtagEntity struct {
+	Tag   string `json:"tag"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+type MentionEntity struct {
+	UserID uuid.UUID `json:"user_id"`
+	Handle string    `json:"handle"`
+	Start  int       `json:"start"`
+	End    int       `json:"end"`
+}
+
+func newChirpEntities() ChirpEntities {
+	return ChirpEntities{Hashtags: []HashtagEntity{}, Mentions: []MentionEntity{}}
+}
+
+// saveChirpEntities records the hashtags and mentions in a new chirp's
+// body. Mentions of handles no user has are left as plain text.
+func saveChirpEntities(ctx context.Context, store database.Store, chirp database.Chirp) error {
+	tags, mentions := parseEntities(chirp.Body)
+	for _, tag := range tags {
+		err := store.AddChirpTag(ctx, database.AddChirpTagParams{
+			ChirpID:     chirp.ID,
+			Tag:         tag.Text,
+			StartOffset: int32(tag.Start),
+			EndOffset:   int32(tag.End),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if len(mentions) == 0 {
+		return nil
+	}
+	handles := make([]string, 0, len(mentions))
+	for _, mention := range mentions {
+		handles = append(handles, mention.Text)
+	}
+	users, err := store.GetUsersByHandles(ctx, slices.Compact(slices.Sorted(slices.Values(handles))))
+	if err != nil {
+		return err
+	}
+	userIDs := make(map[string]uuid.UUID, len(users))
+	for _, user := range users {
+		userIDs[user.Handle.String] = user.ID
+	}
+	for _, mention := range mentions {
+		userID, ok := userIDs[mention.Text]
+		if !ok {
+			continue
+		}
+		err := store.AddChirpMention(ctx, database.AddChirpMentionParams{
+			ChirpID:     chirp.ID,
+			UserID:      userID,
+			StartOffset: int32(mention.Start),
+			EndOffset:   int32(mention.End),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attachEntities fills in the stored hashtags and mentions of chirps.
+func (cfg *apiConfig) attachEntities(ctx context.Context, chirps []*Chirp) error {
+	ids := make([]uuid.UUID, 0, len(chirps))
+	byID := make(map[uuid.UUID][]*Chirp, len(chirps))
+	for _, c := range chirps {
+		c.Entities = newChirpEntities()
+		ids = append(ids, c.ID)
+		byID[c.ID] = append(byID[c.ID], c)
+	}
+	tags, err := cfg.dbQueries.GetChirpTags(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		for _, c := range byID[tag.ChirpID] {
+			c.Entities.Hashtags = append(c.Entities.Hashtags, HashtagEntity{
+				Tag:   tag.Tag,
+				Start: int(tag.StartOffset),
+				End:   int(tag.EndOffset),
+			})
+		}
+	}
+	mentions, err := cfg.dbQueries.GetChirpMentions(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, mention := range mentions {
+		for _, c := range byID[mention.ChirpID] {
+			// the handle as written, which is what the offsets cover
+			body := []rune(c.Body)
+			handle := ""
+			if int(mention.EndOffset) <= len(body) {
+				handle = strings.ToLower(string(body[mention.StartOffset+1 : mention.EndOffset]))
+			}
+			c.Entities.Mentions = append(c.Entities.Mentions, MentionEntity{
+				UserID: mention.UserID,
+				Handle: handle,
+				Start:  int(mention.StartOffset),
+				End:    int(mention.EndOffset),
+			})
+		}
+	}
+	return nil
+}
+
+func (cfg *apiConfig) handlerTagChirps(w http.ResponseWriter, r *http.Request) {
+	tag, ok := normaliseTag(r.PathValue("tag"))
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, "invalid tag", nil)
+		return
+	}
+	limit, cursor, err := parsePage(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	// fetch one extra row to learn whether another page follows
+	params := database.ListChirpsByTagParams{
+		Tag:   tag,
+		Limit: int32(limit + 1),
+	}
+	if cursor != nil {
+		params.BeforeCreatedAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+		params.BeforeID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+	chirps, err := cfg.dbQueries.ListChirpsByTag(r.Context(), params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't list tagged chirps", err)
+		return
+	}
+	cfg.respondWithChirps(w, r, chirps, limit)
+}
+
+func (cfg *apiConfig) handlerUserMentions(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.pathUser(w, r)
+	if !ok {
+		return
+	}
+	limit, cursor, err := parsePage(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	// fetch one extra row to learn whether another page follows
+	params := database.ListMentionsParams{
+		UserID: user.ID,
+		Limit:  int32(limit + 1),
+	}
+	if cursor != nil {
+		params.BeforeCreatedAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+		params.BeforeID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+	chirps, err := cfg.dbQueries.ListMentions(r.Context(), params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't list mentions", err)
+		return
+	}
+	cfg.respondWithChirps(w, r, chirps, limit)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestParseEntities(t *testing.T) {
+	cases := []struct {
+		body     string
+		tags     []parsedEntity
+		mentions []parsedEntity
+	}{
+		{body: "no entities here"},
+		{
+			body:     "#Go and @Bob_1!",
+			tags:     []parsedEntity{{Text: "go", Start: 0, End: 3}},
+			mentions: []parsedEntity{{Text: "bob_1", Start: 8, End: 14}},
+		},
+		{
+			// offsets count characters, not bytes
+			body: "café #naïve",
+			tags: []parsedEntity{{Text: "naïve", Start: 5, End: 11}},
+		},
+		{body: "mail me@example.com or see page#anchor"},
+		{body: "just #123 and a lone # or @"},
+		{body: "@waytoolongtobeahandle"},
+		{
+			body:     "#a#b @x,@y",
+			tags:     []parsedEntity{{Text: "a", Start: 0, End: 2}},
+			mentions: []parsedEntity{{Text: "x", Start: 5, End: 7}, {Text: "y", Start: 8, End: 10}},
+		},
+	}
+	for _, c := range cases {
+		tags, mentions := parseEntities(c.body)
+		if !reflect.DeepEqual(tags, c.tags) || !reflect.DeepEqual(mentions, c.mentions) {
+			t.Errorf("parseEntities(%q) = %v, %v; want %v, %v", c.body, tags, mentions, c.tags, c.mentions)
+		}
+	}
+}
+
+func TestHashtagsAndMentions(t *testing.T) {
+	_, srv := newTestServer(t)
+	signup := func(email, handle string) loginResponse {
+		t.Helper()
+		creds := map[string]string{"email": email, "password": "pw", "handle": handle}
+		resp, body := doRequest(t, srv, http.MethodPost, "/api/users", "", creds)
+		expectStatus(t, resp, body, http.StatusCreated)
+		resp, body = doRequest(t, srv, http.MethodPost, "/api/login", "", creds)
+		expectStatus(t, resp, body, http.StatusOK)
+		return decodeBody[loginResponse](t, body)
+	}
+	alice := signup("alice-entities@example.com", "Alice")
+	bob := signup("bob-entities@example.com", "bob")
+	if alice.Handle == nil || *alice.Handle != "alice" {
+		t.Fatalf("alice's handle = %v, want alice", alice.Handle)
+	}
+	resp, body := doRequest(t, srv, http.MethodPost, "/api/users", "", map[string]string{
+		"email": "bad-handle@example.com", "password": "pw", "handle": "not a handle",
+	})
+	expectStatus(t, resp, body, http.StatusBadRequest)
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/users", "", map[string]string{
+		"email": "taken-handle@example.com", "password": "pw", "handle": "BOB",
+	})
+	expectStatus(t, resp, body, http.StatusBadRequest)
+
+	chirp := createChirp(t, srv, alice.Token, "hi @bob and @nobody #GoLang #go")
+	wantTags := []HashtagEntity{{Tag: "golang", Start: 20, End: 27}, {Tag: "go", Start: 28, End: 31}}
+	wantMentions := []MentionEntity{{UserID: bob.ID, Handle: "bob", Start: 3, End: 7}}
+	if !reflect.DeepEqual(chirp.Entities.Hashtags, wantTags) || !reflect.DeepEqual(chirp.Entities.Mentions, wantMentions) {
+		t.Errorf("entities = %+v, want hashtags %+v and mentions %+v", chirp.Entities, wantTags, wantMentions)
+	}
+	createChirp(t, srv, bob.Token, "more #golang")
+	createChirp(t, srv, bob.Token, "nothing tagged")
+
+	listChirps := func(path string, want int) []Chirp {
+		t.Helper()
+		resp, body := doRequest(t, srv, http.MethodGet, path, "", nil)
+		expectStatus(t, resp, body, want)
+		if want != http.StatusOK {
+			return nil
+		}
+		return decodeBody[chirpsPage](t, body).Chirps
+	}
+	if got := listChirps("/api/tags/GoLang/chirps", http.StatusOK); len(got) != 2 || got[1].ID != chirp.ID {
+		t.Errorf("#golang chirps = %+v, want 2 with alice's last", got)
+	}
+	if got := listChirps("/api/tags/%23go/chirps", http.StatusOK); len(got) != 1 || got[0].ID != chirp.ID {
+		t.Errorf("#go chirps = %+v, want only alice's", got)
+	}
+	listChirps("/api/tags/not-a-tag/chirps", http.StatusBadRequest)
+
+	mentions := listChirps("/api/users/"+bob.ID.String()+"/mentions", http.StatusOK)
+	if len(mentions) != 1 || mentions[0].ID != chirp.ID {
+		t.Errorf("bob's mentions = %+v, want alice's chirp", mentions)
+	}
+	if got := listChirps("/api/users/"+alice.ID.String()+"/mentions", http.StatusOK); len(got) != 0 {
+		t.Errorf("alice's mentions = %+v, want none", got)
+	}
+
+	resp, body = doRequest(t, srv, http.MethodDelete, "/api/chirps/"+chirp.ID.String(), bearer(alice.Token), nil)
+	expectStatus(t, resp, body, http.StatusNoContent)
+	if got := listChirps("/api/users/"+bob.ID.String()+"/mentions", http.StatusOK); len(got) != 0 {
+		t.Errorf("bob's mentions after delete = %+v, want none", got)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/natretsel/chirpy/internal/database"
+)
+
+const (
+	defaultThreadDepth = 5
+	maxThreadDepth     = 10
+	// maxThreadReplies caps how many descendants one thread request loads
+	maxThreadReplies = 500
+)
+
+// ThreadChirp is a chirp placed in its conversation. A deleted chirp,
+// whether in its author's trash or a tombstone left when a chirp with
+// replies is purged, has Deleted set and a nil embedded Chirp, so only
+// its ID and parent are shown. Chirps held for review, and those of an
+// author serving a suspension that hides their chirps, are shown the
+// same way.
+type ThreadChirp struct {
+	ID        uuid.UUID  `json:"id"`
+	InReplyTo *uuid.UUID `json:"in_reply_to"`
+	Deleted   bool       `json:"deleted"`
+	*Chirp
+	Replies []*ThreadChirp `json:"replies"`
+}
+
+type threadResponse struct {
+	Ancestors []*ThreadChirp `json:"ancestors"`
+	Chirp     *ThreadChirp   `json:"chirp"`
+}
+
+func threadChirpFromDB(chirp database.Chirp, authorHidden bool) *ThreadChirp {
+	c := chirpFromDB(chirp)
+	node := &ThreadChirp{
+		ID:        c.ID,
+		InReplyTo: c.InReplyTo,
+		Replies:   []*ThreadChirp{},
+	}
+	if chirp.TombstonedAt.Valid || chirp.DeletedAt.Valid || chirp.HeldAt.Valid || authorHidden {
+		node.Deleted = true
+	} else {
+		node.Chirp = &c
+	}
+	return node
+}
+
+func (cfg *apiConfig) handlerChirpThread(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid Chirp ID", err)
+		return
+	}
+	depth := defaultThreadDepth
+	if depthStr := r.URL.Query().Get("depth"); depthStr != "" {
+		depth, err = strconv.Atoi(depthStr)
+		if err != nil || depth < 0 {
+			respondWithError(w, http.StatusBadRequest, "depth must be a non-negative integer", err)
+			return
+		}
+		depth = min(depth, maxThreadDepth)
+	}
+
+	chirp, err := cfg.dbQueries.GetThreadChirpByID(r.Context(), chirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Couldn't get chirp", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get chirp", err)
+		return
+	}
+	ancestors, err := cfg.dbQueries.GetChirpAncestors(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get thread ancestors", err)
+		return
+	}
+	descendants := []database.GetChirpDescendantsRow{}
+	if depth > 0 {
+		descendants, err = cfg.dbQueries.GetChirpDescendants(r.Context(), database.GetChirpDescendantsParams{
+			ID:       chirpID,
+			MaxDepth: int32(depth),
+			RowLimit: maxThreadReplies,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't get thread replies", err)
+			return
+		}
+	}
+
+	resp := threadResponse{
+		Ancestors: []*ThreadChirp{},
+		Chirp:     threadChirpFromDB(chirp.Chirp, chirp.AuthorHidden),
+	}
+	visible := []*Chirp{}
+	if resp.Chirp.Chirp != nil {
+		visible = append(visible, resp.Chirp.Chirp)
+	}
+	for _, row := range ancestors {
+		node := threadChirpFromDB(row.Chirp, row.AuthorHidden)
+		if node.Chirp != nil {
+			visible = append(visible, node.Chirp)
+		}
+		resp.Ancestors = append(resp.Ancestors, node)
+	}
+	// descendants arrive breadth first, so every parent is placed
+	// before its replies
+	nodes := map[uuid.UUID]*ThreadChirp{chirpID: resp.Chirp}
+	for _, row := range descendants {
+		parent, ok := nodes[row.Chirp.InReplyTo.UUID]
+		if !ok {
+			continue
+		}
+		node := threadChirpFromDB(row.Chirp, row.AuthorHidden)
+		if node.Chirp != nil {
+			visible = append(visible, node.Chirp)
+		}
+		parent.Replies = append(parent.Replies, node)
+		nodes[node.ID] = node
+	}
+
+	err = cfg.hydrateChirps(r.Context(), cfg.viewerID(r), visible...)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't load chirp details", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
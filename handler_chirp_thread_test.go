@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/natretsel/chirpy/internal/database"
+)
+
+func TestChirpThread(t *testing.T) {
+	cfg, srv := newTestServer(t)
+	alice := createUser(t, srv, "alice-threads@example.com", "pw")
+	bob := createUser(t, srv, "bob-threads@example.com", "pw")
+	reply := func(token string, parent uuid.UUID, text string) Chirp {
+		t.Helper()
+		resp, body := doRequest(t, srv, http.MethodPost, "/api/chirps", bearer(token), map[string]any{"body": text, "in_reply_to": parent})
+		expectStatus(t, resp, body, http.StatusCreated)
+		return decodeBody[Chirp](t, body)
+	}
+	getThread := func(id uuid.UUID, query string) threadResponse {
+		t.Helper()
+		resp, body := doRequest(t, srv, http.MethodGet, "/api/chirps/"+id.String()+"/thread"+query, "", nil)
+		expectStatus(t, resp, body, http.StatusOK)
+		return decodeBody[threadResponse](t, body)
+	}
+
+	root := createChirp(t, srv, alice.Token, "root")
+	first := reply(bob.Token, root.ID, "first reply")
+	nested := reply(alice.Token, first.ID, "nested reply")
+	deepest := reply(bob.Token, nested.ID, "deepest reply")
+	second := reply(alice.Token, root.ID, "second reply")
+	if first.InReplyTo == nil || *first.InReplyTo != root.ID {
+		t.Fatalf("reply in_reply_to = %v, want %v", first.InReplyTo, root.ID)
+	}
+
+	thread := getThread(root.ID, "")
+	if len(thread.Ancestors) != 0 || thread.Chirp.Body != "root" {
+		t.Fatalf("root thread = %+v", thread)
+	}
+	if got := thread.Chirp.Replies; len(got) != 2 || got[0].ID != first.ID || got[1].ID != second.ID {
+		t.Fatalf("root replies = %+v", got)
+	}
+	if got := thread.Chirp.Replies[0].Replies[0].Replies; len(got) != 1 || got[0].ID != deepest.ID {
+		t.Errorf("deepest replies = %+v", got)
+	}
+	if got := getThread(root.ID, "?depth=1").Chirp.Replies[0].Replies; len(got) != 0 {
+		t.Errorf("depth=1 returned nested replies %+v", got)
+	}
+
+	// deleting a chirp with replies leaves a tombstone in its place
+	resp, body := doRequest(t, srv, http.MethodDelete, "/api/chirps/"+first.ID.String(), bearer(bob.Token), nil)
+	expectStatus(t, resp, body, http.StatusNoContent)
+	resp, body = doRequest(t, srv, http.MethodGet, "/api/chirps/"+first.ID.String(), "", nil)
+	expectStatus(t, resp, body, http.StatusNotFound)
+
+	thread = getThread(nested.ID, "")
+	if len(thread.Ancestors) != 2 || thread.Ancestors[0].ID != root.ID || thread.Ancestors[1].ID != first.ID {
+		t.Fatalf("ancestors = %+v", thread.Ancestors)
+	}
+	if tomb := thread.Ancestors[1]; !tomb.Deleted || tomb.Chirp != nil {
+		t.Errorf("deleted ancestor = %+v, want a tombstone", tomb)
+	}
+	if thread.Ancestors[0].Deleted || thread.Ancestors[0].Chirp == nil {
+		t.Errorf("root ancestor = %+v, want a full chirp", thread.Ancestors[0])
+	}
+
+	resp, body = doRequest(t, srv, http.MethodGet, "/api/chirps", "", nil)
+	expectStatus(t, resp, body, http.StatusOK)
+	for _, c := range decodeBody[chirpsPage](t, body).Chirps {
+		if c.ID == first.ID {
+			t.Error("tombstone listed by GET /api/chirps")
+		}
+	}
+
+	// chirps without replies stay in the trash until purged, then are
+	// removed outright
+	resp, body = doRequest(t, srv, http.MethodDelete, "/api/chirps/"+deepest.ID.String(), bearer(bob.Token), nil)
+	expectStatus(t, resp, body, http.StatusNoContent)
+	if trashed := getThread(deepest.ID, "").Chirp; !trashed.Deleted || trashed.Chirp != nil {
+		t.Errorf("trashed chirp in its thread = %+v, want it shown as deleted", trashed)
+	}
+	cfg.trashRetention = 0
+	if _, err := cfg.purgeExpiredTrash(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	resp, body = doRequest(t, srv, http.MethodGet, "/api/chirps/"+deepest.ID.String()+"/thread", "", nil)
+	expectStatus(t, resp, body, http.StatusNotFound)
+
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/chirps", bearer(alice.Token), map[string]any{"body": "orphan", "in_reply_to": uuid.New()})
+	expectStatus(t, resp, body, http.StatusBadRequest)
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/chirps", bearer(alice.Token), map[string]any{"body": "reply to a tombstone", "in_reply_to": first.ID})
+	expectStatus(t, resp, body, http.StatusBadRequest)
+	resp, body = doRequest(t, srv, http.MethodGet, "/api/chirps/"+root.ID.String()+"/thread?depth=-1", "", nil)
+	expectStatus(t, resp, body, http.StatusBadRequest)
+}
+
+func TestChirpThreadHidesSuspendedAuthors(t *testing.T) {
+	cfg, srv := newTestServer(t)
+	alice := createUser(t, srv, "alice-hidden@example.com", "pw")
+	bob := createUser(t, srv, "bob-hidden@example.com", "pw")
+	root := createChirp(t, srv, alice.Token, "root")
+	resp, body := doRequest(t, srv, http.MethodPost, "/api/chirps", bearer(bob.Token), map[string]any{"body": "bob's reply", "in_reply_to": root.ID})
+	expectStatus(t, resp, body, http.StatusCreated)
+	reply := decodeBody[Chirp](t, body)
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/chirps", bearer(alice.Token), map[string]any{"body": "alice again", "in_reply_to": reply.ID})
+	expectStatus(t, resp, body, http.StatusCreated)
+	nested := decodeBody[Chirp](t, body)
+
+	_, err := cfg.dbQueries.SuspendUser(context.Background(), database.SuspendUserParams{
+		ID:                    bob.ID,
+		SuspensionReason:      "spam",
+		SuspensionHidesChirps: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	getThread := func(id uuid.UUID) threadResponse {
+		t.Helper()
+		resp, body := doRequest(t, srv, http.MethodGet, "/api/chirps/"+id.String()+"/thread", "", nil)
+		expectStatus(t, resp, body, http.StatusOK)
+		return decodeBody[threadResponse](t, body)
+	}
+	if got := getThread(root.ID).Chirp.Replies; len(got) != 1 || !got[0].Deleted || got[0].Chirp != nil {
+		t.Errorf("hidden reply = %+v, want a placeholder", got)
+	} else if len(got[0].Replies) != 1 || got[0].Replies[0].Chirp == nil {
+		t.Errorf("replies below a hidden chirp = %+v, want them shown", got[0].Replies)
+	}
+	if got := getThread(nested.ID).Ancestors; len(got) != 2 || !got[1].Deleted || got[1].Chirp != nil {
+		t.Errorf("hidden ancestor = %+v, want a placeholder", got)
+	}
+	if got := getThread(reply.ID).Chirp; !got.Deleted || got.Chirp != nil {
+		t.Errorf("hidden chirp = %+v, want a placeholder", got)
+	}
+
+	// a suspension that leaves chirps up shows them in full
+	_, err = cfg.dbQueries.SuspendUser(context.Background(), database.SuspendUserParams{
+		ID:               bob.ID,
+		SuspensionReason: "spam",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := getThread(reply.ID).Chirp; got.Deleted || got.Chirp == nil || got.Body != "bob's reply" {
+		t.Errorf("chirp of a suspended author = %+v, want it shown", got)
+	}
+}
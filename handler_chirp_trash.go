@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	internal "github.com/natretsel/chirpy/internal/auth"
+	"github.com/natretsel/chirpy/internal/database"
+)
+
+const (
+	// defaultTrashRetentionDays is how long deleted chirps can be
+	// restored, unless TRASH_RETENTION_DAYS says otherwise.
+	defaultTrashRetentionDays = 30
+	trashPurgeInterval        = time.Hour
+	trashPurgeBatch           = 100
+)
+
+// TrashedChirp is a deleted chirp its author can still restore until
+// PurgeAt.
+type TrashedChirp struct {
+	Chirp
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at"`
+}
+
+type trashPage struct {
+	Chirps     []TrashedChirp `json:"chirps"`
+	NextCursor string         `json:"next_cursor"`
+}
+
+func (cfg *apiConfig) handlerTrash(w http.ResponseWriter, r *http.Request) {
+	accessToken, err := internal.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "malformed header", err)
+		return
+	}
+	userID, err := cfg.validateAccessToken(r.Context(), accessToken)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "invalid access token", err)
+		return
+	}
+	limit, cursor, err := parsePage(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	// fetch one extra row to learn whether another page follows
+	params := database.ListTrashParams{
+		UserID:       userID,
+		Limit:        int32(limit + 1),
+		DeletedAfter: time.Now().UTC().Add(-cfg.trashRetention),
+	}
+	if cursor != nil {
+		params.BeforeDeletedAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+		params.BeforeID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+	chirps, err := cfg.dbQueries.ListTrash(r.Context(), params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't list trash", err)
+		return
+	}
+
+	page := trashPage{Chirps: []TrashedChirp{}}
+	if len(chirps) > limit {
+		chirps = chirps[:limit]
+		last := chirps[len(chirps)-1]
+		page.NextCursor = encodeCursor(last.DeletedAt.Time, last.ID)
+	}
+	for _, c := range chirps {
+		page.Chirps = append(page.Chirps, TrashedChirp{
+			Chirp:     chirpFromDB(c),
+			DeletedAt: c.DeletedAt.Time,
+			PurgeAt:   c.DeletedAt.Time.Add(cfg.trashRetention),
+		})
+	}
+	ptrs := make([]*Chirp, len(page.Chirps))
+	for i := range page.Chirps {
+		ptrs[i] = &page.Chirps[i].Chirp
+	}
+	err = cfg.hydrateChirps(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, ptrs...)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't load chirp details", err)
+		return
+	}
+	setNextLink(w, r, page.NextCursor)
+	respondWithJSON(w, http.StatusOK, page)
+}
+
+func (cfg *apiConfig) handlerRestoreChirp(w http.ResponseWriter, r *http.Request) {
+	accessToken, err := internal.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "malformed header", err)
+		return
+	}
+	userID, err := cfg.validateAccessToken(r.Context(), accessToken)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "invalid access token", err)
+		return
+	}
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid chirp ID", err)
+		return
+	}
+	chirp, err := cfg.dbQueries.GetTrashedChirp(r.Context(), chirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "chirp is not in the trash", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't get chirp", err)
+		return
+	}
+	if chirp.UserID != userID {
+		respondWithError(w, http.StatusForbidden, "not owner of chirp", nil)
+		return
+	}
+	// the purger may not have got to it yet
+	if time.Since(chirp.DeletedAt.Time) >= cfg.trashRetention {
+		respondWithError(w, http.StatusNotFound, "chirp is not in the trash", nil)
+		return
+	}
+
+	err = cfg.dbQueries.RestoreChirp(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't restore chirp", err)
+		return
+	}
+	chirp, err = cfg.dbQueries.GetChirpByID(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't get chirp", err)
+		return
+	}
+	resp := chirpFromDB(chirp)
+	err = cfg.hydrateChirps(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, &resp)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't load chirp details", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+// purgeExpiredTrash permanently deletes every chirp that has been in
+// the trash for the whole retention period, returning how many it
+// purged.
+func (cfg *apiConfig) purgeExpiredTrash(ctx context.Context) (int, error) {
+	cutoff := time.Now().UTC().Add(-cfg.trashRetention)
+	purged := 0
+	for {
+		ids, err := cfg.dbQueries.ListExpiredTrash(ctx, database.ListExpiredTrashParams{
+			Cutoff: cutoff,
+			Limit:  trashPurgeBatch,
+		})
+		if err != nil {
+			return purged, err
+		}
+		for _, id := range ids {
+			err = purgeChirp(ctx, cfg.dbQueries, id)
+			if err != nil {
+				return purged, err
+			}
+			purged++
+		}
+		if len(ids) < trashPurgeBatch {
+			return purged, nil
+		}
+	}
+}
+
+// runTrashPurger purges expired trash every interval until ctx is done.
+func (cfg *apiConfig) runTrashPurger(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		purged, err := cfg.purgeExpiredTrash(ctx)
+		if err != nil {
+			log.Printf("couldn't purge trash: %v", err)
+		} else if purged > 0 {
+			log.Printf("purged %d chirps from the trash", purged)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
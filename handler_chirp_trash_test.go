@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/natretsel/chirpy/internal/database"
+)
+
+func TestChirpTrash(t *testing.T) {
+	cfg, srv := newTestServer(t)
+	alice := createUser(t, srv, "alice-trash@example.com", "pw")
+	bob := createUser(t, srv, "bob-trash@example.com", "pw")
+	chirp := createChirp(t, srv, alice.Token, "keep #this")
+	path := "/api/chirps/" + chirp.ID.String()
+	resp, body := doRequest(t, srv, http.MethodPut, path+"/reactions/"+url.PathEscape("👍"), bearer(bob.Token), nil)
+	expectStatus(t, resp, body, http.StatusNoContent)
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/chirps", bearer(bob.Token), map[string]any{"rechirp_of": chirp.ID})
+	expectStatus(t, resp, body, http.StatusCreated)
+	rechirp := decodeBody[Chirp](t, body)
+	trash := func(token string) []TrashedChirp {
+		t.Helper()
+		resp, body := doRequest(t, srv, http.MethodGet, "/api/me/trash", bearer(token), nil)
+		expectStatus(t, resp, body, http.StatusOK)
+		return decodeBody[trashPage](t, body).Chirps
+	}
+	listed := func() []Chirp {
+		t.Helper()
+		resp, body := doRequest(t, srv, http.MethodGet, "/api/chirps", "", nil)
+		expectStatus(t, resp, body, http.StatusOK)
+		return decodeBody[chirpsPage](t, body).Chirps
+	}
+
+	resp, body = doRequest(t, srv, http.MethodDelete, path, bearer(alice.Token), nil)
+	expectStatus(t, resp, body, http.StatusNoContent)
+	resp, body = doRequest(t, srv, http.MethodGet, path, "", nil)
+	expectStatus(t, resp, body, http.StatusNotFound)
+	resp, body = doRequest(t, srv, http.MethodDelete, path, bearer(alice.Token), nil)
+	expectStatus(t, resp, body, http.StatusNotFound)
+	resp, body = doRequest(t, srv, http.MethodDelete, "/api/chirps/"+uuid.NewString(), bearer(alice.Token), nil)
+	expectStatus(t, resp, body, http.StatusNotFound)
+	if got := listed(); len(got) != 0 {
+		t.Errorf("chirps listed after deleting = %+v, want none", got)
+	}
+	trashed := trash(alice.Token)
+	if len(trashed) != 1 || trashed[0].ID != chirp.ID || trashed[0].Body != "keep #this" {
+		t.Fatalf("alice's trash = %+v", trashed)
+	}
+	if want := trashed[0].DeletedAt.Add(defaultTrashRetentionDays * 24 * time.Hour); !trashed[0].PurgeAt.Equal(want) {
+		t.Errorf("purge_at = %v, want %v", trashed[0].PurgeAt, want)
+	}
+	if got := trash(bob.Token); len(got) != 0 {
+		t.Errorf("bob's trash = %+v, want his hidden rechirp left out", got)
+	}
+	resp, body = doRequest(t, srv, http.MethodGet, "/api/me/trash", "", nil)
+	expectStatus(t, resp, body, http.StatusUnauthorized)
+
+	resp, body = doRequest(t, srv, http.MethodPost, path+"/restore", bearer(bob.Token), nil)
+	expectStatus(t, resp, body, http.StatusForbidden)
+	resp, body = doRequest(t, srv, http.MethodPost, path+"/restore", bearer(alice.Token), nil)
+	expectStatus(t, resp, body, http.StatusOK)
+	restored := decodeBody[Chirp](t, body)
+	if restored.RechirpCount != 1 || len(restored.Reactions) != 1 || len(restored.Entities.Hashtags) != 1 {
+		t.Errorf("restored chirp = %+v, want its rechirp, reaction and hashtag back", restored)
+	}
+	if got := listed(); len(got) != 2 {
+		t.Errorf("chirps listed after restoring = %+v, want the chirp and its rechirp", got)
+	}
+	resp, body = doRequest(t, srv, http.MethodPost, path+"/restore", bearer(alice.Token), nil)
+	expectStatus(t, resp, body, http.StatusNotFound)
+
+	// un-rechirping skips the trash, so the chirp can be rechirped again
+	resp, body = doRequest(t, srv, http.MethodDelete, "/api/chirps/"+rechirp.ID.String(), bearer(bob.Token), nil)
+	expectStatus(t, resp, body, http.StatusNoContent)
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/chirps/"+rechirp.ID.String()+"/restore", bearer(bob.Token), nil)
+	expectStatus(t, resp, body, http.StatusNotFound)
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/chirps", bearer(bob.Token), map[string]any{"rechirp_of": chirp.ID})
+	expectStatus(t, resp, body, http.StatusCreated)
+
+	// once the retention period is over the chirp is gone for good
+	resp, body = doRequest(t, srv, http.MethodDelete, path, bearer(alice.Token), nil)
+	expectStatus(t, resp, body, http.StatusNoContent)
+	cfg.trashRetention = 0
+	if got := trash(alice.Token); len(got) != 0 {
+		t.Errorf("expired trash still listed: %+v", got)
+	}
+	resp, body = doRequest(t, srv, http.MethodPost, path+"/restore", bearer(alice.Token), nil)
+	expectStatus(t, resp, body, http.StatusNotFound)
+	purged, err := cfg.purgeExpiredTrash(context.Background())
+	if err != nil || purged != 2 {
+		t.Errorf("purgeExpiredTrash = %d, %v; want the chirp and its rechirp", purged, err)
+	}
+	if _, err := cfg.dbQueries.GetThreadChirpByID(context.Background(), chirp.ID); err == nil {
+		t.Error("purged chirp is still stored")
+	}
+}
+
+// failingMediaStore can't detach media, inside a transaction or out.
+type failingMediaStore struct {
+	database.Store
+}
+
+func (s failingMediaStore) DetachChirpMedia(ctx context.Context, chirpID uuid.UUID) error {
+	return errors.New("media is down")
+}
+
+func (s failingMediaStore) InTx(ctx context.Context, fn func(database.Store) error) error {
+	return s.Store.InTx(ctx, func(database.Store) error { return fn(s) })
+}
+
+func TestPurgeTombstoneClearsEntities(t *testing.T) {
+	cfg, srv := newTestServer(t)
+	alice := createUser(t, srv, "alice-tombstone@example.com", "pw")
+	chirp := createChirp(t, srv, alice.Token, "about #this")
+	resp, body := doRequest(t, srv, http.MethodPost, "/api/chirps", bearer(alice.Token), map[string]any{"body": "a reply", "in_reply_to": chirp.ID})
+	expectStatus(t, resp, body, http.StatusCreated)
+	ctx := context.Background()
+	media, err := cfg.dbQueries.CreateMedia(ctx, database.CreateMediaParams{
+		ID:          uuid.New(),
+		UserID:      alice.ID,
+		ContentType: "image/png",
+		SizeBytes:   1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cfg.dbQueries.AttachChirpMedia(ctx, database.AttachChirpMediaParams{ChirpID: chirp.ID, MediaID: media.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entities := func() (int, int) {
+		t.Helper()
+		tags, err := cfg.dbQueries.GetChirpTags(ctx, []uuid.UUID{chirp.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		attached, err := cfg.dbQueries.GetChirpMedia(ctx, []uuid.UUID{chirp.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(tags), len(attached)
+	}
+
+	// a failed purge leaves the chirp as it was
+	store := cfg.dbQueries
+	if err := purgeChirp(ctx, failingMediaStore{store}, chirp.ID); err == nil {
+		t.Fatal("purgeChirp succeeded with media down")
+	}
+	stored, err := store.GetThreadChirpByID(ctx, chirp.ID)
+	if err != nil || stored.Chirp.TombstonedAt.Valid || stored.Chirp.Body != "about #this" {
+		t.Errorf("chirp after a failed purge = %+v (%v)", stored.Chirp, err)
+	}
+	if tags, attached := entities(); tags != 1 || attached != 1 {
+		t.Errorf("after a failed purge: %d tags, %d attachments; want 1 each", tags, attached)
+	}
+
+	if err := purgeChirp(ctx, store, chirp.ID); err != nil {
+		t.Fatal(err)
+	}
+	stored, err = store.GetThreadChirpByID(ctx, chirp.ID)
+	if err != nil || !stored.Chirp.TombstonedAt.Valid {
+		t.Fatalf("purged chirp with a reply = %+v (%v), want a tombstone", stored.Chirp, err)
+	}
+	if tags, attached := entities(); tags != 0 || attached != 0 {
+		t.Errorf("tombstone kept %d tags, %d attachments", tags, attached)
+	}
+}
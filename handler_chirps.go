@@ -1,96 +1,280 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"slices"
-	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/natretsel/chirpy/chirplen"
 	internal "github.com/natretsel/chirpy/internal/auth"
 	"github.com/natretsel/chirpy/internal/database"
 )
 
 type Chirp struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	UserID    uuid.UUID `json:"user_id"`
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Body      string     `json:"body"`
+	UserID    uuid.UUID  `json:"user_id"`
+	Edited    bool       `json:"edited"`
+	InReplyTo *uuid.UUID `json:"in_reply_to"`
+	RechirpOf *uuid.UUID `json:"rechirp_of"`
+	QuoteOf   *uuid.UUID `json:"quote_of"`
+	// Original is the rechirped or quoted chirp, when there is one
+	Original     *Chirp            `json:"original,omitempty"`
+	RechirpCount int32             `json:"rechirp_count"`
+	QuoteCount   int32             `json:"quote_count"`
+	Reactions    []ReactionCount   `json:"reactions"`
+	Entities     ChirpEntities     `json:"entities"`
+	Media        []MediaAttachment `json:"media"`
+	Held         bool              `json:"held,omitempty"`
+}
+
+func chirpFromDB(chirp database.Chirp) Chirp {
+	c := Chirp{
+		ID:        chirp.ID,
+		CreatedAt: chirp.CreatedAt,
+		UpdatedAt: chirp.UpdatedAt,
+		Body:      chirp.Body,
+		UserID:    chirp.UserID,
+		Edited:    chirp.UpdatedAt.After(chirp.CreatedAt),
+		Reactions: []ReactionCount{},
+		Entities:  newChirpEntities(),
+		Media:     []MediaAttachment{},
+		Held:      chirp.HeldAt.Valid,
+	}
+	if chirp.InReplyTo.Valid {
+		c.InReplyTo = &chirp.InReplyTo.UUID
+	}
+	if chirp.RechirpOf.Valid {
+		c.RechirpOf = &chirp.RechirpOf.UUID
+	}
+	if chirp.QuoteOf.Valid {
+		c.QuoteOf = &chirp.QuoteOf.UUID
+	}
+	return c
+}
+
+// hydrateChirps fills in everything a Chirp response carries beyond
+// its own row: the rechirped or quoted original, share counts, entities,
+// media and reactions as seen by viewer. Originals are hydrated too.
+func (cfg *apiConfig) hydrateChirps(ctx context.Context, viewer uuid.NullUUID, chirps ...*Chirp) error {
+	if len(chirps) == 0 {
+		return nil
+	}
+	originals, err := cfg.attachOriginals(ctx, chirps)
+	if err != nil {
+		return err
+	}
+	all := slices.Concat(chirps, originals)
+	err = cfg.attachShareCounts(ctx, all)
+	if err != nil {
+		return err
+	}
+	err = cfg.attachEntities(ctx, all)
+	if err != nil {
+		return err
+	}
+	err = cfg.attachMedia(ctx, all)
+	if err != nil {
+		return err
+	}
+	return cfg.attachReactions(ctx, viewer, all...)
+}
+
+// attachOriginals sets Original on every rechirp and quote in chirps,
+// returning the originals it loaded.
+func (cfg *apiConfig) attachOriginals(ctx context.Context, chirps []*Chirp) ([]*Chirp, error) {
+	ids := []uuid.UUID{}
+	for _, c := range chirps {
+		if c.RechirpOf != nil {
+			ids = append(ids, *c.RechirpOf)
+		} else if c.QuoteOf != nil {
+			ids = append(ids, *c.QuoteOf)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	rows, err := cfg.dbQueries.GetChirpsByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]*Chirp, len(rows))
+	originals := make([]*Chirp, 0, len(rows))
+	for _, row := range rows {
+		original := chirpFromDB(row)
+		byID[row.ID] = &original
+		originals = append(originals, &original)
+	}
+	for _, c := range chirps {
+		if c.RechirpOf != nil {
+			c.Original = byID[*c.RechirpOf]
+		} else if c.QuoteOf != nil {
+			c.Original = byID[*c.QuoteOf]
+		}
+	}
+	return originals, nil
+}
+
+// attachShareCounts sets the rechirp and quote counts of chirps.
+func (cfg *apiConfig) attachShareCounts(ctx context.Context, chirps []*Chirp) error {
+	ids := make([]uuid.UUID, 0, len(chirps))
+	for _, c := range chirps {
+		ids = append(ids, c.ID)
+	}
+	rows, err := cfg.dbQueries.GetShareCounts(ctx, ids)
+	if err != nil {
+		return err
+	}
+	counts := make(map[uuid.UUID]database.GetShareCountsRow, len(rows))
+	for _, row := range rows {
+		counts[row.ChirpID] = row
+	}
+	for _, c := range chirps {
+		c.RechirpCount = counts[c.ID].Rechirps
+		c.QuoteCount = counts[c.ID].Quotes
+	}
+	return nil
+}
+
+// resolveChirpRef loads the chirp a new chirp replies to, rechirps or
+// quotes. A rechirp stands in for its original, so references to one
+// resolve to the chirp it shares.
+func (cfg *apiConfig) resolveChirpRef(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	chirp, err := cfg.dbQueries.GetChirpByID(ctx, id)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	if chirp.RechirpOf.Valid {
+		return cfg.dbQueries.GetChirpByID(ctx, chirp.RechirpOf.UUID)
+	}
+	return chirp, nil
 }
 
 func (cfg *apiConfig) handlerChirpsGetByID(w http.ResponseWriter, r *http.Request) {
 	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
 	if err != nil {
 		respondWithError(w, http.StatusBadRequest, "Invalid Chirp ID", err)
+		return
 	}
 	chirp, err := cfg.dbQueries.GetChirpByID(r.Context(), chirpID)
 	if err != nil {
 		respondWithError(w, http.StatusNotFound, "Couldn't get chirp", nil)
+		return
 	}
 
-	respondWithJSON(w, http.StatusOK, Chirp{
-		ID:        chirpID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserID:    chirp.UserID,
-	})
+	resp := chirpFromDB(chirp)
+	err = cfg.hydrateChirps(r.Context(), cfg.viewerID(r), &resp)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't load chirp details", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+// chirpsPage is one page of a chirp listing. NextCursor is empty on
+// the last page.
+type chirpsPage struct {
+	Chirps     []Chirp `json:"chirps"`
+	NextCursor string  `json:"next_cursor"`
 }
 
 func (cfg *apiConfig) handlerChirpsGet(w http.ResponseWriter, r *http.Request) {
-	// check for optional query param
-	authorIDStr := r.URL.Query().Get("author_id")
-	order := r.URL.Query().Get("sort")
-	// retrieves all chirps in ascending order
-	chirps, err := cfg.dbQueries.GetChirps(r.Context())
+	// check for optional query params
+	query := r.URL.Query()
+	authorIDStr := query.Get("author_id")
+	order := query.Get("sort")
+	if order != "" && order != "asc" && order != "desc" {
+		respondWithError(w, http.StatusBadRequest, "sort must be asc or desc", nil)
+		return
+	}
+	limit, cursor, err := parsePage(query)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "couldn't query all chirps", err)
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
 		return
 	}
-	authorID := uuid.Nil
+
+	authorID := uuid.NullUUID{}
 	if len(authorIDStr) != 0 {
-		authorID, err = uuid.Parse(authorIDStr)
+		id, err := uuid.Parse(authorIDStr)
 		if err != nil {
 			respondWithError(w, http.StatusBadRequest, "invalid author_id", err)
 			return
 		}
-		user, err := cfg.dbQueries.GetUserByID(r.Context(), authorID)
+		user, err := cfg.dbQueries.GetUserByID(r.Context(), id)
 		if err != nil {
 			respondWithError(w, http.StatusBadRequest, "invalid author", err)
 			return
 		}
-		chirps, err = cfg.dbQueries.GetChirpsByUserID(r.Context(), user.ID)
-		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "couldn't query all chirps", err)
-			return
+		authorID = uuid.NullUUID{UUID: user.ID, Valid: true}
+	}
+
+	// fetch one extra row to learn whether another page follows
+	var chirps []database.Chirp
+	if order == "desc" {
+		params := database.ListChirpsDescParams{
+			Limit:  int32(limit + 1),
+			UserID: authorID,
+		}
+		if cursor != nil {
+			params.BeforeCreatedAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+			params.BeforeID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+		}
+		chirps, err = cfg.dbQueries.ListChirpsDesc(r.Context(), params)
+	} else {
+		params := database.ListChirpsAscParams{
+			Limit:  int32(limit + 1),
+			UserID: authorID,
 		}
+		if cursor != nil {
+			params.AfterCreatedAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+			params.AfterID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+		}
+		chirps, err = cfg.dbQueries.ListChirpsAsc(r.Context(), params)
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't query chirps", err)
+		return
 	}
 
-	chirpsArr := []Chirp{}
+	cfg.respondWithChirps(w, r, chirps, limit)
+}
+
+// respondWithChirps writes one hydrated page of chirps, which were
+// fetched with one extra row to detect a next page.
+func (cfg *apiConfig) respondWithChirps(w http.ResponseWriter, r *http.Request, chirps []database.Chirp, limit int) {
+	page := chirpsPage{Chirps: []Chirp{}}
+	if len(chirps) > limit {
+		chirps = chirps[:limit]
+		last := chirps[len(chirps)-1]
+		page.NextCursor = encodeCursor(last.CreatedAt, last.ID)
+	}
 	for _, c := range chirps {
-		if authorID != uuid.Nil && authorID != c.UserID {
-			continue
-		}
-		chirpsArr = append(chirpsArr, Chirp{
-			ID:        c.ID,
-			CreatedAt: c.CreatedAt,
-			UpdatedAt: c.UpdatedAt,
-			Body:      c.Body,
-			UserID:    c.UserID,
-		})
+		page.Chirps = append(page.Chirps, chirpFromDB(c))
 	}
-	if order == "desc" {
-		slices.Reverse(chirpsArr)
+	err := cfg.hydrateChirps(r.Context(), cfg.viewerID(r), chirpPointers(page.Chirps)...)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't load chirp details", err)
+		return
 	}
-	respondWithJSON(w, http.StatusOK, chirpsArr)
+	setNextLink(w, r, page.NextCursor)
+	respondWithJSON(w, http.StatusOK, page)
 }
 
 func (cfg *apiConfig) handlerChirpsCreate(w http.ResponseWriter, r *http.Request) {
 	type parameters struct {
-		Body string `json:"body"`
+		Body      string       `json:"body"`
+		InReplyTo *uuid.UUID   `json:"in_reply_to"`
+		RechirpOf *uuid.UUID   `json:"rechirp_of"`
+		QuoteOf   *uuid.UUID   `json:"quote_of"`
+		Media     []mediaParam `json:"media"`
 	}
 
 	type cleanedTextResponse struct {
@@ -103,70 +287,213 @@ func (cfg *apiConfig) handlerChirpsCreate(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	userId, err := internal.ValidateJWT(bearerToken, cfg.secret)
+	userId, err := cfg.validateAccessToken(r.Context(), bearerToken)
 	if err != nil {
 		respondWithError(w, http.StatusUnauthorized, "invalid token", err)
 		return
 	}
+	author, err := cfg.dbQueries.GetUserByID(r.Context(), userId)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "invalid token", err)
+		return
+	}
+	if isSuspended(author) {
+		respondSuspended(w, author)
+		return
+	}
+	if !cfg.checkVerified(w, author, actionChirp) {
+		return
+	}
 
+	r.Body = http.MaxBytesReader(w, r.Body, maxChirpRequestBytes)
 	decoder := json.NewDecoder(r.Body)
 	params := parameters{}
 	err = decoder.Decode(&params)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+	if requestTooLarge(err) {
+		respondWithError(w, http.StatusRequestEntityTooLarge, "request is too large", err)
 		return
 	}
-
-	cleanedBody, err := validateChirp(params.Body)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
 		return
 	}
 
 	chirpParam := database.CreateChirpParams{
-		Body:   cleanedBody,
 		UserID: userId,
 	}
-	chirp, err := cfg.dbQueries.CreateChirp(r.Context(), chirpParam)
-
+	if params.RechirpOf != nil {
+		// a rechirp only points at the original
+		if params.Body != "" || params.InReplyTo != nil || params.QuoteOf != nil || len(params.Media) > 0 {
+			respondWithError(w, http.StatusBadRequest, "A rechirp can't have a body, reply, quote or media; use quote_of to add them", nil)
+			return
+		}
+		original, ok := cfg.chirpRefParam(w, r, *params.RechirpOf, "chirp being rechirped")
+		if !ok {
+			return
+		}
+		_, err = cfg.dbQueries.GetRechirp(r.Context(), database.GetRechirpParams{
+			UserID:    userId,
+			RechirpOf: original.UUID,
+		})
+		if err == nil {
+			respondWithError(w, http.StatusConflict, "Chirp already rechirped", nil)
+			return
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't check for existing rechirp", err)
+			return
+		}
+		chirpParam.RechirpOf = original
+	} else {
+		verdict, err := cfg.validateChirp(params.Body)
+		if err != nil {
+			respondWithInvalidChirp(w, err)
+			return
+		}
+		chirpParam.Body = verdict.Body
+		chirpParam.Held = verdict.Hold
+		chirpParam.HoldReason = verdict.HoldReason
+	}
+	if params.InReplyTo != nil {
+		parent, ok := cfg.chirpRefParam(w, r, *params.InReplyTo, "chirp being replied to")
+		if !ok {
+			return
+		}
+		chirpParam.InReplyTo = parent
+	}
+	if params.QuoteOf != nil {
+		quoted, ok := cfg.chirpRefParam(w, r, *params.QuoteOf, "chirp being quoted")
+		if !ok {
+			return
+		}
+		chirpParam.QuoteOf = quoted
+	}
+	if !cfg.checkMediaParams(w, r, userId, params.Media) {
+		return
+	}
+	// the chirp is only kept along with its hashtags, mentions and
+	// media, and a held one along with its report
+	var chirp database.Chirp
+	err = cfg.dbQueries.InTx(r.Context(), func(store database.Store) error {
+		var err error
+		chirp, err = store.CreateChirp(r.Context(), chirpParam)
+		if err != nil {
+			return err
+		}
+		err = saveChirpEntities(r.Context(), store, chirp)
+		if err != nil {
+			return fmt.Errorf("couldn't save hashtags and mentions: %w", err)
+		}
+		for i, media := range params.Media {
+			err = store.AttachChirpMedia(r.Context(), database.AttachChirpMediaParams{
+				ChirpID:  chirp.ID,
+				MediaID:  media.ID,
+				Position: int32(i),
+				AltText:  media.AltText,
+			})
+			if err != nil {
+				return fmt.Errorf("couldn't attach media: %w", err)
+			}
+		}
+		if chirp.HeldAt.Valid {
+			err = reportHeldChirp(r.Context(), store, chirp)
+			if err != nil {
+				return fmt.Errorf("couldn't queue chirp for review: %w", err)
+			}
+		}
+		return nil
+	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Couldn't create chirp", err)
+		return
 	}
-	respondWithJSON(w, 201, cleanedTextResponse{
-		Chirp: Chirp{
-			ID:        chirp.ID,
-			CreatedAt: chirp.CreatedAt,
-			UpdatedAt: chirp.UpdatedAt,
-			Body:      chirp.Body,
-			UserID:    chirp.UserID,
-		},
-	})
+	resp := cleanedTextResponse{
+		Chirp: chirpFromDB(chirp),
+	}
+	err = cfg.hydrateChirps(r.Context(), uuid.NullUUID{UUID: userId, Valid: true}, &resp.Chirp)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't load chirp details", err)
+		return
+	}
+	// a held chirp is accepted but not yet published
+	if chirp.HeldAt.Valid {
+		respondWithJSON(w, http.StatusAccepted, resp)
+		return
+	}
+	respondWithJSON(w, 201, resp)
 
 }
 
-func validateChirp(body string) (string, error) {
-	const maxChirpLength = 140
-	if len(body) > maxChirpLength {
-		return "", errors.New("Chirp is too long")
+// chirpRefParam resolves a chirp referenced by a new chirp's payload,
+// responding with an error and returning false if it doesn't exist.
+// what names the reference in error messages.
+func (cfg *apiConfig) chirpRefParam(w http.ResponseWriter, r *http.Request, id uuid.UUID, what string) (uuid.NullUUID, bool) {
+	chirp, err := cfg.resolveChirpRef(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusBadRequest, what+" doesn't exist", err)
+		return uuid.NullUUID{}, false
 	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get "+what, err)
+		return uuid.NullUUID{}, false
+	}
+	return uuid.NullUUID{UUID: chirp.ID, Valid: true}, true
+}
+
+// chirpTooLongError reports a chirp body over the length limit, as
+// counted by chirplen.Count.
+type chirpTooLongError struct {
+	Length int `json:"length"`
+	Limit  int `json:"limit"`
+}
+
+func (e chirpTooLongError) Error() string {
+	return fmt.Sprintf("Chirp is too long: %d characters, the limit is %d", e.Length, e.Limit)
+}
 
-	badWords := map[string]bool{
-		"kerfuffle": true,
-		"sharbert":  true,
-		"fornax":    true,
+// respondWithInvalidChirp writes the 400 response for an error from
+// validateChirp. Too-long errors also carry the length and limit.
+func respondWithInvalidChirp(w http.ResponseWriter, err error) {
+	var tooLong chirpTooLongError
+	if !errors.As(err, &tooLong) {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	type response struct {
+		Error string `json:"error"`
+		chirpTooLongError
 	}
-	cleaned := getCleanedBody(body, badWords)
-	return cleaned, nil
+	respondWithJSON(w, http.StatusBadRequest, response{
+		Error:             err.Error(),
+		chirpTooLongError: tooLong,
+	})
 }
 
-func getCleanedBody(str string, bannedWords map[string]bool) string {
-	redactedString := "****"
-	splitBody := strings.Split(str, " ")
-	for idx, word := range splitBody {
-		if _, ok := bannedWords[strings.ToLower(word)]; ok {
-			splitBody[idx] = redactedString
+// maxChirpRequestBytes bounds the request a chirp is created or edited
+// with, which besides the body can carry the alt text of its media.
+const maxChirpRequestBytes = 64 << 10
 
-		}
+// requestTooLarge reports whether err is from reading a request body
+// past the limit set by http.MaxBytesReader.
+func requestTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
+// validateChirp checks body against the length limit and the
+// moderation rules, returning the body to store and whether the chirp
+// must be held for review.
+func (cfg *apiConfig) validateChirp(body string) (chirpVerdict, error) {
+	// the character count alone doesn't bound the size of a chirp
+	if len(body) > chirplen.MaxBytes {
+		return chirpVerdict{}, fmt.Errorf("Chirp is too large: the limit is %d bytes", chirplen.MaxBytes)
+	}
+	if chirplen.LongestURL(body) > chirplen.MaxURLBytes {
+		return chirpVerdict{}, fmt.Errorf("Chirp has a URL over %d bytes", chirplen.MaxURLBytes)
+	}
+	length := chirplen.Count(body)
+	if length > chirplen.MaxLength {
+		return chirpVerdict{}, chirpTooLongError{Length: length, Limit: chirplen.MaxLength}
 	}
-	return strings.Join(splitBody, " ")
+	return moderate(body, cfg.moderation.get())
 }
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestRechirpsAndQuotes(t *testing.T) {
+	cfg, srv := newTestServer(t)
+	alice := createUser(t, srv, "alice-rechirps@example.com", "pw")
+	bob := createUser(t, srv, "bob-rechirps@example.com", "pw")
+	carol := createUser(t, srv, "carol-rechirps@example.com", "pw")
+	post := func(token string, payload map[string]any, want int) Chirp {
+		t.Helper()
+		resp, body := doRequest(t, srv, http.MethodPost, "/api/chirps", bearer(token), payload)
+		expectStatus(t, resp, body, want)
+		if want != http.StatusCreated {
+			return Chirp{}
+		}
+		return decodeBody[Chirp](t, body)
+	}
+	getChirp := func(id uuid.UUID) Chirp {
+		t.Helper()
+		resp, body := doRequest(t, srv, http.MethodGet, "/api/chirps/"+id.String(), "", nil)
+		expectStatus(t, resp, body, http.StatusOK)
+		return decodeBody[Chirp](t, body)
+	}
+	listChirps := func(path, token string) []Chirp {
+		t.Helper()
+		authorization := ""
+		if token != "" {
+			authorization = bearer(token)
+		}
+		resp, body := doRequest(t, srv, http.MethodGet, path, authorization, nil)
+		expectStatus(t, resp, body, http.StatusOK)
+		return decodeBody[chirpsPage](t, body).Chirps
+	}
+
+	original := createChirp(t, srv, alice.Token, "original thought")
+	rechirp := post(bob.Token, map[string]any{"rechirp_of": original.ID}, http.StatusCreated)
+	if rechirp.RechirpOf == nil || *rechirp.RechirpOf != original.ID || rechirp.Original == nil || rechirp.Original.Body != "original thought" {
+		t.Fatalf("rechirp = %+v, want it to carry the original", rechirp)
+	}
+	post(bob.Token, map[string]any{"rechirp_of": original.ID}, http.StatusConflict)
+	post(bob.Token, map[string]any{"rechirp_of": original.ID, "body": "extra"}, http.StatusBadRequest)
+	post(bob.Token, map[string]any{"rechirp_of": uuid.New()}, http.StatusBadRequest)
+
+	// rechirping a rechirp shares the original
+	second := post(carol.Token, map[string]any{"rechirp_of": rechirp.ID}, http.StatusCreated)
+	if *second.RechirpOf != original.ID {
+		t.Errorf("rechirp of a rechirp points at %v, want %v", *second.RechirpOf, original.ID)
+	}
+	quote := post(carol.Token, map[string]any{"quote_of": original.ID, "body": "so true"}, http.StatusCreated)
+	if quote.QuoteOf == nil || *quote.QuoteOf != original.ID || quote.Original == nil || quote.Body != "so true" {
+		t.Fatalf("quote = %+v", quote)
+	}
+
+	if got := getChirp(original.ID); got.RechirpCount != 2 || got.QuoteCount != 1 {
+		t.Errorf("original counts = %d rechirps, %d quotes; want 2 and 1", got.RechirpCount, got.QuoteCount)
+	}
+
+	bobsFeed := listChirps("/api/chirps?author_id="+bob.ID.String(), "")
+	if len(bobsFeed) != 1 || bobsFeed[0].ID != rechirp.ID || bobsFeed[0].Original == nil || bobsFeed[0].Original.UserID != alice.ID {
+		t.Errorf("bob's feed = %+v, want his rechirp attributed to alice", bobsFeed)
+	}
+	resp, body := doRequest(t, srv, http.MethodPost, "/api/users/"+bob.ID.String()+"/follow", bearer(carol.Token), nil)
+	expectStatus(t, resp, body, http.StatusNoContent)
+	timeline := listChirps("/api/timeline", carol.Token)
+	if !containsChirp(timeline, rechirp.ID) {
+		t.Errorf("carol's timeline is missing bob's rechirp: %+v", timeline)
+	}
+
+	// trashing the original hides its rechirps and quoted content
+	resp, body = doRequest(t, srv, http.MethodDelete, "/api/chirps/"+original.ID.String(), bearer(alice.Token), nil)
+	expectStatus(t, resp, body, http.StatusNoContent)
+	all := listChirps("/api/chirps", "")
+	if len(all) != 1 || all[0].ID != quote.ID || all[0].Original != nil {
+		t.Errorf("chirps after deleting the original = %+v, want only the quote", all)
+	}
+
+	// purging it removes rechirps and detaches quotes
+	purge := func() {
+		t.Helper()
+		retention := cfg.trashRetention
+		cfg.trashRetention = 0
+		defer func() { cfg.trashRetention = retention }()
+		if _, err := cfg.purgeExpiredTrash(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	purge()
+	all = listChirps("/api/chirps", "")
+	if len(all) != 1 || all[0].ID != quote.ID || all[0].QuoteOf != nil || all[0].Original != nil {
+		t.Errorf("chirps after purging the original = %+v, want only the detached quote", all)
+	}
+
+	// the same holds when the original is kept as a tombstone
+	tombstoned := createChirp(t, srv, alice.Token, "has replies")
+	post(bob.Token, map[string]any{"in_reply_to": tombstoned.ID, "body": "a reply"}, http.StatusCreated)
+	post(bob.Token, map[string]any{"rechirp_of": tombstoned.ID}, http.StatusCreated)
+	laterQuote := post(carol.Token, map[string]any{"quote_of": tombstoned.ID, "body": "quoting"}, http.StatusCreated)
+	resp, body = doRequest(t, srv, http.MethodDelete, "/api/chirps/"+tombstoned.ID.String(), bearer(alice.Token), nil)
+	expectStatus(t, resp, body, http.StatusNoContent)
+	purge()
+	for _, c := range listChirps("/api/chirps", "") {
+		if c.RechirpOf != nil {
+			t.Errorf("rechirp %v of a deleted chirp is still listed", c.ID)
+		}
+		if c.ID == laterQuote.ID && (c.QuoteOf != nil || c.Original != nil) {
+			t.Errorf("quote of a deleted chirp still references it: %+v", c)
+		}
+	}
+}
+
+func containsChirp(chirps []Chirp, id uuid.UUID) bool {
+	for _, c := range chirps {
+		if c.ID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/natretsel/chirpy/internal/database"
+)
+
+// ChirpSearchResult is a chirp matched by full-text search. Highlight
+// is the HTML escaped body with every matched term wrapped in <mark>.
+type ChirpSearchResult struct {
+	Chirp
+	Rank      float32 `json:"rank"`
+	Highlight string  `json:"highlight"`
+}
+
+type chirpSearchPage struct {
+	Chirps     []ChirpSearchResult `json:"chirps"`
+	NextCursor string              `json:"next_cursor"`
+}
+
+func (cfg *apiConfig) handlerChirpsSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := strings.TrimSpace(query.Get("q"))
+	if q == "" {
+		respondWithError(w, http.StatusBadRequest, "missing search query q", nil)
+		return
+	}
+	limit, cursor, err := parsePage(query)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	params := database.SearchChirpsParams{
+		Limit: int32(limit + 1),
+		Query: q,
+	}
+	if authorIDStr := query.Get("author_id"); authorIDStr != "" {
+		authorID, err := uuid.Parse(authorIDStr)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "invalid author_id", err)
+			return
+		}
+		params.UserID = uuid.NullUUID{UUID: authorID, Valid: true}
+	}
+	if params.Since, err = parseTimeParam(query, "since"); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	if params.Until, err = parseTimeParam(query, "until"); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	if cursor != nil {
+		params.AfterRank = sql.NullFloat64{Float64: float64(cursor.Rank), Valid: true}
+		params.AfterCreatedAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+		params.AfterID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+
+	rows, err := cfg.dbQueries.SearchChirps(r.Context(), params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't search chirps", err)
+		return
+	}
+
+	page := chirpSearchPage{Chirps: []ChirpSearchResult{}}
+	if len(rows) > limit {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		page.NextCursor = pageCursor{
+			Rank:      last.Rank,
+			CreatedAt: last.Chirp.CreatedAt,
+			ID:        last.Chirp.ID,
+		}.encode()
+	}
+	for _, row := range rows {
+		page.Chirps = append(page.Chirps, ChirpSearchResult{
+			Chirp:     chirpFromDB(row.Chirp),
+			Rank:      row.Rank,
+			Highlight: row.Highlight,
+		})
+	}
+	chirps := make([]*Chirp, len(page.Chirps))
+	for i := range page.Chirps {
+		chirps[i] = &page.Chirps[i].Chirp
+	}
+	err = cfg.hydrateChirps(r.Context(), cfg.viewerID(r), chirps...)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't load chirp details", err)
+		return
+	}
+	setNextLink(w, r, page.NextCursor)
+	respondWithJSON(w, http.StatusOK, page)
+}
+
+// parseTimeParam reads an optional RFC 3339 timestamp query param.
+func parseTimeParam(query url.Values, name string) (sql.NullTime, error) {
+	value := query.Get(name)
+	if value == "" {
+		return sql.NullTime{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return sql.NullTime{}, fmt.Errorf("%s must be an RFC 3339 timestamp", name)
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}, nil
+}
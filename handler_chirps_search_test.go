@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestChirpsSearch(t *testing.T) {
+	_, srv := newTestServer(t)
+	alice := createUser(t, srv, "searcher@example.com", "pw")
+	bob := createUser(t, srv, "other-searcher@example.com", "pw")
+	best := createChirp(t, srv, alice.Token, "Gophers gophers everywhere")
+	partial := createChirp(t, srv, alice.Token, "I saw some gophers at the <park> today")
+	bobs := createChirp(t, srv, bob.Token, "Gophers are great")
+	createChirp(t, srv, alice.Token, "nothing to see here")
+
+	search := func(t *testing.T, params url.Values) chirpSearchPage {
+		t.Helper()
+		resp, body := doRequest(t, srv, http.MethodGet, "/api/chirps/search?"+params.Encode(), "", nil)
+		expectStatus(t, resp, body, http.StatusOK)
+		return decodeBody[chirpSearchPage](t, body)
+	}
+
+	page := search(t, url.Values{"q": {"gophers"}})
+	if len(page.Chirps) != 3 {
+		t.Fatalf("got %d results, want 3", len(page.Chirps))
+	}
+	if page.Chirps[0].ID != best.ID {
+		t.Errorf("top result = %q, want %q", page.Chirps[0].Body, best.Body)
+	}
+
+	page = search(t, url.Values{"q": {"gophers park"}, "author_id": {alice.ID.String()}})
+	if len(page.Chirps) != 1 || page.Chirps[0].ID != partial.ID {
+		t.Fatalf("author filtered results = %+v", page.Chirps)
+	}
+	if want := "I saw some <mark>gophers</mark> at the &lt;<mark>park</mark>&gt; today"; page.Chirps[0].Highlight != want {
+		t.Errorf("highlight = %q, want %q", page.Chirps[0].Highlight, want)
+	}
+	if page.Chirps[0].Body != partial.Body {
+		t.Errorf("body = %q, want it unchanged", page.Chirps[0].Body)
+	}
+
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	if page = search(t, url.Values{"q": {"gophers"}, "since": {future}}); len(page.Chirps) != 0 {
+		t.Errorf("since in the future returned %d results", len(page.Chirps))
+	}
+	if page = search(t, url.Values{"q": {"gophers"}, "until": {future}}); len(page.Chirps) != 3 {
+		t.Errorf("until in the future returned %d results, want 3", len(page.Chirps))
+	}
+
+	var paged []string
+	params := url.Values{"q": {"gophers"}, "limit": {"1"}}
+	for {
+		page = search(t, params)
+		for _, c := range page.Chirps {
+			paged = append(paged, c.ID.String())
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		params.Set("cursor", page.NextCursor)
+	}
+	if len(paged) != 3 || paged[0] != best.ID.String() || !slices.Contains(paged, bobs.ID.String()) {
+		t.Errorf("paged results = %v", paged)
+	}
+
+	for _, query := range []string{"", "?q=", "?q=x&since=yesterday", "?q=x&author_id=nope"} {
+		resp, body := doRequest(t, srv, http.MethodGet, "/api/chirps/search"+query, "", nil)
+		expectStatus(t, resp, body, http.StatusBadRequest)
+	}
+}
@@ -14,6 +14,7 @@ func (cfg *apiConfig) handlerUpgradeChirpyRed(w http.ResponseWriter, r *http.Req
 	reqAPIKey, err := internal.GetAPIKey(r.Header)
 	if reqAPIKey != cfg.polka_key {
 		respondWithError(w, http.StatusUnauthorized, "invalid API Key", err)
+		return
 	}
 	type RequestData struct {
 		UserID uuid.UUID `json:"user_id"`
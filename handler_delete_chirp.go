@@ -1,10 +1,15 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/google/uuid"
 	internal "github.com/natretsel/chirpy/internal/auth"
+	"github.com/natretsel/chirpy/internal/database"
 )
 
 func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, r *http.Request) {
@@ -16,7 +21,7 @@ func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, r *http.Request)
 	}
 
 	// check access token
-	userId, err := internal.ValidateJWT(accessToken, cfg.secret)
+	userId, err := cfg.validateAccessToken(r.Context(), accessToken)
 	if err != nil {
 		respondWithError(w, http.StatusUnauthorized, "Unauthorized", err)
 		return
@@ -29,6 +34,10 @@ func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	chirpDBObj, err := cfg.dbQueries.GetChirpByID(r.Context(), chirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "chirp not found", err)
+		return
+	}
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "couldn't get chirp by ID", err)
 		return
@@ -38,11 +47,61 @@ func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, r *http.Request)
 		respondWithError(w, http.StatusForbidden, "not owner of chirp", err)
 		return
 	}
-	// Delete chirp
-	err = cfg.dbQueries.DeleteChirpByID(r.Context(), chirpID)
+	// Un-rechirping is immediate; anything else goes to the trash
+	if chirpDBObj.RechirpOf.Valid {
+		err = purgeChirp(r.Context(), cfg.dbQueries, chirpID)
+	} else {
+		err = cfg.dbQueries.SoftDeleteChirp(r.Context(), chirpID)
+	}
 	if err != nil {
-		respondWithError(w, http.StatusNotFound, "couldn't delete chirp by ID", err)
+		respondWithError(w, http.StatusInternalServerError, "couldn't delete chirp by ID", err)
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// purgeChirp permanently deletes a chirp. Chirps with replies become
+// tombstones so the thread stays intact.
+func purgeChirp(ctx context.Context, store database.Store, chirpID uuid.UUID) error {
+	return store.InTx(ctx, func(store database.Store) error {
+		hasReplies, err := store.ChirpHasReplies(ctx, chirpID)
+		if err != nil {
+			return fmt.Errorf("couldn't check for replies: %w", err)
+		}
+		if !hasReplies {
+			return store.DeleteChirpByID(ctx, chirpID)
+		}
+		err = store.TombstoneChirp(ctx, chirpID)
+		if err != nil {
+			return err
+		}
+		err = store.DeleteReactionsByChirpID(ctx, chirpID)
+		if err != nil {
+			return fmt.Errorf("couldn't delete reactions: %w", err)
+		}
+		// earlier bodies go with the current one, as do the tags,
+		// mentions and attachments taken from it
+		err = store.DeleteChirpRevisions(ctx, chirpID)
+		if err != nil {
+			return fmt.Errorf("couldn't delete revisions: %w", err)
+		}
+		err = store.DeleteChirpEntities(ctx, chirpID)
+		if err != nil {
+			return fmt.Errorf("couldn't delete entities: %w", err)
+		}
+		err = store.DetachChirpMedia(ctx, chirpID)
+		if err != nil {
+			return fmt.Errorf("couldn't detach media: %w", err)
+		}
+		// mirror the foreign key actions a hard delete would trigger
+		err = store.DeleteRechirpsOf(ctx, chirpID)
+		if err != nil {
+			return fmt.Errorf("couldn't delete rechirps: %w", err)
+		}
+		err = store.DetachQuotesOf(ctx, chirpID)
+		if err != nil {
+			return fmt.Errorf("couldn't detach quotes: %w", err)
+		}
+		return nil
+	})
+}
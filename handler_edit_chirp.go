@@ -0,0 +1,186 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	internal "github.com/natretsel/chirpy/internal/auth"
+	"github.com/natretsel/chirpy/internal/database"
+)
+
+// defaultEditWindow is how long after posting a chirp can be edited,
+// unless CHIRP_EDIT_WINDOW says otherwise.
+const defaultEditWindow = time.Hour
+
+// ChirpRevision is an earlier body of an edited chirp, current from
+// CreatedAt until an edit replaced it at ReplacedAt.
+type ChirpRevision struct {
+	ID         uuid.UUID `json:"id"`
+	Body       string    `json:"body"`
+	CreatedAt  time.Time `json:"created_at"`
+	ReplacedAt time.Time `json:"replaced_at"`
+}
+
+func (cfg *apiConfig) handlerChirpsEdit(w http.ResponseWriter, r *http.Request) {
+	accessToken, err := internal.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "malformed header", err)
+		return
+	}
+	userID, err := cfg.validateAccessToken(r.Context(), accessToken)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "invalid access token", err)
+		return
+	}
+	author, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "invalid access token", err)
+		return
+	}
+	if isSuspended(author) {
+		respondSuspended(w, author)
+		return
+	}
+	if !cfg.checkVerified(w, author, actionChirp) {
+		return
+	}
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid chirp ID", err)
+		return
+	}
+	type parameters struct {
+		Body string `json:"body"`
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxChirpRequestBytes)
+	params := parameters{}
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if requestTooLarge(err) {
+		respondWithError(w, http.StatusRequestEntityTooLarge, "request is too large", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	chirp, err := cfg.dbQueries.GetChirpByID(r.Context(), chirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Couldn't get chirp", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't get chirp by ID", err)
+		return
+	}
+	if chirp.UserID != userID {
+		respondWithError(w, http.StatusForbidden, "not owner of chirp", nil)
+		return
+	}
+	if chirp.RechirpOf.Valid {
+		respondWithError(w, http.StatusBadRequest, "rechirps have no body to edit", nil)
+		return
+	}
+	if time.Since(chirp.CreatedAt) > cfg.editWindow {
+		respondWithError(w, http.StatusForbidden, "the edit window for this chirp has closed", nil)
+		return
+	}
+	verdict, err := cfg.validateChirp(params.Body)
+	if err != nil {
+		respondWithInvalidChirp(w, err)
+		return
+	}
+
+	// an edit that changes nothing leaves no revision behind, and one
+	// that fails partway leaves the chirp as it was
+	if verdict.Body != chirp.Body {
+		err = cfg.dbQueries.InTx(r.Context(), func(store database.Store) error {
+			err := store.CreateChirpRevision(r.Context(), chirpID)
+			if err != nil {
+				return fmt.Errorf("couldn't save revision: %w", err)
+			}
+			chirp, err = store.UpdateChirpBody(r.Context(), database.UpdateChirpBodyParams{
+				ID:         chirpID,
+				Body:       verdict.Body,
+				Held:       verdict.Hold,
+				HoldReason: verdict.HoldReason,
+			})
+			if err != nil {
+				return err
+			}
+			err = store.DeleteChirpEntities(r.Context(), chirpID)
+			if err != nil {
+				return fmt.Errorf("couldn't clear hashtags and mentions: %w", err)
+			}
+			err = saveChirpEntities(r.Context(), store, chirp)
+			if err != nil {
+				return fmt.Errorf("couldn't save hashtags and mentions: %w", err)
+			}
+			if chirp.HeldAt.Valid {
+				err = reportHeldChirp(r.Context(), store, chirp)
+				if err != nil {
+					return fmt.Errorf("couldn't queue chirp for review: %w", err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "couldn't update chirp", err)
+			return
+		}
+	}
+
+	resp := chirpFromDB(chirp)
+	err = cfg.hydrateChirps(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, &resp)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't load chirp details", err)
+		return
+	}
+	// an edit that trips a hold rule takes the chirp down until review
+	if chirp.HeldAt.Valid {
+		respondWithJSON(w, http.StatusAccepted, resp)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (cfg *apiConfig) handlerChirpRevisions(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid chirp ID", err)
+		return
+	}
+	_, err = cfg.dbQueries.GetChirpByID(r.Context(), chirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Couldn't get chirp", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't get chirp by ID", err)
+		return
+	}
+	revisions, err := cfg.dbQueries.GetChirpRevisions(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't get revisions", err)
+		return
+	}
+
+	type response struct {
+		Revisions []ChirpRevision `json:"revisions"`
+	}
+	resp := response{Revisions: []ChirpRevision{}}
+	for _, rev := range revisions {
+		resp.Revisions = append(resp.Revisions, ChirpRevision{
+			ID:         rev.ID,
+			Body:       rev.Body,
+			CreatedAt:  rev.CreatedAt,
+			ReplacedAt: rev.ReplacedAt,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/natretsel/chirpy/internal/database"
+)
+
+func TestEditChirp(t *testing.T) {
+	cfg, srv := newTestServer(t)
+	alice := createUser(t, srv, "alice-edit@example.com", "pw")
+	bob := createUser(t, srv, "bob-edit@example.com", "pw")
+	chirp := createChirp(t, srv, alice.Token, "teh #typo")
+	if chirp.Edited {
+		t.Error("a new chirp is marked edited")
+	}
+	path := "/api/chirps/" + chirp.ID.String()
+	edit := func(token, body string, want int) Chirp {
+		t.Helper()
+		resp, respBody := doRequest(t, srv, http.MethodPatch, path, bearer(token), map[string]string{"body": body})
+		expectStatus(t, resp, respBody, want)
+		if want != http.StatusOK {
+			return Chirp{}
+		}
+		return decodeBody[Chirp](t, respBody)
+	}
+	revisions := func() []ChirpRevision {
+		t.Helper()
+		resp, body := doRequest(t, srv, http.MethodGet, path+"/revisions", "", nil)
+		expectStatus(t, resp, body, http.StatusOK)
+		return decodeBody[struct {
+			Revisions []ChirpRevision `json:"revisions"`
+		}](t, body).Revisions
+	}
+
+	edit(bob.Token, "not mine", http.StatusForbidden)
+	edit(alice.Token, strings.Repeat("a", 141), http.StatusBadRequest)
+	if got := revisions(); len(got) != 0 {
+		t.Fatalf("revisions before any edit = %+v", got)
+	}
+
+	edited := edit(alice.Token, "the #fix kerfuffle", http.StatusOK)
+	if edited.Body != "the #fix ****" || !edited.Edited || !edited.UpdatedAt.After(edited.CreatedAt) {
+		t.Errorf("edited chirp = %+v", edited)
+	}
+	if len(edited.Entities.Hashtags) != 1 || edited.Entities.Hashtags[0].Tag != "fix" {
+		t.Errorf("hashtags after edit = %+v, want only #fix", edited.Entities.Hashtags)
+	}
+	resp, body := doRequest(t, srv, http.MethodGet, "/api/tags/typo/chirps", "", nil)
+	expectStatus(t, resp, body, http.StatusOK)
+	if got := decodeBody[chirpsPage](t, body).Chirps; len(got) != 0 {
+		t.Errorf("#typo still lists %d chirps after the edit", len(got))
+	}
+
+	// an edit that changes nothing is not a revision
+	edit(alice.Token, "the #fix kerfuffle", http.StatusOK)
+	got := revisions()
+	if len(got) != 1 || got[0].Body != "teh #typo" || !got[0].CreatedAt.Equal(chirp.CreatedAt) || got[0].ReplacedAt.After(edited.UpdatedAt) {
+		t.Errorf("revisions = %+v, want the original body", got)
+	}
+
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/chirps", bearer(bob.Token), map[string]any{"rechirp_of": chirp.ID})
+	expectStatus(t, resp, body, http.StatusCreated)
+	rechirp := decodeBody[Chirp](t, body)
+	resp, body = doRequest(t, srv, http.MethodPatch, "/api/chirps/"+rechirp.ID.String(), bearer(bob.Token), map[string]string{"body": "x"})
+	expectStatus(t, resp, body, http.StatusBadRequest)
+
+	cfg.editWindow = 0
+	edit(alice.Token, "too late", http.StatusForbidden)
+
+	// purging a chirp that is kept as a tombstone drops its history
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/chirps", bearer(bob.Token), map[string]any{"body": "reply", "in_reply_to": chirp.ID})
+	expectStatus(t, resp, body, http.StatusCreated)
+	resp, body = doRequest(t, srv, http.MethodDelete, path, bearer(alice.Token), nil)
+	expectStatus(t, resp, body, http.StatusNoContent)
+	cfg.trashRetention = 0
+	if _, err := cfg.purgeExpiredTrash(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	resp, body = doRequest(t, srv, http.MethodGet, path+"/revisions", "", nil)
+	expectStatus(t, resp, body, http.StatusNotFound)
+	if left, _ := cfg.dbQueries.GetChirpRevisions(context.Background(), chirp.ID); len(left) != 0 {
+		t.Errorf("%d revisions survive the tombstone", len(left))
+	}
+}
+
+// failingTagStore can't save hashtags, inside a transaction or out.
+type failingTagStore struct {
+	database.Store
+}
+
+func (s failingTagStore) AddChirpTag(ctx context.Context, arg database.AddChirpTagParams) error {
+	return errors.New("hashtags are down")
+}
+
+func (s failingTagStore) InTx(ctx context.Context, fn func(database.Store) error) error {
+	return s.Store.InTx(ctx, func(database.Store) error { return fn(s) })
+}
+
+func TestEditChirpRollsBack(t *testing.T) {
+	cfg, srv := newTestServer(t)
+	alice := createUser(t, srv, "alice-edit-fail@example.com", "pw")
+	chirp := createChirp(t, srv, alice.Token, "the #original")
+	path := "/api/chirps/" + chirp.ID.String()
+
+	cfg.dbQueries = failingTagStore{cfg.dbQueries}
+	resp, body := doRequest(t, srv, http.MethodPatch, path, bearer(alice.Token), map[string]string{"body": "the #edit"})
+	expectStatus(t, resp, body, http.StatusInternalServerError)
+
+	resp, body = doRequest(t, srv, http.MethodGet, path, "", nil)
+	expectStatus(t, resp, body, http.StatusOK)
+	got := decodeBody[Chirp](t, body)
+	if got.Body != "the #original" || got.Edited || len(got.Entities.Hashtags) != 1 || got.Entities.Hashtags[0].Tag != "original" {
+		t.Errorf("chirp after a failed edit = %+v", got)
+	}
+	resp, body = doRequest(t, srv, http.MethodGet, path+"/revisions", "", nil)
+	expectStatus(t, resp, body, http.StatusOK)
+	if got := decodeBody[struct {
+		Revisions []ChirpRevision `json:"revisions"`
+	}](t, body).Revisions; len(got) != 0 {
+		t.Errorf("revisions after a failed edit = %+v", got)
+	}
+}
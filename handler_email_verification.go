@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	internal "github.com/natretsel/chirpy/internal/auth"
+	"github.com/natretsel/chirpy/internal/database"
+	"github.com/natretsel/chirpy/internal/mailer"
+)
+
+// emailVerificationLifetime is how long an emailed verification token
+// stays usable.
+const emailVerificationLifetime = 24 * time.Hour
+
+// errCodeUnverified is the code in the error response a user gets for
+// an action their unverified account isn't allowed.
+const errCodeUnverified = "email_unverified"
+
+// Actions that can be allowed to users who haven't verified their
+// email, through UNVERIFIED_ACTIONS.
+const (
+	actionChirp  = "chirp"
+	actionMedia  = "media"
+	actionReact  = "react"
+	actionFollow = "follow"
+	actionReport = "report"
+)
+
+var unverifiedActionNames = []string{actionChirp, actionMedia, actionReact, actionFollow, actionReport}
+
+// defaultUnverifiedActions is used when UNVERIFIED_ACTIONS is not set.
+const defaultUnverifiedActions = "follow,react"
+
+// parseUnverifiedActions splits a comma separated action list, dropping
+// blanks and duplicates. "none" allows nothing.
+func parseUnverifiedActions(list string) ([]string, error) {
+	actions := []string{}
+	if strings.TrimSpace(list) == "none" {
+		return actions, nil
+	}
+	for _, action := range strings.Split(list, ",") {
+		action = strings.TrimSpace(action)
+		if action == "" || slices.Contains(actions, action) {
+			continue
+		}
+		if !slices.Contains(unverifiedActionNames, action) {
+			return nil, fmt.Errorf("unknown action %q, want one of %v", action, strings.Join(unverifiedActionNames, ", "))
+		}
+		actions = append(actions, action)
+	}
+	return actions, nil
+}
+
+// checkVerified reports whether user may take action, refusing the
+// request if their email is unverified and the action isn't allowed
+// to unverified users.
+func (cfg *apiConfig) checkVerified(w http.ResponseWriter, user database.User, action string) bool {
+	if user.VerifiedAt.Valid || slices.Contains(cfg.unverifiedActions, action) {
+		return true
+	}
+	type response struct {
+		Error string `json:"error"`
+		Code  string `json:"code"`
+	}
+	respondWithJSON(w, http.StatusForbidden, response{
+		Error: "verify your email address first",
+		Code:  errCodeUnverified,
+	})
+	return false
+}
+
+// requireVerified is checkVerified for handlers that only have the
+// user's ID. It skips the lookup when the action is allowed anyway.
+func (cfg *apiConfig) requireVerified(w http.ResponseWriter, r *http.Request, userID uuid.UUID, action string) bool {
+	if slices.Contains(cfg.unverifiedActions, action) {
+		return true
+	}
+	user, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "invalid access token", err)
+		return false
+	}
+	return cfg.checkVerified(w, user, action)
+}
+
+// emailParam validates the email in a user payload. It has to be a bare
+// address, without a display name or angle brackets.
+func emailParam(email string) (string, error) {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return "", errors.New("email must be a valid email address")
+	}
+	return email, nil
+}
+
+func emailVerificationMessage(to, token string) mailer.Message {
+	return mailer.Message{
+		To:      to,
+		Subject: "Verify your Chirpy email address",
+		Body: fmt.Sprintf(`Use this code to confirm that this is your email address within a day:
+
+%s
+
+If you didn't sign up for Chirpy or change your email, ignore this email.
+`, token),
+	}
+}
+
+// sendEmailVerification emails userID a token that verifies email.
+func (cfg *apiConfig) sendEmailVerification(ctx context.Context, userID uuid.UUID, email string) error {
+	token, err := internal.MakeOneTimeToken()
+	if err != nil {
+		return err
+	}
+	_, err = cfg.dbQueries.CreateEmailVerificationToken(ctx, database.CreateEmailVerificationTokenParams{
+		TokenHash: internal.HashToken(token),
+		UserID:    userID,
+		Email:     email,
+		ExpiresAt: time.Now().UTC().Add(emailVerificationLifetime),
+	})
+	if err != nil {
+		return err
+	}
+	return cfg.mailer.Send(ctx, emailVerificationMessage(email, token))
+}
+
+// handlerVerifyEmail confirms an email address with an emailed token.
+// A token for the account's current email verifies it; one for its
+// pending email makes that the account's email.
+func (cfg *apiConfig) handlerVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Token string `json:"token"`
+	}
+	params := parameters{}
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	verification, err := cfg.dbQueries.UseEmailVerificationToken(r.Context(), internal.HashToken(params.Token))
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusBadRequest, "invalid or expired verification token", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't use verification token", err)
+		return
+	}
+	user, err := cfg.dbQueries.VerifyUserEmail(r.Context(), database.VerifyUserEmailParams{
+		ID:    verification.UserID,
+		Email: verification.Email,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		// the address may have been taken since it was set pending
+		other, lookupErr := cfg.dbQueries.GetUserByEmail(r.Context(), verification.Email)
+		if lookupErr == nil && other.ID != verification.UserID {
+			respondWithError(w, http.StatusConflict, "email already in use", nil)
+			return
+		}
+		user, err = cfg.dbQueries.ApplyPendingEmail(r.Context(), database.ApplyPendingEmailParams{
+			ID:           verification.UserID,
+			PendingEmail: sql.NullString{String: verification.Email, Valid: true},
+		})
+	}
+	// the account's email changed since the token was sent
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusBadRequest, "invalid or expired verification token", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't verify email", err)
+		return
+	}
+	// ADMIN_EMAIL may have been waiting on this to become the first admin
+	if cfg.adminEmail != "" && user.Email == cfg.adminEmail {
+		err = bootstrapAdmin(r.Context(), cfg.dbQueries, cfg.adminEmail)
+		if err != nil {
+			log.Printf("couldn't bootstrap admin: %v", err)
+		}
+		user, err = cfg.dbQueries.GetUserByID(r.Context(), user.ID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "couldn't get user", err)
+			return
+		}
+	}
+	respondWithJSON(w, http.StatusOK, userFromDB(user))
+}
+
+// handlerResendEmailVerification emails a new verification token for
+// the caller's pending email, or their current one if it is unverified.
+func (cfg *apiConfig) handlerResendEmailVerification(w http.ResponseWriter, r *http.Request) {
+	accessToken, err := internal.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "malformed header", err)
+		return
+	}
+	userID, err := cfg.validateAccessToken(r.Context(), accessToken)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "invalid access token", err)
+		return
+	}
+	user, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "invalid access token", err)
+		return
+	}
+	email := user.Email
+	if user.PendingEmail.Valid {
+		email = user.PendingEmail.String
+	} else if user.VerifiedAt.Valid {
+		respondWithError(w, http.StatusConflict, "email already verified", nil)
+		return
+	}
+	err = cfg.sendEmailVerification(r.Context(), user.ID, email)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't send verification email", err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+const verificationSubject = "Verify your Chirpy email address"
+
+func TestEmailVerification(t *testing.T) {
+	cfg, srv := newTestServer(t)
+	user := createUser(t, srv, "new@example.com", "pw")
+	if user.EmailVerified {
+		t.Fatalf("new user = %+v, want an unverified email", user.User)
+	}
+	verify := func(token string, want int) User {
+		t.Helper()
+		resp, body := doRequest(t, srv, http.MethodPost, "/api/users/verify-email", "", map[string]string{"token": token})
+		expectStatus(t, resp, body, want)
+		if want != http.StatusOK {
+			return User{}
+		}
+		return decodeBody[User](t, body)
+	}
+
+	resp, body := doRequest(t, srv, http.MethodPost, "/api/users/verify-email/resend", bearer(user.Token), nil)
+	expectStatus(t, resp, body, http.StatusAccepted)
+	tokens := emailedTokens(t, cfg, "new@example.com", verificationSubject)
+	if len(tokens) != 2 || tokens[0] == "" || tokens[0] == tokens[1] {
+		t.Fatalf("emailed tokens = %v, want one from signup and one resent", tokens)
+	}
+
+	verify("not-a-token", http.StatusBadRequest)
+	if got := verify(tokens[0], http.StatusOK); !got.EmailVerified || got.ID != user.ID {
+		t.Errorf("verified user = %+v", got)
+	}
+	verify(tokens[0], http.StatusBadRequest)
+
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/users/verify-email/resend", bearer(user.Token), nil)
+	expectStatus(t, resp, body, http.StatusConflict)
+}
+
+func TestSignupRejectsInvalidEmail(t *testing.T) {
+	_, srv := newTestServer(t)
+	for _, email := range []string{"", "not-an-email", "Someone <someone@example.com>", " padded@example.com"} {
+		resp, body := doRequest(t, srv, http.MethodPost, "/api/users", "", map[string]string{"email": email, "password": "pw"})
+		expectStatus(t, resp, body, http.StatusBadRequest)
+	}
+}
+
+func TestEmailChange(t *testing.T) {
+	cfg, srv := newTestServer(t)
+	user := createUser(t, srv, "before@example.com", "pw")
+	createUser(t, srv, "taken@example.com", "pw")
+	update := func(email string, want int) User {
+		t.Helper()
+		resp, body := doRequest(t, srv, http.MethodPut, "/api/users", bearer(user.Token), map[string]string{"email": email, "password": "pw"})
+		expectStatus(t, resp, body, want)
+		if want != http.StatusOK {
+			return User{}
+		}
+		// every update revokes the caller's access token
+		resp, body = doRequest(t, srv, http.MethodPost, "/api/login", "", map[string]string{"email": "before@example.com", "password": "pw"})
+		expectStatus(t, resp, body, http.StatusOK)
+		user = decodeBody[loginResponse](t, body)
+		return user.User
+	}
+	verify := func(token string, want int) {
+		t.Helper()
+		resp, body := doRequest(t, srv, http.MethodPost, "/api/users/verify-email", "", map[string]string{"token": token})
+		expectStatus(t, resp, body, want)
+	}
+
+	update("taken@example.com", http.StatusConflict)
+	update("invalid", http.StatusBadRequest)
+	update("first@example.com", http.StatusOK)
+	if got := update("after@example.com", http.StatusOK); got.Email != "before@example.com" || got.PendingEmail == nil || *got.PendingEmail != "after@example.com" {
+		t.Errorf("user with a pending email = %+v", got)
+	}
+
+	// a token for an email that is no longer pending can't be used
+	verify(emailedTokens(t, cfg, "first@example.com", verificationSubject)[0], http.StatusBadRequest)
+	verify(emailedTokens(t, cfg, "after@example.com", verificationSubject)[0], http.StatusOK)
+
+	resp, body := doRequest(t, srv, http.MethodPost, "/api/login", "", map[string]string{"email": "after@example.com", "password": "pw"})
+	expectStatus(t, resp, body, http.StatusOK)
+	if got := decodeBody[loginResponse](t, body); got.Email != "after@example.com" || got.PendingEmail != nil || !got.EmailVerified {
+		t.Errorf("user after verifying the new email = %+v", got.User)
+	}
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/login", "", map[string]string{"email": "before@example.com", "password": "pw"})
+	expectStatus(t, resp, body, http.StatusForbidden)
+}
+
+func TestUnverifiedActions(t *testing.T) {
+	cfg, srv := newTestServer(t)
+	actions, err := parseUnverifiedActions(defaultUnverifiedActions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.unverifiedActions = actions
+	user := createUser(t, srv, "unverified@example.com", "pw")
+	other := createUser(t, srv, "other@example.com", "pw")
+
+	resp, body := doRequest(t, srv, http.MethodPost, "/api/chirps", bearer(user.Token), map[string]string{"body": "hello"})
+	expectStatus(t, resp, body, http.StatusForbidden)
+	if got := decodeBody[map[string]string](t, body); got["code"] != errCodeUnverified {
+		t.Errorf("error = %v, want code %v", got, errCodeUnverified)
+	}
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/users/"+other.ID.String()+"/report", bearer(user.Token), map[string]string{"category": "spam"})
+	expectStatus(t, resp, body, http.StatusForbidden)
+	// following is allowed by default
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/users/"+other.ID.String()+"/follow", bearer(user.Token), nil)
+	expectStatus(t, resp, body, http.StatusNoContent)
+
+	tokens := emailedTokens(t, cfg, "unverified@example.com", verificationSubject)
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/users/verify-email", "", map[string]string{"token": tokens[0]})
+	expectStatus(t, resp, body, http.StatusOK)
+	createChirp(t, srv, user.Token, "hello")
+}
+
+func TestParseUnverifiedActions(t *testing.T) {
+	got, err := parseUnverifiedActions(" follow, react,follow,")
+	if err != nil || len(got) != 2 || got[0] != actionFollow || got[1] != actionReact {
+		t.Errorf("parseUnverifiedActions() = %v, %v", got, err)
+	}
+	if got, err := parseUnverifiedActions("none"); err != nil || len(got) != 0 {
+		t.Errorf("parseUnverifiedActions(none) = %v, %v", got, err)
+	}
+	if _, err := parseUnverifiedActions("follow,teleport"); err == nil {
+		t.Error("an unknown action was accepted")
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	internal "github.com/natretsel/chirpy/internal/auth"
+	"github.com/natretsel/chirpy/internal/database"
+)
+
+// Follow is one edge of the follow graph as seen from the listed user:
+// UserID is the follower or followee on the other end.
+type Follow struct {
+	UserID     uuid.UUID `json:"user_id"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+type followsPage struct {
+	Users      []Follow `json:"users"`
+	NextCursor string   `json:"next_cursor"`
+}
+
+func (cfg *apiConfig) handlerFollowUser(w http.ResponseWriter, r *http.Request) {
+	accessToken, err := internal.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "malformed header", err)
+		return
+	}
+	userID, err := cfg.validateAccessToken(r.Context(), accessToken)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "invalid access token", err)
+		return
+	}
+	if !cfg.requireVerified(w, r, userID, actionFollow) {
+		return
+	}
+	followee, ok := cfg.pathUser(w, r)
+	if !ok {
+		return
+	}
+	if followee.ID == userID {
+		respondWithError(w, http.StatusBadRequest, "cannot follow yourself", nil)
+		return
+	}
+
+	err = cfg.dbQueries.FollowUser(r.Context(), database.FollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followee.ID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't follow user", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerUnfollowUser(w http.ResponseWriter, r *http.Request) {
+	accessToken, err := internal.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "malformed header", err)
+		return
+	}
+	userID, err := cfg.validateAccessToken(r.Context(), accessToken)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "invalid access token", err)
+		return
+	}
+	followeeID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid user ID", err)
+		return
+	}
+
+	err = cfg.dbQueries.UnfollowUser(r.Context(), database.UnfollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't unfollow user", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerListFollowers(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.pathUser(w, r)
+	if !ok {
+		return
+	}
+	limit, cursor, err := parsePage(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	params := database.ListFollowersParams{
+		FolloweeID: user.ID,
+		Limit:      int32(limit + 1),
+	}
+	if cursor != nil {
+		params.BeforeCreatedAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+		params.BeforeID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+	follows, err := cfg.dbQueries.ListFollowers(r.Context(), params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't list followers", err)
+		return
+	}
+	respondWithFollows(w, r, follows, limit, func(f database.Follow) uuid.UUID { return f.FollowerID })
+}
+
+func (cfg *apiConfig) handlerListFollowing(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.pathUser(w, r)
+	if !ok {
+		return
+	}
+	limit, cursor, err := parsePage(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	params := database.ListFollowingParams{
+		FollowerID: user.ID,
+		Limit:      int32(limit + 1),
+	}
+	if cursor != nil {
+		params.BeforeCreatedAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+		params.BeforeID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+	follows, err := cfg.dbQueries.ListFollowing(r.Context(), params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't list followed users", err)
+		return
+	}
+	respondWithFollows(w, r, follows, limit, func(f database.Follow) uuid.UUID { return f.FolloweeID })
+}
+
+// respondWithFollows writes one page of follows, which were fetched with
+// one extra row to detect a next page. other picks the listed user.
+func respondWithFollows(w http.ResponseWriter, r *http.Request, follows []database.Follow, limit int, other func(database.Follow) uuid.UUID) {
+	page := followsPage{Users: []Follow{}}
+	if len(follows) > limit {
+		follows = follows[:limit]
+		last := follows[len(follows)-1]
+		page.NextCursor = encodeCursor(last.CreatedAt, other(last))
+	}
+	for _, f := range follows {
+		page.Users = append(page.Users, Follow{
+			UserID:     other(f),
+			FollowedAt: f.CreatedAt,
+		})
+	}
+	setNextLink(w, r, page.NextCursor)
+	respondWithJSON(w, http.StatusOK, page)
+}
+
+// pathUser loads the user named by the {id} path value, responding with
+// an error and returning false if it is malformed or unknown.
+func (cfg *apiConfig) pathUser(w http.ResponseWriter, r *http.Request) (database.User, bool) {
+	userID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid user ID", err)
+		return database.User{}, false
+	}
+	user, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "user not found", err)
+		return database.User{}, false
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't get user", err)
+		return database.User{}, false
+	}
+	return user, true
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestFollowAndTimeline(t *testing.T) {
+	_, srv := newTestServer(t)
+	alice := createUser(t, srv, "alice-follows@example.com", "pw")
+	bob := createUser(t, srv, "bob-follows@example.com", "pw")
+	carol := createUser(t, srv, "carol-follows@example.com", "pw")
+	followPath := func(id uuid.UUID) string { return "/api/users/" + id.String() + "/follow" }
+
+	resp, body := doRequest(t, srv, http.MethodPost, followPath(bob.ID), "", nil)
+	expectStatus(t, resp, body, http.StatusUnauthorized)
+	resp, body = doRequest(t, srv, http.MethodPost, followPath(alice.ID), bearer(alice.Token), nil)
+	expectStatus(t, resp, body, http.StatusBadRequest)
+	resp, body = doRequest(t, srv, http.MethodPost, followPath(uuid.New()), bearer(alice.Token), nil)
+	expectStatus(t, resp, body, http.StatusNotFound)
+	for range 2 {
+		resp, body = doRequest(t, srv, http.MethodPost, followPath(bob.ID), bearer(alice.Token), nil)
+		expectStatus(t, resp, body, http.StatusNoContent)
+	}
+	resp, body = doRequest(t, srv, http.MethodPost, followPath(bob.ID), bearer(carol.Token), nil)
+	expectStatus(t, resp, body, http.StatusNoContent)
+
+	resp, body = doRequest(t, srv, http.MethodGet, "/api/users/"+bob.ID.String()+"/followers", "", nil)
+	expectStatus(t, resp, body, http.StatusOK)
+	followers := decodeBody[followsPage](t, body).Users
+	if len(followers) != 2 || followers[0].UserID != carol.ID || followers[1].UserID != alice.ID {
+		t.Errorf("bob's followers = %+v, want carol then alice", followers)
+	}
+	resp, body = doRequest(t, srv, http.MethodGet, "/api/users/"+alice.ID.String()+"/following?limit=1", "", nil)
+	expectStatus(t, resp, body, http.StatusOK)
+	following := decodeBody[followsPage](t, body)
+	if len(following.Users) != 1 || following.Users[0].UserID != bob.ID || following.NextCursor != "" {
+		t.Errorf("alice's following = %+v, want just bob", following)
+	}
+
+	own := createChirp(t, srv, alice.Token, "mine")
+	followed := createChirp(t, srv, bob.Token, "from bob")
+	createChirp(t, srv, carol.Token, "carol is not followed")
+	timeline := func() []uuid.UUID {
+		t.Helper()
+		resp, body := doRequest(t, srv, http.MethodGet, "/api/timeline", bearer(alice.Token), nil)
+		expectStatus(t, resp, body, http.StatusOK)
+		var ids []uuid.UUID
+		for _, c := range decodeBody[chirpsPage](t, body).Chirps {
+			ids = append(ids, c.ID)
+		}
+		return ids
+	}
+	if got := timeline(); len(got) != 2 || got[0] != followed.ID || got[1] != own.ID {
+		t.Errorf("timeline = %v, want [%v %v]", got, followed.ID, own.ID)
+	}
+
+	resp, body = doRequest(t, srv, http.MethodDelete, followPath(bob.ID), bearer(alice.Token), nil)
+	expectStatus(t, resp, body, http.StatusNoContent)
+	if got := timeline(); len(got) != 1 || got[0] != own.ID {
+		t.Errorf("timeline after unfollow = %v, want [%v]", got, own.ID)
+	}
+	resp, body = doRequest(t, srv, http.MethodGet, "/api/timeline", "", nil)
+	expectStatus(t, resp, body, http.StatusUnauthorized)
+}
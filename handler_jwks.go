@@ -0,0 +1,22 @@
+package main
+
+import (
+	"net/http"
+
+	internal "github.com/natretsel/chirpy/internal/auth"
+)
+
+// jwksCacheControl lets verifiers cache the key set for five minutes. A
+// new signing key should be published at least that long before it
+// becomes active.
+const jwksCacheControl = "public, max-age=300"
+
+// handlerJWKS publishes the public keys that verify Chirpy access
+// tokens, so other services can check them without calling us.
+func (cfg *apiConfig) handlerJWKS(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Keys []internal.JWK `json:"keys"`
+	}
+	w.Header().Set("Cache-Control", jwksCacheControl)
+	respondWithJSON(w, http.StatusOK, response{Keys: cfg.keys.JWKS()})
+}
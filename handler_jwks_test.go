@@ -0,0 +1,31 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	internal "github.com/natretsel/chirpy/internal/auth"
+)
+
+func TestJWKS(t *testing.T) {
+	_, srv := newTestServer(t)
+	user := createUser(t, srv, "jwks@example.com", "pw")
+
+	resp, body := doRequest(t, srv, http.MethodGet, "/.well-known/jwks.json", "", nil)
+	expectStatus(t, resp, body, http.StatusOK)
+	if got := resp.Header.Get("Cache-Control"); !strings.Contains(got, "max-age") {
+		t.Errorf("Cache-Control = %q, want the key set cacheable", got)
+	}
+	jwks := decodeBody[struct {
+		Keys []internal.JWK `json:"keys"`
+	}](t, body).Keys
+	token, _, err := jwt.NewParser().ParseUnverified(user.Token, &internal.Claims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jwks) != 1 || jwks[0].KeyID != token.Header["kid"] || jwks[0].Alg != token.Method.Alg() {
+		t.Errorf("keys = %+v, want the key that signed %v", jwks, token.Header)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/google/uuid"
 	internal "github.com/natretsel/chirpy/internal/auth"
 	"github.com/natretsel/chirpy/internal/database"
 )
@@ -36,28 +37,30 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password", err)
 		return
 	}
-	timeToExpiry := time.Hour
-
-	jwtToken, err := internal.MakeJWT(user.ID, cfg.secret, timeToExpiry)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Error generating jwtToken", err)
+	if isSuspended(user) {
+		respondSuspended(w, user)
+		return
+	}
+	if user.TotpEnabledAt.Valid {
+		cfg.respondMFAChallenge(w, r, user)
 		return
 	}
+	cfg.respondLogin(w, r, user)
+}
+
+// respondLogin starts a session for user and responds with its tokens.
+func (cfg *apiConfig) respondLogin(w http.ResponseWriter, r *http.Request, user database.User) {
+	timeToExpiry := time.Hour
 
-	refreshToken, err := internal.MakeRefreshToken()
+	// every login starts a new session, which is a refresh token family
+	sessionID := uuid.New()
+	jwtToken, err := internal.MakeJWT(user.ID, user.Role, sessionID, cfg.keys, timeToExpiry)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Error generating Refresh token", err)
+		respondWithError(w, http.StatusInternalServerError, "Error generating jwtToken", err)
 		return
 	}
 
-	// store refresh token in DB
-	_, err = cfg.dbQueries.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
-		Token:     refreshToken,
-		UpdatedAt: time.Now().UTC(),
-		UserID:    user.ID,
-		ExpiresAt: time.Now().UTC().Add(time.Hour * 24 * 60),
-	})
-
+	refreshToken, err := cfg.issueRefreshToken(r, user.ID, sessionID)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error creating refresh token in DB", err)
 		return
@@ -68,13 +71,7 @@ func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
 		RefreshToken string `json:"refresh_token"`
 	}
 	respondWithJSON(w, http.StatusOK, response{
-		User: User{
-			ID:            user.ID,
-			CreatedAt:     user.CreatedAt,
-			UpdatedAt:     user.UpdatedAt,
-			Email:         user.Email,
-			Is_chirpy_red: user.IsChirpyRed.Bool,
-		},
+		User:         userFromDB(user),
 		Token:        jwtToken,
 		RefreshToken: refreshToken,
 	})
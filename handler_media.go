@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	internal "github.com/natretsel/chirpy/internal/auth"
+	"github.com/natretsel/chirpy/internal/blobstore"
+	"github.com/natretsel/chirpy/internal/database"
+)
+
+const (
+	maxChirpMedia       = 4
+	maxAltTextLength    = 1000
+	freeMediaBytes      = 5 << 20
+	chirpyRedMediaBytes = 20 << 20
+	// multipartOverhead allows for the boundaries and part headers
+	// around the uploaded file.
+	multipartOverhead = 64 << 10
+)
+
+// allowedMediaTypes are the content types uploads may sniff as.
+var allowedMediaTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+	"video/mp4":  true,
+	"video/webm": true,
+}
+
+// Media is an uploaded file. URL is where it's served from.
+type Media struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	URL         string    `json:"url"`
+	ContentType string    `json:"content_type"`
+	SizeBytes   int64     `json:"size_bytes"`
+}
+
+// MediaAttachment is a media file as attached to a chirp.
+type MediaAttachment struct {
+	ID          uuid.UUID `json:"id"`
+	URL         string    `json:"url"`
+	ContentType string    `json:"content_type"`
+	AltText     string    `json:"alt_text"`
+}
+
+func mediaURL(id uuid.UUID) string {
+	return "/api/media/" + id.String()
+}
+
+// maxMediaBytes is the upload size limit for user's tier.
+func maxMediaBytes(user database.User) int64 {
+	if user.IsChirpyRed.Bool {
+		return chirpyRedMediaBytes
+	}
+	return freeMediaBytes
+}
+
+func (cfg *apiConfig) handlerMediaUpload(w http.ResponseWriter, r *http.Request) {
+	accessToken, err := internal.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "malformed header", err)
+		return
+	}
+	userID, err := cfg.validateAccessToken(r.Context(), accessToken)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "invalid access token", err)
+		return
+	}
+	user, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid user", err)
+		return
+	}
+	if !cfg.checkVerified(w, user, actionMedia) {
+		return
+	}
+	limit := maxMediaBytes(user)
+	r.Body = http.MaxBytesReader(w, r.Body, limit+multipartOverhead)
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "expected a multipart/form-data upload", err)
+		return
+	}
+	var file io.Reader
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			respondWithError(w, http.StatusBadRequest, "missing file field", nil)
+			return
+		}
+		if err != nil {
+			respondWithUploadError(w, err)
+			return
+		}
+		if part.FormName() == "file" {
+			file = part
+			break
+		}
+	}
+
+	// sniff the first bytes rather than trusting the client's type
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		respondWithUploadError(w, err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusBadRequest, "file is empty", nil)
+		return
+	}
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head[:n]))
+	if !allowedMediaTypes[contentType] {
+		respondWithError(w, http.StatusUnsupportedMediaType, "unsupported media type "+contentType, nil)
+		return
+	}
+
+	mediaID := uuid.New()
+	key := mediaID.String()
+	body := &io.LimitedReader{R: io.MultiReader(bytes.NewReader(head[:n]), file), N: limit + 1}
+	err = cfg.media.Put(r.Context(), key, body)
+	if err != nil {
+		cfg.deleteBlob(r.Context(), key)
+		respondWithUploadError(w, err)
+		return
+	}
+	size := limit + 1 - body.N
+	if size > limit {
+		cfg.deleteBlob(r.Context(), key)
+		respondWithError(w, http.StatusRequestEntityTooLarge, "file is larger than "+strconv.FormatInt(limit>>20, 10)+" MiB", nil)
+		return
+	}
+
+	media, err := cfg.dbQueries.CreateMedia(r.Context(), database.CreateMediaParams{
+		ID:          mediaID,
+		UserID:      userID,
+		ContentType: contentType,
+		SizeBytes:   size,
+	})
+	if err != nil {
+		cfg.deleteBlob(r.Context(), key)
+		respondWithError(w, http.StatusInternalServerError, "couldn't save media", err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, Media{
+		ID:          media.ID,
+		CreatedAt:   media.CreatedAt,
+		URL:         mediaURL(media.ID),
+		ContentType: media.ContentType,
+		SizeBytes:   media.SizeBytes,
+	})
+}
+
+// respondWithUploadError reports a failure reading or storing an
+// upload, which is the client's fault when the body was too large.
+func respondWithUploadError(w http.ResponseWriter, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		respondWithError(w, http.StatusRequestEntityTooLarge, "upload is too large", err)
+		return
+	}
+	respondWithError(w, http.StatusInternalServerError, "couldn't store upload", err)
+}
+
+// deleteBlob cleans up after a failed upload. Failing to do so only
+// leaves an orphaned file, so it is logged rather than reported.
+func (cfg *apiConfig) deleteBlob(ctx context.Context, key string) {
+	if err := cfg.media.Delete(ctx, key); err != nil {
+		log.Printf("couldn't delete blob %s: %v", key, err)
+	}
+}
+
+func (cfg *apiConfig) handlerMediaGet(w http.ResponseWriter, r *http.Request) {
+	mediaID, err := uuid.Parse(r.PathValue("mediaID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid media ID", err)
+		return
+	}
+	media, err := cfg.dbQueries.GetMediaByID(r.Context(), mediaID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "media not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't get media", err)
+		return
+	}
+	blob, err := cfg.media.Open(r.Context(), media.ID.String())
+	if errors.Is(err, blobstore.ErrNotFound) {
+		respondWithError(w, http.StatusNotFound, "media not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't open media", err)
+		return
+	}
+	defer blob.Close()
+
+	w.Header().Set("Content-Type", media.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(media.SizeBytes, 10))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, blob); err != nil {
+		log.Printf("couldn't send media %s: %v", media.ID, err)
+	}
+}
+
+// mediaParam is a media file referenced by a new chirp's payload.
+type mediaParam struct {
+	ID      uuid.UUID `json:"id"`
+	AltText string    `json:"alt_text"`
+}
+
+// checkMediaParams validates the media a new chirp attaches: at most
+// four of the author's own uploads, each once. It responds with an
+// error and returns false if any is unusable.
+func (cfg *apiConfig) checkMediaParams(w http.ResponseWriter, r *http.Request, userID uuid.UUID, params []mediaParam) bool {
+	if len(params) > maxChirpMedia {
+		respondWithError(w, http.StatusBadRequest, "A chirp can have at most "+strconv.Itoa(maxChirpMedia)+" media attachments", nil)
+		return false
+	}
+	seen := make(map[uuid.UUID]bool, len(params))
+	for _, param := range params {
+		if seen[param.ID] {
+			respondWithError(w, http.StatusBadRequest, "media attached more than once", nil)
+			return false
+		}
+		seen[param.ID] = true
+		if utf8.RuneCountInString(param.AltText) > maxAltTextLength {
+			respondWithError(w, http.StatusBadRequest, "alt text is too long", nil)
+			return false
+		}
+		media, err := cfg.dbQueries.GetMediaByID(r.Context(), param.ID)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && media.UserID != userID) {
+			respondWithError(w, http.StatusBadRequest, "media doesn't exist", err)
+			return false
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't get media", err)
+			return false
+		}
+	}
+	return true
+}
+
+// attachMedia fills in the media attached to chirps.
+func (cfg *apiConfig) attachMedia(ctx context.Context, chirps []*Chirp) error {
+	ids := make([]uuid.UUID, 0, len(chirps))
+	byID := make(map[uuid.UUID][]*Chirp, len(chirps))
+	for _, c := range chirps {
+		c.Media = []MediaAttachment{}
+		ids = append(ids, c.ID)
+		byID[c.ID] = append(byID[c.ID], c)
+	}
+	rows, err := cfg.dbQueries.GetChirpMedia(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		for _, c := range byID[row.ChirpID] {
+			c.Media = append(c.Media, MediaAttachment{
+				ID:          row.Media.ID,
+				URL:         mediaURL(row.Media.ID),
+				ContentType: row.Media.ContentType,
+				AltText:     row.AltText,
+			})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/natretsel/chirpy/internal/database"
+)
+
+// uploadMedia posts data as the file field of a multipart upload.
+func uploadMedia(t *testing.T, srv *httptest.Server, token string, data []byte) (*http.Response, []byte) {
+	t.Helper()
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	part, err := writer.CreateFormFile("file", "upload.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(data)
+	writer.Close()
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/api/media", &form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Authorization", bearer(token))
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, body
+}
+
+func pngBytes(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestMediaUpload(t *testing.T) {
+	_, srv := newTestServer(t)
+	user := createUser(t, srv, "media@example.com", "pw")
+	picture := pngBytes(t)
+
+	resp, body := uploadMedia(t, srv, user.Token, picture)
+	expectStatus(t, resp, body, http.StatusCreated)
+	media := decodeBody[Media](t, body)
+	if media.ContentType != "image/png" || media.SizeBytes != int64(len(picture)) || media.URL != "/api/media/"+media.ID.String() {
+		t.Errorf("uploaded media = %+v", media)
+	}
+
+	resp, body = doRequest(t, srv, http.MethodGet, media.URL, "", nil)
+	expectStatus(t, resp, body, http.StatusOK)
+	if !bytes.Equal(body, picture) || resp.Header.Get("Content-Type") != "image/png" {
+		t.Errorf("served %d bytes as %q, want the upload as image/png", len(body), resp.Header.Get("Content-Type"))
+	}
+	resp, body = doRequest(t, srv, http.MethodGet, "/api/media/"+uuid.NewString(), "", nil)
+	expectStatus(t, resp, body, http.StatusNotFound)
+
+	// the type is sniffed, whatever the client claims
+	resp, body = uploadMedia(t, srv, user.Token, []byte("<html><script>alert(1)</script></html>"))
+	expectStatus(t, resp, body, http.StatusUnsupportedMediaType)
+	resp, body = uploadMedia(t, srv, user.Token, nil)
+	expectStatus(t, resp, body, http.StatusBadRequest)
+	resp, body = uploadMedia(t, srv, "not-a-token", picture)
+	expectStatus(t, resp, body, http.StatusUnauthorized)
+
+	// free accounts are capped lower than Chirpy Red
+	large := append(pngBytes(t), make([]byte, freeMediaBytes)...)
+	resp, body = uploadMedia(t, srv, user.Token, large)
+	expectStatus(t, resp, body, http.StatusRequestEntityTooLarge)
+	upgrade := map[string]any{"event": "user.upgraded", "data": map[string]any{"user_id": user.ID}}
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/polka/webhooks", "ApiKey "+testPolkaKey, upgrade)
+	expectStatus(t, resp, body, http.StatusNoContent)
+	resp, body = uploadMedia(t, srv, user.Token, large)
+	expectStatus(t, resp, body, http.StatusCreated)
+}
+
+func TestChirpMedia(t *testing.T) {
+	_, srv := newTestServer(t)
+	alice := createUser(t, srv, "alice-media@example.com", "pw")
+	bob := createUser(t, srv, "bob-media@example.com", "pw")
+	upload := func(token string) uuid.UUID {
+		t.Helper()
+		resp, body := uploadMedia(t, srv, token, pngBytes(t))
+		expectStatus(t, resp, body, http.StatusCreated)
+		return decodeBody[Media](t, body).ID
+	}
+	var mine []uuid.UUID
+	for range maxChirpMedia + 1 {
+		mine = append(mine, upload(alice.Token))
+	}
+	post := func(payload map[string]any, want int) Chirp {
+		t.Helper()
+		resp, body := doRequest(t, srv, http.MethodPost, "/api/chirps", bearer(alice.Token), payload)
+		expectStatus(t, resp, body, want)
+		if want != http.StatusCreated {
+			return Chirp{}
+		}
+		return decodeBody[Chirp](t, body)
+	}
+
+	chirp := post(map[string]any{
+		"body": "look",
+		"media": []map[string]any{
+			{"id": mine[1], "alt_text": "second upload"},
+			{"id": mine[0]},
+		},
+	}, http.StatusCreated)
+	want := []MediaAttachment{
+		{ID: mine[1], URL: "/api/media/" + mine[1].String(), ContentType: "image/png", AltText: "second upload"},
+		{ID: mine[0], URL: "/api/media/" + mine[0].String(), ContentType: "image/png"},
+	}
+	resp, body := doRequest(t, srv, http.MethodGet, "/api/chirps/"+chirp.ID.String(), "", nil)
+	expectStatus(t, resp, body, http.StatusOK)
+	if got := decodeBody[Chirp](t, body).Media; len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("media = %+v, want %+v", got, want)
+	}
+	if plain := createChirp(t, srv, alice.Token, "no pictures"); plain.Media == nil || len(plain.Media) != 0 {
+		t.Errorf("media of a plain chirp = %#v, want empty", plain.Media)
+	}
+
+	tooMany := []map[string]any{}
+	for _, id := range mine {
+		tooMany = append(tooMany, map[string]any{"id": id})
+	}
+	post(map[string]any{"body": "too many", "media": tooMany}, http.StatusBadRequest)
+	post(map[string]any{"body": "twice", "media": []map[string]any{{"id": mine[0]}, {"id": mine[0]}}}, http.StatusBadRequest)
+	post(map[string]any{"body": "not mine", "media": []map[string]any{{"id": upload(bob.Token)}}}, http.StatusBadRequest)
+	post(map[string]any{"body": "missing", "media": []map[string]any{{"id": uuid.New()}}}, http.StatusBadRequest)
+	post(map[string]any{"rechirp_of": chirp.ID, "media": []map[string]any{{"id": mine[2]}}}, http.StatusBadRequest)
+}
+
+func TestChirpMediaRollsBack(t *testing.T) {
+	cfg, srv := newTestServer(t)
+	alice := createUser(t, srv, "alice-rollback@example.com", "pw")
+	// a media row that goes missing before it is attached takes the
+	// chirp down with it
+	err := cfg.dbQueries.InTx(context.Background(), func(store database.Store) error {
+		chirp, err := store.CreateChirp(context.Background(), database.CreateChirpParams{Body: "look", UserID: alice.ID})
+		if err != nil {
+			return err
+		}
+		return store.AttachChirpMedia(context.Background(), database.AttachChirpMediaParams{ChirpID: chirp.ID, MediaID: uuid.New()})
+	})
+	if err == nil {
+		t.Fatal("attaching missing media succeeded")
+	}
+	resp, body := doRequest(t, srv, http.MethodGet, "/api/chirps", "", nil)
+	expectStatus(t, resp, body, http.StatusOK)
+	if got := decodeBody[struct {
+		Chirps []Chirp `json:"chirps"`
+	}](t, body).Chirps; len(got) != 0 {
+		t.Errorf("chirps = %+v, want none", got)
+	}
+}
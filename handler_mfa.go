@@ -0,0 +1,461 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	internal "github.com/natretsel/chirpy/internal/auth"
+	"github.com/natretsel/chirpy/internal/database"
+)
+
+const (
+	// totpIssuer names the service in authenticator apps.
+	totpIssuer = "Chirpy"
+	// recoveryCodeCount is how many recovery codes a user gets at a time.
+	recoveryCodeCount = 10
+	// mfaChallengeLifetime is how long a login has to send its second
+	// factor after the password.
+	mfaChallengeLifetime = 5 * time.Minute
+	// maxMFAAttempts is how many wrong codes a challenge takes before it
+	// stops working and the user has to log in again.
+	maxMFAAttempts = 5
+	// maxMFAFailures is how many wrong codes in a row a user can send,
+	// over any number of challenges, before each further one locks
+	// their second factor for mfaLockout.
+	maxMFAFailures = 10
+	mfaLockout     = 15 * time.Minute
+)
+
+// errMFALocked is returned by checkSecondFactor while the user's second
+// factor is locked after too many wrong codes.
+var errMFALocked = errors.New("too many wrong codes; try again later")
+
+// respondCheckFailed answers a request whose second factor couldn't be
+// checked.
+func respondCheckFailed(w http.ResponseWriter, err error) {
+	if errors.Is(err, errMFALocked) {
+		respondWithError(w, http.StatusTooManyRequests, err.Error(), err)
+		return
+	}
+	respondWithError(w, http.StatusInternalServerError, "couldn't check code", err)
+}
+
+// mfaUser authenticates the caller of an enrollment endpoint and loads
+// their user.
+func (cfg *apiConfig) mfaUser(w http.ResponseWriter, r *http.Request) (database.User, bool) {
+	accessToken, err := internal.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "malformed header", err)
+		return database.User{}, false
+	}
+	userID, err := cfg.validateAccessToken(r.Context(), accessToken)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "invalid access token", err)
+		return database.User{}, false
+	}
+	user, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "invalid access token", err)
+		return database.User{}, false
+	}
+	return user, true
+}
+
+// decodeMFACode reads the code in a request body.
+func decodeMFACode(w http.ResponseWriter, r *http.Request) (string, bool) {
+	type parameters struct {
+		Code string `json:"code"`
+	}
+	params := parameters{}
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return "", false
+	}
+	if params.Code == "" {
+		respondWithError(w, http.StatusBadRequest, "code is required", nil)
+		return "", false
+	}
+	return params.Code, true
+}
+
+// reauthParameters is the body of an enrollment request, which
+// proves the caller is the user and not just someone with their access
+// token.
+type reauthParameters struct {
+	Password string `json:"password"`
+	// Code is a current TOTP or recovery code, needed only once TOTP
+	// is on. Confirming enrollment takes the first code from the new
+	// secret here instead.
+	Code string `json:"code"`
+}
+
+// reauthenticate checks the caller's current password and, if TOTP is
+// already on, a fresh second factor. It responds to the request itself
+// and returns false if either is wrong.
+func (cfg *apiConfig) reauthenticate(w http.ResponseWriter, r *http.Request, user database.User, params reauthParameters) bool {
+	err := internal.CheckPasswordHash(user.HashedPassword, params.Password)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "incorrect password", err)
+		return false
+	}
+	if !user.TotpEnabledAt.Valid {
+		return true
+	}
+	ok, err := cfg.checkSecondFactor(r.Context(), user, params.Code)
+	if err != nil {
+		respondCheckFailed(w, err)
+		return false
+	}
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, "invalid code", nil)
+		return false
+	}
+	return true
+}
+
+// checkSecondFactor reports whether code is a TOTP code or an unused
+// recovery code of user, and uses it up so it can't be sent again.
+// Wrong codes count towards locking the user's second factor.
+func (cfg *apiConfig) checkSecondFactor(ctx context.Context, user database.User, code string) (bool, error) {
+	if !user.TotpEnabledAt.Valid {
+		return false, nil
+	}
+	if user.MfaLockedUntil.Valid && user.MfaLockedUntil.Time.After(time.Now()) {
+		return false, errMFALocked
+	}
+	ok, err := cfg.useSecondFactor(ctx, user, code)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		return false, cfg.dbQueries.RecordMFAFailure(ctx, database.RecordMFAFailureParams{
+			ID:          user.ID,
+			MaxFailures: maxMFAFailures,
+			LockedUntil: time.Now().UTC().Add(mfaLockout),
+		})
+	}
+	if user.MfaFailedAttempts > 0 {
+		err = cfg.dbQueries.ResetMFAFailures(ctx, user.ID)
+		if err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// useSecondFactor uses up code if it is one of user's.
+func (cfg *apiConfig) useSecondFactor(ctx context.Context, user database.User, code string) (bool, error) {
+	if step, ok := internal.ValidateTOTP(user.TotpSecret.String, code, time.Now()); ok {
+		n, err := cfg.dbQueries.UseTOTPStep(ctx, database.UseTOTPStepParams{
+			ID:           user.ID,
+			TotpLastStep: sql.NullInt64{Int64: step, Valid: true},
+		})
+		return n == 1, err
+	}
+	n, err := cfg.dbQueries.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+		UserID:   user.ID,
+		CodeHash: internal.HashToken(internal.NormalizeRecoveryCode(code)),
+	})
+	return n == 1, err
+}
+
+// replaceRecoveryCodes gives the user a new set of recovery codes,
+// voiding the old ones, and returns them. Only their hashes are kept.
+func (cfg *apiConfig) replaceRecoveryCodes(ctx context.Context, user database.User) ([]string, error) {
+	err := cfg.dbQueries.DeleteUserRecoveryCodes(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	codes := make([]string, 0, recoveryCodeCount)
+	for range recoveryCodeCount {
+		code, err := internal.MakeRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		err = cfg.dbQueries.CreateRecoveryCode(ctx, database.CreateRecoveryCodeParams{
+			CodeHash: internal.HashToken(internal.NormalizeRecoveryCode(code)),
+			UserID:   user.ID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+func respondRecoveryCodes(w http.ResponseWriter, codes []string) {
+	type response struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	respondWithJSON(w, http.StatusOK, response{RecoveryCodes: codes})
+}
+
+// handlerTOTPEnroll starts TOTP enrollment with a new secret. It only
+// takes effect once a code from it is sent to handlerTOTPConfirm. It
+// takes the current password, so a stolen access token isn't enough.
+func (cfg *apiConfig) handlerTOTPEnroll(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.mfaUser(w, r)
+	if !ok {
+		return
+	}
+	params := reauthParameters{}
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	if !cfg.reauthenticate(w, r, user, params) {
+		return
+	}
+	if user.TotpEnabledAt.Valid {
+		respondWithError(w, http.StatusConflict, "two-factor authentication is already on", nil)
+		return
+	}
+	secret, err := internal.GenerateTOTPSecret()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't create TOTP secret", err)
+		return
+	}
+	_, err = cfg.dbQueries.StartTOTPEnrollment(r.Context(), database.StartTOTPEnrollmentParams{
+		ID:         user.ID,
+		TotpSecret: sql.NullString{String: secret, Valid: true},
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusConflict, "two-factor authentication is already on", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't start TOTP enrollment", err)
+		return
+	}
+	type response struct {
+		Secret     string `json:"secret"`
+		OTPAuthURI string `json:"otpauth_uri"`
+	}
+	respondWithJSON(w, http.StatusCreated, response{
+		Secret:     secret,
+		OTPAuthURI: internal.TOTPURI(totpIssuer, user.Email, secret),
+	})
+}
+
+// handlerTOTPConfirm turns TOTP on with a first code from the enrolled
+// secret and responds with the user's recovery codes. Like
+// handlerTOTPEnroll it takes the current password.
+func (cfg *apiConfig) handlerTOTPConfirm(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.mfaUser(w, r)
+	if !ok {
+		return
+	}
+	params := reauthParameters{}
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	if user.TotpEnabledAt.Valid {
+		respondWithError(w, http.StatusConflict, "two-factor authentication is already on", nil)
+		return
+	}
+	// TOTP is off, so code is left for the new secret
+	if !cfg.reauthenticate(w, r, user, params) {
+		return
+	}
+	code := params.Code
+	if code == "" {
+		respondWithError(w, http.StatusBadRequest, "code is required", nil)
+		return
+	}
+	if !user.TotpSecret.Valid {
+		respondWithError(w, http.StatusBadRequest, "start TOTP enrollment first", nil)
+		return
+	}
+	step, ok := internal.ValidateTOTP(user.TotpSecret.String, code, time.Now())
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, "invalid code", nil)
+		return
+	}
+	user, err = cfg.dbQueries.EnableTOTP(r.Context(), database.EnableTOTPParams{
+		ID:           user.ID,
+		TotpLastStep: sql.NullInt64{Int64: step, Valid: true},
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusConflict, "two-factor authentication is already on", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't turn on TOTP", err)
+		return
+	}
+	codes, err := cfg.replaceRecoveryCodes(r.Context(), user)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't create recovery codes", err)
+		return
+	}
+	respondRecoveryCodes(w, codes)
+}
+
+// handlerTOTPDisable turns TOTP off. It takes a current code or a
+// recovery code, so a stolen access token isn't enough.
+func (cfg *apiConfig) handlerTOTPDisable(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.mfaUser(w, r)
+	if !ok {
+		return
+	}
+	code, ok := decodeMFACode(w, r)
+	if !ok {
+		return
+	}
+	if !user.TotpEnabledAt.Valid {
+		respondWithError(w, http.StatusConflict, "two-factor authentication is off", nil)
+		return
+	}
+	ok, err := cfg.checkSecondFactor(r.Context(), user, code)
+	if err != nil {
+		respondCheckFailed(w, err)
+		return
+	}
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, "invalid code", nil)
+		return
+	}
+	err = cfg.dbQueries.DisableTOTP(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't turn off TOTP", err)
+		return
+	}
+	err = cfg.dbQueries.DeleteUserRecoveryCodes(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't delete recovery codes", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerRegenerateRecoveryCodes replaces the caller's recovery codes,
+// for when they have used or lost them.
+func (cfg *apiConfig) handlerRegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.mfaUser(w, r)
+	if !ok {
+		return
+	}
+	code, ok := decodeMFACode(w, r)
+	if !ok {
+		return
+	}
+	if !user.TotpEnabledAt.Valid {
+		respondWithError(w, http.StatusConflict, "two-factor authentication is off", nil)
+		return
+	}
+	ok, err := cfg.checkSecondFactor(r.Context(), user, code)
+	if err != nil {
+		respondCheckFailed(w, err)
+		return
+	}
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, "invalid code", nil)
+		return
+	}
+	codes, err := cfg.replaceRecoveryCodes(r.Context(), user)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't create recovery codes", err)
+		return
+	}
+	respondRecoveryCodes(w, codes)
+}
+
+// respondMFAChallenge answers a correct password from a user with TOTP
+// on. Instead of tokens they get a challenge token to send with their
+// second factor to handlerLoginMFA.
+func (cfg *apiConfig) respondMFAChallenge(w http.ResponseWriter, r *http.Request, user database.User) {
+	token, err := internal.MakeOneTimeToken()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't create MFA challenge", err)
+		return
+	}
+	challenge, err := cfg.dbQueries.CreateMFAChallenge(r.Context(), database.CreateMFAChallengeParams{
+		TokenHash: internal.HashToken(token),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().UTC().Add(mfaChallengeLifetime),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't create MFA challenge", err)
+		return
+	}
+	type response struct {
+		MFARequired bool      `json:"mfa_required"`
+		MFAToken    string    `json:"mfa_token"`
+		ExpiresAt   time.Time `json:"expires_at"`
+	}
+	respondWithJSON(w, http.StatusOK, response{
+		MFARequired: true,
+		MFAToken:    token,
+		ExpiresAt:   challenge.ExpiresAt,
+	})
+}
+
+// handlerLoginMFA completes a login that was answered with an MFA
+// challenge, given a TOTP code or a recovery code.
+func (cfg *apiConfig) handlerLoginMFA(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		MFAToken string `json:"mfa_token"`
+		Code     string `json:"code"`
+	}
+	params := parameters{}
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	tokenHash := internal.HashToken(params.MFAToken)
+	challenge, err := cfg.dbQueries.GetMFAChallenge(r.Context(), database.GetMFAChallengeParams{
+		TokenHash:      tokenHash,
+		FailedAttempts: maxMFAAttempts,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusUnauthorized, "invalid or expired MFA token", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't get MFA challenge", err)
+		return
+	}
+	user, err := cfg.dbQueries.GetUserByID(r.Context(), challenge.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "invalid or expired MFA token", err)
+		return
+	}
+	ok, err := cfg.checkSecondFactor(r.Context(), user, params.Code)
+	if err != nil {
+		respondCheckFailed(w, err)
+		return
+	}
+	if !ok {
+		err = cfg.dbQueries.RecordMFAChallengeFailure(r.Context(), tokenHash)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "couldn't record failed attempt", err)
+			return
+		}
+		respondWithError(w, http.StatusUnauthorized, "invalid code", nil)
+		return
+	}
+	n, err := cfg.dbQueries.UseMFAChallenge(r.Context(), tokenHash)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't use MFA challenge", err)
+		return
+	}
+	if n == 0 {
+		respondWithError(w, http.StatusUnauthorized, "invalid or expired MFA token", nil)
+		return
+	}
+	if isSuspended(user) {
+		respondSuspended(w, user)
+		return
+	}
+	cfg.respondLogin(w, r, user)
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	internal "github.com/natretsel/chirpy/internal/auth"
+	"github.com/natretsel/chirpy/internal/database"
+)
+
+// wrongCode is never a valid TOTP code, which are all digits.
+const wrongCode = "abcdef"
+
+type mfaChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	Token       string `json:"token"`
+}
+
+// enrollTOTP turns TOTP on for user and returns the secret, the code
+// that confirmed it and the recovery codes.
+func enrollTOTP(t *testing.T, srv *httptest.Server, user loginResponse, password string) (string, string, []string) {
+	t.Helper()
+	resp, body := doRequest(t, srv, http.MethodPost, "/api/mfa/totp", bearer(user.Token), map[string]string{"password": password})
+	expectStatus(t, resp, body, http.StatusCreated)
+	enrollment := decodeBody[struct {
+		Secret     string `json:"secret"`
+		OTPAuthURI string `json:"otpauth_uri"`
+	}](t, body)
+	if enrollment.Secret == "" || !strings.HasPrefix(enrollment.OTPAuthURI, "otpauth://totp/Chirpy:") || !strings.Contains(enrollment.OTPAuthURI, "secret="+enrollment.Secret) {
+		t.Fatalf("enrollment = %+v", enrollment)
+	}
+
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/mfa/totp/confirm", bearer(user.Token), map[string]string{"password": password, "code": wrongCode})
+	expectStatus(t, resp, body, http.StatusBadRequest)
+	code := totpCode(t, enrollment.Secret, 0)
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/mfa/totp/confirm", bearer(user.Token), map[string]string{"password": password, "code": code})
+	expectStatus(t, resp, body, http.StatusOK)
+	codes := decodeBody[struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}](t, body).RecoveryCodes
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("recovery codes = %v, want %d", codes, recoveryCodeCount)
+	}
+	return enrollment.Secret, code, codes
+}
+
+// totpCode returns the code for secret the given number of periods
+// from now.
+func totpCode(t *testing.T, secret string, periods int) string {
+	t.Helper()
+	code, err := internal.TOTPCode(secret, time.Now().Add(time.Duration(periods)*30*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+func TestTOTPLogin(t *testing.T) {
+	_, srv := newTestServer(t)
+	user := createUser(t, srv, "careful@example.com", "pw")
+	secret, confirmCode, recoveryCodes := enrollTOTP(t, srv, user, "pw")
+
+	resp, body := doRequest(t, srv, http.MethodPost, "/api/mfa/totp", bearer(user.Token), map[string]string{"password": "pw", "code": recoveryCodes[2]})
+	expectStatus(t, resp, body, http.StatusConflict)
+
+	login := func() string {
+		t.Helper()
+		resp, body := doRequest(t, srv, http.MethodPost, "/api/login", "", map[string]string{"email": "careful@example.com", "password": "pw"})
+		expectStatus(t, resp, body, http.StatusOK)
+		challenge := decodeBody[mfaChallengeResponse](t, body)
+		if !challenge.MFARequired || challenge.MFAToken == "" || challenge.Token != "" {
+			t.Fatalf("login with TOTP on = %s, want only a challenge", body)
+		}
+		return challenge.MFAToken
+	}
+	completeLogin := func(mfaToken, code string, want int) {
+		t.Helper()
+		resp, body := doRequest(t, srv, http.MethodPost, "/api/login/mfa", "", map[string]string{"mfa_token": mfaToken, "code": code})
+		expectStatus(t, resp, body, want)
+		if want == http.StatusOK {
+			if got := decodeBody[loginResponse](t, body); got.Token == "" || got.RefreshToken == "" || !got.MFAEnabled {
+				t.Errorf("completed login = %s", body)
+			}
+		}
+	}
+
+	mfaToken := login()
+	// the code used to confirm enrollment can't be replayed
+	completeLogin(mfaToken, confirmCode, http.StatusUnauthorized)
+	completeLogin("not-a-token", totpCode(t, secret, 1), http.StatusUnauthorized)
+	completeLogin(mfaToken, totpCode(t, secret, 1), http.StatusOK)
+	completeLogin(mfaToken, recoveryCodes[0], http.StatusUnauthorized)
+
+	// recovery codes work once, however they are typed
+	completeLogin(login(), strings.ToUpper(recoveryCodes[0]), http.StatusOK)
+	completeLogin(login(), recoveryCodes[0], http.StatusUnauthorized)
+
+	// a challenge stops working after too many wrong codes
+	mfaToken = login()
+	for range maxMFAAttempts {
+		completeLogin(mfaToken, wrongCode, http.StatusUnauthorized)
+	}
+	completeLogin(mfaToken, recoveryCodes[1], http.StatusUnauthorized)
+	completeLogin(login(), recoveryCodes[1], http.StatusOK)
+}
+
+func TestTOTPEnrollReauthenticates(t *testing.T) {
+	_, srv := newTestServer(t)
+	user := createUser(t, srv, "stolen@example.com", "pw")
+	enroll := func(params map[string]string, want int) string {
+		t.Helper()
+		resp, body := doRequest(t, srv, http.MethodPost, "/api/mfa/totp", bearer(user.Token), params)
+		expectStatus(t, resp, body, want)
+		if want != http.StatusCreated {
+			return ""
+		}
+		return decodeBody[struct {
+			Secret string `json:"secret"`
+		}](t, body).Secret
+	}
+
+	// an access token alone can't start or finish enrollment
+	enroll(nil, http.StatusUnauthorized)
+	enroll(map[string]string{"password": "guess"}, http.StatusUnauthorized)
+	secret := enroll(map[string]string{"password": "pw"}, http.StatusCreated)
+	resp, body := doRequest(t, srv, http.MethodPost, "/api/mfa/totp/confirm", bearer(user.Token), map[string]string{"password": "guess", "code": totpCode(t, secret, 0)})
+	expectStatus(t, resp, body, http.StatusUnauthorized)
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/login", "", map[string]string{"email": "stolen@example.com", "password": "pw"})
+	expectStatus(t, resp, body, http.StatusOK)
+	if got := decodeBody[loginResponse](t, body); got.Token == "" {
+		t.Fatalf("login after a rejected confirmation = %s, want TOTP still off", body)
+	}
+
+	// once TOTP is on, enrolling again also takes a second factor
+	secret, _, recoveryCodes := enrollTOTP(t, srv, user, "pw")
+	enroll(map[string]string{"password": "pw"}, http.StatusBadRequest)
+	enroll(map[string]string{"password": "pw", "code": wrongCode}, http.StatusBadRequest)
+	enroll(map[string]string{"password": "guess", "code": recoveryCodes[0]}, http.StatusUnauthorized)
+	enroll(map[string]string{"password": "pw", "code": totpCode(t, secret, 1)}, http.StatusConflict)
+}
+
+func TestTOTPDisable(t *testing.T) {
+	_, srv := newTestServer(t)
+	user := createUser(t, srv, "relaxed@example.com", "pw")
+	_, _, recoveryCodes := enrollTOTP(t, srv, user, "pw")
+
+	resp, body := doRequest(t, srv, http.MethodPost, "/api/mfa/recovery-codes", bearer(user.Token), map[string]string{"code": recoveryCodes[0]})
+	expectStatus(t, resp, body, http.StatusOK)
+	fresh := decodeBody[struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}](t, body).RecoveryCodes
+
+	// the old codes were replaced
+	resp, body = doRequest(t, srv, http.MethodDelete, "/api/mfa/totp", bearer(user.Token), map[string]string{"code": recoveryCodes[1]})
+	expectStatus(t, resp, body, http.StatusBadRequest)
+	resp, body = doRequest(t, srv, http.MethodDelete, "/api/mfa/totp", bearer(user.Token), map[string]string{"code": fresh[0]})
+	expectStatus(t, resp, body, http.StatusNoContent)
+	resp, body = doRequest(t, srv, http.MethodDelete, "/api/mfa/totp", bearer(user.Token), map[string]string{"code": fresh[1]})
+	expectStatus(t, resp, body, http.StatusConflict)
+
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/login", "", map[string]string{"email": "relaxed@example.com", "password": "pw"})
+	expectStatus(t, resp, body, http.StatusOK)
+	if got := decodeBody[loginResponse](t, body); got.Token == "" || got.MFAEnabled {
+		t.Errorf("login with TOTP off = %s", body)
+	}
+}
+
+func TestMFALockout(t *testing.T) {
+	cfg, srv := newTestServer(t)
+	user := createUser(t, srv, "guessed@example.com", "pw")
+	secret, _, recoveryCodes := enrollTOTP(t, srv, user, "pw")
+	login := func() string {
+		t.Helper()
+		resp, body := doRequest(t, srv, http.MethodPost, "/api/login", "", map[string]string{"email": "guessed@example.com", "password": "pw"})
+		expectStatus(t, resp, body, http.StatusOK)
+		return decodeBody[mfaChallengeResponse](t, body).MFAToken
+	}
+	completeLogin := func(mfaToken, code string, want int) {
+		t.Helper()
+		resp, body := doRequest(t, srv, http.MethodPost, "/api/login/mfa", "", map[string]string{"mfa_token": mfaToken, "code": code})
+		expectStatus(t, resp, body, want)
+	}
+
+	// fresh challenges don't bring fresh guesses
+	var mfaToken string
+	for i := range maxMFAFailures {
+		if i%maxMFAAttempts == 0 {
+			mfaToken = login()
+		}
+		completeLogin(mfaToken, wrongCode, http.StatusUnauthorized)
+	}
+	completeLogin(login(), totpCode(t, secret, 1), http.StatusTooManyRequests)
+	resp, body := doRequest(t, srv, http.MethodDelete, "/api/mfa/totp", bearer(user.Token), map[string]string{"code": recoveryCodes[0]})
+	expectStatus(t, resp, body, http.StatusTooManyRequests)
+
+	// once the lockout has passed a right code works and clears the count
+	err := cfg.dbQueries.RecordMFAFailure(context.Background(), database.RecordMFAFailureParams{
+		ID:          user.ID,
+		MaxFailures: maxMFAFailures,
+		LockedUntil: time.Now().UTC().Add(-time.Second),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	completeLogin(login(), totpCode(t, secret, 1), http.StatusOK)
+	if got, _ := cfg.dbQueries.GetUserByID(context.Background(), user.ID); got.MfaFailedAttempts != 0 || got.MfaLockedUntil.Valid {
+		t.Errorf("after a right code: %d failures, locked until %v", got.MfaFailedAttempts, got.MfaLockedUntil)
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/natretsel/chirpy/internal/database"
+)
+
+// ModerationRule is a rule applied to every new or edited chirp body.
+type ModerationRule struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Kind      string    `json:"kind"`
+	Patterns  []string  `json:"patterns"`
+	Action    string    `json:"action"`
+	Reason    string    `json:"reason"`
+	Enabled   bool      `json:"enabled"`
+}
+
+func moderationRuleFromDB(rule database.ModerationRule) ModerationRule {
+	return ModerationRule{
+		ID:        rule.ID,
+		CreatedAt: rule.CreatedAt,
+		UpdatedAt: rule.UpdatedAt,
+		Kind:      rule.Kind,
+		Patterns:  rule.Patterns,
+		Action:    rule.Action,
+		Reason:    rule.Reason,
+		Enabled:   rule.Enabled,
+	}
+}
+
+// moderationRuleParams is the body of a create or update request.
+// Rules are enabled unless enabled is false.
+type moderationRuleParams struct {
+	Kind     string   `json:"kind"`
+	Patterns []string `json:"patterns"`
+	Action   string   `json:"action"`
+	Reason   string   `json:"reason"`
+	Enabled  *bool    `json:"enabled"`
+}
+
+// decodeModerationRule reads and checks a rule from the request body,
+// responding with an error and returning false if it is invalid.
+func decodeModerationRule(w http.ResponseWriter, r *http.Request) (database.ModerationRule, bool) {
+	params := moderationRuleParams{}
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return database.ModerationRule{}, false
+	}
+	rule := database.ModerationRule{
+		Kind:     params.Kind,
+		Patterns: params.Patterns,
+		Action:   params.Action,
+		Reason:   params.Reason,
+		Enabled:  params.Enabled == nil || *params.Enabled,
+	}
+	_, err = compileModerationRule(rule)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return database.ModerationRule{}, false
+	}
+	return rule, true
+}
+
+// reloadModerationRules refreshes the cache after an admin change. A
+// failure is only logged, as the change is saved and the background
+// refresh will pick it up.
+func (cfg *apiConfig) reloadModerationRules(r *http.Request) {
+	err := cfg.moderation.load(r.Context(), cfg.dbQueries)
+	if err != nil {
+		log.Printf("couldn't reload moderation rules: %v", err)
+	}
+}
+
+func (cfg *apiConfig) handlerListModerationRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := cfg.dbQueries.ListModerationRules(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't list moderation rules", err)
+		return
+	}
+	type response struct {
+		Rules []ModerationRule `json:"rules"`
+	}
+	resp := response{Rules: []ModerationRule{}}
+	for _, rule := range rules {
+		resp.Rules = append(resp.Rules, moderationRuleFromDB(rule))
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (cfg *apiConfig) handlerCreateModerationRule(w http.ResponseWriter, r *http.Request) {
+	rule, ok := decodeModerationRule(w, r)
+	if !ok {
+		return
+	}
+	rule, err := cfg.dbQueries.CreateModerationRule(r.Context(), database.CreateModerationRuleParams{
+		Kind:     rule.Kind,
+		Patterns: rule.Patterns,
+		Action:   rule.Action,
+		Reason:   rule.Reason,
+		Enabled:  rule.Enabled,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't create moderation rule", err)
+		return
+	}
+	cfg.reloadModerationRules(r)
+	respondWithJSON(w, http.StatusCreated, moderationRuleFromDB(rule))
+}
+
+func (cfg *apiConfig) handlerUpdateModerationRule(w http.ResponseWriter, r *http.Request) {
+	ruleID, err := uuid.Parse(r.PathValue("ruleID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid rule ID", err)
+		return
+	}
+	rule, ok := decodeModerationRule(w, r)
+	if !ok {
+		return
+	}
+	rule, err = cfg.dbQueries.UpdateModerationRule(r.Context(), database.UpdateModerationRuleParams{
+		ID:       ruleID,
+		Kind:     rule.Kind,
+		Patterns: rule.Patterns,
+		Action:   rule.Action,
+		Reason:   rule.Reason,
+		Enabled:  rule.Enabled,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Couldn't get moderation rule", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't update moderation rule", err)
+		return
+	}
+	cfg.reloadModerationRules(r)
+	respondWithJSON(w, http.StatusOK, moderationRuleFromDB(rule))
+}
+
+func (cfg *apiConfig) handlerDeleteModerationRule(w http.ResponseWriter, r *http.Request) {
+	ruleID, err := uuid.Parse(r.PathValue("ruleID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid rule ID", err)
+		return
+	}
+	_, err = cfg.dbQueries.GetModerationRule(r.Context(), ruleID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Couldn't get moderation rule", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't get moderation rule", err)
+		return
+	}
+	err = cfg.dbQueries.DeleteModerationRule(r.Context(), ruleID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't delete moderation rule", err)
+		return
+	}
+	cfg.reloadModerationRules(r)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	internal "github.com/natretsel/chirpy/internal/auth"
+	"github.com/natretsel/chirpy/internal/database"
+)
+
+func TestModerate(t *testing.T) {
+	compile := func(kind, action, reason string, patterns ...string) moderationRule {
+		t.Helper()
+		rule, err := compileModerationRule(database.ModerationRule{
+			Kind:     kind,
+			Patterns: patterns,
+			Action:   action,
+			Reason:   reason,
+			Enabled:  true,
+		})
+		if err != nil {
+			t.Fatalf("compile %s %q: %v", kind, patterns, err)
+		}
+		return rule
+	}
+	rules := []moderationRule{
+		compile(ruleKindWord, ruleActionRedact, "", "kerfuffle", "fornax"),
+		compile(ruleKindPhrase, ruleActionRedact, "", "bad day"),
+		compile(ruleKindRegex, ruleActionHold, "looks like a phone number", `\d{3}-\d{4}`),
+		compile(ruleKindWord, ruleActionReject, "no spam", "buynow"),
+	}
+	disabled := compile(ruleKindWord, ruleActionReject, "", "hello")
+	disabled.Enabled = false
+	rules = append(rules, disabled)
+
+	tests := []struct {
+		name     string
+		body     string
+		want     string
+		hold     bool
+		rejected bool
+	}{
+		{name: "Clean", body: "hello there", want: "hello there"},
+		{name: "Word any case", body: "What a KERFUFFLE!", want: "What a ****!"},
+		{name: "Only whole words", body: "kerfuffles and fornaxian", want: "kerfuffles and fornaxian"},
+		{name: "Phrase across spaces", body: "a bad   day indeed", want: "a **** indeed"},
+		{name: "Phrase needs every word", body: "a bad dog", want: "a bad dog"},
+		{name: "Hold keeps body", body: "call 555-1234 kerfuffle", want: "call 555-1234 ****", hold: true},
+		{name: "Reject", body: "buynow kerfuffle", rejected: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verdict, err := moderate(tt.body, rules)
+			if tt.rejected {
+				if !errors.Is(err, errChirpRejected) || err.Error() != "Chirp rejected: no spam" {
+					t.Fatalf("moderate(%q) error = %v, want rejection", tt.body, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("moderate(%q): %v", tt.body, err)
+			}
+			if verdict.Body != tt.want || verdict.Hold != tt.hold {
+				t.Errorf("moderate(%q) = %+v, want body %q hold %v", tt.body, verdict, tt.want, tt.hold)
+			}
+			if tt.hold && verdict.HoldReason != "looks like a phone number" {
+				t.Errorf("hold reason = %q", verdict.HoldReason)
+			}
+		})
+	}
+}
+
+func TestModerationCorpus(t *testing.T) {
+	rules := []moderationRule{}
+	for _, rule := range []database.ModerationRule{
+		{Kind: ruleKindWord, Patterns: []string{"kerfuffle", "sharbert", "fornax", "straße"}},
+		{Kind: ruleKindPhrase, Patterns: []string{"bad day"}},
+	} {
+		rule.Action = ruleActionRedact
+		rule.Enabled = true
+		compiled, err := compileModerationRule(rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rules = append(rules, compiled)
+	}
+
+	tests := []struct {
+		body string
+		want string
+	}{
+		{body: "Kerfuffle!", want: "****!"},
+		{body: "kerfuffle, sharbert.", want: "****, ****."},
+		{body: "(fornax)", want: "(****)"},
+		{body: "«Sharbert»", want: "«****»"},
+		{body: "SHARBERT’s fault", want: "****’s fault"},
+		{body: "#kerfuffle", want: "#****"},
+		{body: "hi\nkerfuffle", want: "hi\n****"},
+		{body: "a\tkerfuffle  b", want: "a\t****  b"},
+		{body: "KERFUFFLE", want: "****"},
+		{body: "ｋｅｒｆｕｆｆｌｅ", want: "****"},
+		{body: "𝐟𝐨𝐫𝐧𝐚𝐱", want: "****"},
+		{body: "STRASSE", want: "****"},
+		{body: "k3rfuffl3", want: "****"},
+		{body: "$harb3rt", want: "****"},
+		{body: "f0rn@x", want: "****"},
+		{body: "ker\u200bfuf\u00adfle", want: "****"},
+		{body: "\u200bkerfuffle\u200b", want: "\u200b****\u200b"},
+		{body: "Bad\nDay!", want: "****!"},
+		{body: "a bad, day", want: "a bad, day"},
+		{body: "kerfuffles", want: "kerfuffles"},
+		{body: "unkerfuffle", want: "unkerfuffle"},
+		{body: "kerfuffle_fan", want: "kerfuffle_fan"},
+		{body: "fornax 2024", want: "**** 2024"},
+		{body: "no bad words 🙂", want: "no bad words 🙂"},
+	}
+	for _, tt := range tests {
+		verdict, err := moderate(tt.body, rules)
+		if err != nil {
+			t.Fatalf("moderate(%q): %v", tt.body, err)
+		}
+		if verdict.Body != tt.want {
+			t.Errorf("moderate(%q) = %q, want %q", tt.body, verdict.Body, tt.want)
+		}
+	}
+}
+
+func TestRedactSpans(t *testing.T) {
+	got := redactSpans("abcdefgh", [][2]int{{5, 7}, {1, 3}, {2, 4}})
+	if got != "a****e****h" {
+		t.Errorf("redactSpans = %q, want overlapping spans merged", got)
+	}
+}
+
+func TestModerationRules(t *testing.T) {
+	cfg, srv := newTestServer(t)
+	user := createUser(t, srv, "moderated@example.com", "pw")
+	admin := bearer(createStaff(t, cfg, srv, "rules-admin@example.com", internal.RoleAdmin).Token)
+	moderator := bearer(createStaff(t, cfg, srv, "rules-mod@example.com", internal.RoleModerator).Token)
+
+	resp, body := doRequest(t, srv, http.MethodGet, "/admin/moderation/rules", "", nil)
+	expectStatus(t, resp, body, http.StatusUnauthorized)
+	resp, body = doRequest(t, srv, http.MethodGet, "/admin/moderation/rules", admin, nil)
+	expectStatus(t, resp, body, http.StatusOK)
+	rules := decodeBody[struct {
+		Rules []ModerationRule `json:"rules"`
+	}](t, body).Rules
+	if len(rules) != 1 || rules[0].Action != ruleActionRedact || len(rules[0].Patterns) != 3 {
+		t.Fatalf("seeded rules = %+v, want the original bad words", rules)
+	}
+
+	invalid := []map[string]any{
+		{"kind": "glob", "patterns": []string{"x"}, "action": "redact"},
+		{"kind": "word", "patterns": []string{"x"}, "action": "shout"},
+		{"kind": "word", "patterns": []string{}, "action": "redact"},
+		{"kind": "word", "patterns": []string{"two words"}, "action": "redact"},
+		{"kind": "regex", "patterns": []string{"("}, "action": "reject"},
+	}
+	for _, params := range invalid {
+		resp, body = doRequest(t, srv, http.MethodPost, "/admin/moderation/rules", admin, params)
+		expectStatus(t, resp, body, http.StatusBadRequest)
+	}
+
+	resp, body = doRequest(t, srv, http.MethodPost, "/admin/moderation/rules", admin, map[string]any{
+		"kind": "phrase", "patterns": []string{"free money"}, "action": "reject", "reason": "no spam",
+	})
+	expectStatus(t, resp, body, http.StatusCreated)
+	reject := decodeBody[ModerationRule](t, body)
+	if !reject.Enabled {
+		t.Errorf("new rule = %+v, want enabled by default", reject)
+	}
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/chirps", bearer(user.Token), map[string]string{"body": "Free  Money here"})
+	expectStatus(t, resp, body, http.StatusBadRequest)
+	if got := decodeBody[map[string]string](t, body)["error"]; got != "Chirp rejected: no spam" {
+		t.Errorf("rejection error = %q", got)
+	}
+
+	// turning the rule into a hold takes effect without a restart
+	rulePath := "/admin/moderation/rules/" + reject.ID.String()
+	resp, body = doRequest(t, srv, http.MethodPut, rulePath, admin, map[string]any{
+		"kind": "phrase", "patterns": []string{"free money"}, "action": "hold", "reason": "possible spam",
+	})
+	expectStatus(t, resp, body, http.StatusOK)
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/chirps", bearer(user.Token), map[string]string{"body": "free money, kerfuffle"})
+	expectStatus(t, resp, body, http.StatusAccepted)
+	held := decodeBody[Chirp](t, body)
+	if !held.Held || held.Body != "free money, ****" {
+		t.Errorf("held chirp = %+v", held)
+	}
+	resp, body = doRequest(t, srv, http.MethodGet, "/api/chirps/"+held.ID.String(), "", nil)
+	expectStatus(t, resp, body, http.StatusNotFound)
+	stored, err := cfg.dbQueries.GetThreadChirpByID(context.Background(), held.ID)
+	if err != nil || stored.Chirp.HoldReason != "possible spam" {
+		t.Errorf("stored hold reason = %q (%v)", stored.Chirp.HoldReason, err)
+	}
+
+	// an edit can put a published chirp on hold
+	chirp := createChirp(t, srv, user.Token, "nothing to see")
+	resp, body = doRequest(t, srv, http.MethodPatch, "/api/chirps/"+chirp.ID.String(), bearer(user.Token), map[string]string{"body": "free money"})
+	expectStatus(t, resp, body, http.StatusAccepted)
+	resp, body = doRequest(t, srv, http.MethodGet, "/api/chirps/"+chirp.ID.String(), "", nil)
+	expectStatus(t, resp, body, http.StatusNotFound)
+
+	resp, body = doRequest(t, srv, http.MethodDelete, rulePath, admin, nil)
+	expectStatus(t, resp, body, http.StatusNoContent)
+	resp, body = doRequest(t, srv, http.MethodDelete, rulePath, admin, nil)
+	expectStatus(t, resp, body, http.StatusNotFound)
+	createChirp(t, srv, user.Token, "free money")
+
+	// moderators can read the rules but only admins change them
+	resp, body = doRequest(t, srv, http.MethodGet, "/admin/moderation/rules", moderator, nil)
+	expectStatus(t, resp, body, http.StatusOK)
+	resp, body = doRequest(t, srv, http.MethodPost, "/admin/moderation/rules", moderator, map[string]any{
+		"kind": "word", "patterns": []string{"x"}, "action": "redact",
+	})
+	expectStatus(t, resp, body, http.StatusForbidden)
+	resp, body = doRequest(t, srv, http.MethodGet, "/admin/moderation/rules", bearer(user.Token), nil)
+	expectStatus(t, resp, body, http.StatusForbidden)
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	internal "github.com/natretsel/chirpy/internal/auth"
+	"github.com/natretsel/chirpy/internal/database"
+	"github.com/natretsel/chirpy/internal/mailer"
+)
+
+// passwordResetLifetime is how long an emailed reset token stays usable.
+const passwordResetLifetime = time.Hour
+
+// passwordResetSendTimeout bounds creating and emailing a reset token.
+const passwordResetSendTimeout = 30 * time.Second
+
+func passwordResetMessage(to, token string) mailer.Message {
+	return mailer.Message{
+		To:      to,
+		Subject: "Reset your Chirpy password",
+		Body: fmt.Sprintf(`Someone asked to reset the password of your Chirpy account.
+If it was you, use this code to choose a new one within the hour:
+
+%s
+
+If it wasn't you, ignore this email. Your password hasn't changed.
+`, token),
+	}
+}
+
+// handlerPasswordResetRequest emails a reset token. It answers the same
+// whether or not the email belongs to an account, so it can't be used
+// to find out who has one; that includes answering as quickly, so the
+// token is made and sent after responding.
+func (cfg *apiConfig) handlerPasswordResetRequest(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Email string `json:"email"`
+	}
+	params := parameters{}
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	if params.Email == "" {
+		respondWithError(w, http.StatusBadRequest, "email is required", nil)
+		return
+	}
+	user, err := cfg.dbQueries.GetUserByEmail(r.Context(), params.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't get user", err)
+		return
+	}
+	cfg.background.Add(1)
+	go func() {
+		defer cfg.background.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), passwordResetSendTimeout)
+		defer cancel()
+		// a failure is only logged, as telling the caller would reveal
+		// that the account exists
+		err := cfg.sendPasswordReset(ctx, user)
+		if err != nil {
+			log.Printf("couldn't send password reset email: %v", err)
+		}
+	}()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// sendPasswordReset emails user a token that resets their password.
+func (cfg *apiConfig) sendPasswordReset(ctx context.Context, user database.User) error {
+	token, err := internal.MakeOneTimeToken()
+	if err != nil {
+		return err
+	}
+	_, err = cfg.dbQueries.CreatePasswordResetToken(ctx, database.CreatePasswordResetTokenParams{
+		TokenHash: internal.HashToken(token),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().UTC().Add(passwordResetLifetime),
+	})
+	if err != nil {
+		return err
+	}
+	return cfg.mailer.Send(ctx, passwordResetMessage(user.Email, token))
+}
+
+// handlerPasswordResetConfirm sets a new password with an emailed reset
+// token, then signs the user out everywhere.
+func (cfg *apiConfig) handlerPasswordResetConfirm(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	params := parameters{}
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	if params.Password == "" {
+		respondWithError(w, http.StatusBadRequest, "password is required", nil)
+		return
+	}
+	hashedPassword, err := internal.HashPassword(params.Password)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "couldn't use that password", err)
+		return
+	}
+	// the password only changes if every session goes with the old one
+	err = cfg.dbQueries.InTx(r.Context(), func(store database.Store) error {
+		reset, err := store.UsePasswordResetToken(r.Context(), internal.HashToken(params.Token))
+		if err != nil {
+			return err
+		}
+		err = store.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{
+			ID:             reset.UserID,
+			HashedPassword: hashedPassword,
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't update password: %w", err)
+		}
+		err = store.ExpireUserPasswordResetTokens(r.Context(), reset.UserID)
+		if err != nil {
+			return fmt.Errorf("couldn't expire reset tokens: %w", err)
+		}
+		err = store.RevokeUserRefreshTokens(r.Context(), reset.UserID)
+		if err != nil {
+			return fmt.Errorf("couldn't revoke refresh tokens: %w", err)
+		}
+		err = cfg.revokeAccessTokens(r.Context(), store, reset.UserID)
+		if err != nil {
+			return fmt.Errorf("couldn't revoke access tokens: %w", err)
+		}
+		return nil
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusBadRequest, "invalid or expired reset token", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't reset password", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	internal "github.com/natretsel/chirpy/internal/auth"
+	"github.com/natretsel/chirpy/internal/database"
+	"github.com/natretsel/chirpy/internal/mailer"
+)
+
+func TestPasswordReset(t *testing.T) {
+	cfg, srv := newTestServer(t)
+	user := createUser(t, srv, "forgetful@example.com", "old-pw")
+	request := func(email string) {
+		t.Helper()
+		resp, body := doRequest(t, srv, http.MethodPost, "/api/password-reset/request", "", map[string]string{"email": email})
+		expectStatus(t, resp, body, http.StatusAccepted)
+	}
+	confirm := func(token string, want int) {
+		t.Helper()
+		resp, body := doRequest(t, srv, http.MethodPost, "/api/password-reset/confirm", "", map[string]string{"token": token, "password": "new-pw"})
+		expectStatus(t, resp, body, want)
+	}
+	login := func(password string, want int) {
+		t.Helper()
+		resp, body := doRequest(t, srv, http.MethodPost, "/api/login", "", map[string]string{"email": "forgetful@example.com", "password": password})
+		expectStatus(t, resp, body, want)
+	}
+
+	// unknown addresses get the same answer and no email
+	request("nobody@example.com")
+	if got := emailedTokens(t, cfg, "nobody@example.com", "Reset your Chirpy password"); len(got) != 0 {
+		t.Errorf("emails to an unknown address = %v", got)
+	}
+	request("forgetful@example.com")
+	request("forgetful@example.com")
+	tokens := emailedTokens(t, cfg, "forgetful@example.com", "Reset your Chirpy password")
+	if len(tokens) != 2 || tokens[0] == "" || tokens[0] == tokens[1] {
+		t.Fatalf("emailed tokens = %v, want two different ones", tokens)
+	}
+
+	confirm("not-a-token", http.StatusBadRequest)
+	confirm(tokens[1], http.StatusNoContent)
+	// tokens are single use, and using one voids the others
+	confirm(tokens[1], http.StatusBadRequest)
+	confirm(tokens[0], http.StatusBadRequest)
+
+	login("old-pw", http.StatusUnauthorized)
+	login("new-pw", http.StatusOK)
+	resp, body := doRequest(t, srv, http.MethodPost, "/api/refresh", bearer(user.RefreshToken), nil)
+	expectStatus(t, resp, body, http.StatusUnauthorized)
+	resp, body = doRequest(t, srv, http.MethodGet, "/api/timeline", bearer(user.Token), nil)
+	expectStatus(t, resp, body, http.StatusUnauthorized)
+}
+
+func TestPasswordResetExpires(t *testing.T) {
+	cfg, srv := newTestServer(t)
+	user := createUser(t, srv, "slow@example.com", "pw")
+	_, err := cfg.dbQueries.CreatePasswordResetToken(context.Background(), database.CreatePasswordResetTokenParams{
+		TokenHash: internal.HashToken("stale"),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().UTC().Add(-time.Minute),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, body := doRequest(t, srv, http.MethodPost, "/api/password-reset/confirm", "", map[string]string{"token": "stale", "password": "new-pw"})
+	expectStatus(t, resp, body, http.StatusBadRequest)
+}
+
+// failingRevokeStore can't revoke refresh tokens, inside a transaction
+// or out.
+type failingRevokeStore struct {
+	database.Store
+}
+
+func (s failingRevokeStore) RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error {
+	return errors.New("refresh tokens are down")
+}
+
+func (s failingRevokeStore) InTx(ctx context.Context, fn func(database.Store) error) error {
+	return s.Store.InTx(ctx, func(database.Store) error { return fn(s) })
+}
+
+func TestPasswordResetRollsBack(t *testing.T) {
+	cfg, srv := newTestServer(t)
+	user := createUser(t, srv, "unlucky@example.com", "old-pw")
+	_, err := cfg.dbQueries.CreatePasswordResetToken(context.Background(), database.CreatePasswordResetTokenParams{
+		TokenHash: internal.HashToken("fresh"),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().UTC().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	confirm := func(want int) {
+		t.Helper()
+		resp, body := doRequest(t, srv, http.MethodPost, "/api/password-reset/confirm", "", map[string]string{"token": "fresh", "password": "new-pw"})
+		expectStatus(t, resp, body, want)
+	}
+
+	store := cfg.dbQueries
+	cfg.dbQueries = failingRevokeStore{store}
+	confirm(http.StatusInternalServerError)
+	cfg.dbQueries = store
+
+	// the token and the old password still work
+	resp, body := doRequest(t, srv, http.MethodPost, "/api/login", "", map[string]string{"email": "unlucky@example.com", "password": "old-pw"})
+	expectStatus(t, resp, body, http.StatusOK)
+	confirm(http.StatusNoContent)
+}
+
+// blockingMailer holds every email until release is closed.
+type blockingMailer struct {
+	release   chan struct{}
+	deadlines chan bool
+}
+
+func (m blockingMailer) Send(ctx context.Context, msg mailer.Message) error {
+	_, ok := ctx.Deadline()
+	m.deadlines <- ok
+	<-m.release
+	return nil
+}
+
+func TestPasswordResetDoesNotWaitForEmail(t *testing.T) {
+	cfg, srv := newTestServer(t)
+	createUser(t, srv, "patient@example.com", "pw")
+	mail := blockingMailer{release: make(chan struct{}), deadlines: make(chan bool, 1)}
+	cfg.mailer = mail
+
+	// the email is still being sent when the answer comes back
+	resp, body := doRequest(t, srv, http.MethodPost, "/api/password-reset/request", "", map[string]string{"email": "patient@example.com"})
+	expectStatus(t, resp, body, http.StatusAccepted)
+	if !<-mail.deadlines {
+		t.Error("email sent without a deadline")
+	}
+	close(mail.release)
+	cfg.background.Wait()
+}
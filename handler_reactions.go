@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	internal "github.com/natretsel/chirpy/internal/auth"
+	"github.com/natretsel/chirpy/internal/database"
+)
+
+// defaultReactions is used when ALLOWED_REACTIONS is not set.
+const defaultReactions = "👍,❤️,😂,😮,😢,🎉"
+
+// ReactionCount is how many users reacted to a chirp with Emoji, and
+// whether the caller is one of them.
+type ReactionCount struct {
+	Emoji   string `json:"emoji"`
+	Count   int32  `json:"count"`
+	Reacted bool   `json:"reacted"`
+}
+
+// parseReactions splits a comma separated emoji list, dropping blanks
+// and duplicates.
+func parseReactions(list string) []string {
+	reactions := []string{}
+	for _, emoji := range strings.Split(list, ",") {
+		emoji = strings.TrimSpace(emoji)
+		if emoji != "" && !slices.Contains(reactions, emoji) {
+			reactions = append(reactions, emoji)
+		}
+	}
+	return reactions
+}
+
+func (cfg *apiConfig) handlerReactionAdd(w http.ResponseWriter, r *http.Request) {
+	params, ok := cfg.reactionParams(w, r)
+	if !ok {
+		return
+	}
+	if !slices.Contains(cfg.allowedReactions, params.Emoji) {
+		respondWithError(w, http.StatusBadRequest, "reaction not allowed", nil)
+		return
+	}
+	if !cfg.requireVerified(w, r, params.UserID, actionReact) {
+		return
+	}
+	err := cfg.dbQueries.AddReaction(r.Context(), database.AddReactionParams{
+		ChirpID: params.ChirpID,
+		UserID:  params.UserID,
+		Emoji:   params.Emoji,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't add reaction", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerReactionRemove(w http.ResponseWriter, r *http.Request) {
+	params, ok := cfg.reactionParams(w, r)
+	if !ok {
+		return
+	}
+	err := cfg.dbQueries.RemoveReaction(r.Context(), database.RemoveReactionParams{
+		ChirpID: params.ChirpID,
+		UserID:  params.UserID,
+		Emoji:   params.Emoji,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't remove reaction", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type reactionParams struct {
+	ChirpID uuid.UUID
+	UserID  uuid.UUID
+	Emoji   string
+}
+
+// reactionParams authenticates the caller and validates the chirp in
+// the path, responding with an error and returning false if either is
+// unacceptable. The emoji is checked against the allowed reactions
+// only when adding one, so reactions that have since been disallowed
+// can still be removed.
+func (cfg *apiConfig) reactionParams(w http.ResponseWriter, r *http.Request) (reactionParams, bool) {
+	accessToken, err := internal.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "malformed header", err)
+		return reactionParams{}, false
+	}
+	userID, err := cfg.validateAccessToken(r.Context(), accessToken)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "invalid access token", err)
+		return reactionParams{}, false
+	}
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid chirp ID", err)
+		return reactionParams{}, false
+	}
+	emoji := r.PathValue("emoji")
+	_, err = cfg.dbQueries.GetChirpByID(r.Context(), chirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "chirp not found", err)
+		return reactionParams{}, false
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't get chirp", err)
+		return reactionParams{}, false
+	}
+	return reactionParams{ChirpID: chirpID, UserID: userID, Emoji: emoji}, true
+}
+
+// viewerID returns the caller's user ID on endpoints where signing in
+// is optional. A missing or invalid token means an anonymous viewer.
+func (cfg *apiConfig) viewerID(r *http.Request) uuid.NullUUID {
+	accessToken, err := internal.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+	userID, err := cfg.validateAccessToken(r.Context(), accessToken)
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: userID, Valid: true}
+}
+
+// attachReactions fills in the reaction counts of chirps in one query,
+// flagging the reactions made by viewer.
+func (cfg *apiConfig) attachReactions(ctx context.Context, viewer uuid.NullUUID, chirps ...*Chirp) error {
+	if len(chirps) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, 0, len(chirps))
+	byID := make(map[uuid.UUID][]*Chirp, len(chirps))
+	for _, c := range chirps {
+		c.Reactions = []ReactionCount{}
+		ids = append(ids, c.ID)
+		byID[c.ID] = append(byID[c.ID], c)
+	}
+	rows, err := cfg.dbQueries.GetReactionCounts(ctx, database.GetReactionCountsParams{
+		ViewerID: viewer,
+		ChirpIds: ids,
+	})
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		for _, c := range byID[row.ChirpID] {
+			c.Reactions = append(c.Reactions, ReactionCount{
+				Emoji:   row.Emoji,
+				Count:   row.Count,
+				Reacted: row.Reacted,
+			})
+		}
+	}
+	return nil
+}
+
+// chirpPointers lets a whole page of chirps be passed to hydrateChirps.
+func chirpPointers(chirps []Chirp) []*Chirp {
+	ptrs := make([]*Chirp, len(chirps))
+	for i := range chirps {
+		ptrs[i] = &chirps[i]
+	}
+	return ptrs
+}
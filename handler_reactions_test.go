@@ -0,0 +1,107 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestParseReactions(t *testing.T) {
+	tests := []struct {
+		name string
+		list string
+		want []string
+	}{
+		{name: "Default set", list: defaultReactions, want: []string{"👍", "❤️", "😂", "😮", "😢", "🎉"}},
+		{name: "Blanks and duplicates", list: " 👍, ,👍,🔥 ", want: []string{"👍", "🔥"}},
+		{name: "Empty", list: "", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseReactions(tt.list); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseReactions(%q) = %q, want %q", tt.list, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReactions(t *testing.T) {
+	cfg, srv := newTestServer(t)
+	alice := createUser(t, srv, "alice-reacts@example.com", "pw")
+	bob := createUser(t, srv, "bob-reacts@example.com", "pw")
+	chirp := createChirp(t, srv, alice.Token, "react to me")
+	reactionPath := func(chirpID uuid.UUID, emoji string) string {
+		return "/api/chirps/" + chirpID.String() + "/reactions/" + url.PathEscape(emoji)
+	}
+	getChirp := func(token string) Chirp {
+		t.Helper()
+		authorization := ""
+		if token != "" {
+			authorization = bearer(token)
+		}
+		resp, body := doRequest(t, srv, http.MethodGet, "/api/chirps/"+chirp.ID.String(), authorization, nil)
+		expectStatus(t, resp, body, http.StatusOK)
+		return decodeBody[Chirp](t, body)
+	}
+
+	for _, token := range []string{alice.Token, bob.Token, bob.Token} {
+		resp, body := doRequest(t, srv, http.MethodPut, reactionPath(chirp.ID, "👍"), bearer(token), nil)
+		expectStatus(t, resp, body, http.StatusNoContent)
+	}
+	resp, body := doRequest(t, srv, http.MethodPut, reactionPath(chirp.ID, "🎉"), bearer(bob.Token), nil)
+	expectStatus(t, resp, body, http.StatusNoContent)
+
+	want := []ReactionCount{{Emoji: "👍", Count: 2, Reacted: true}, {Emoji: "🎉", Count: 1, Reacted: false}}
+	if got := getChirp(alice.Token).Reactions; !reflect.DeepEqual(got, want) {
+		t.Errorf("alice sees reactions %+v, want %+v", got, want)
+	}
+	want = []ReactionCount{{Emoji: "👍", Count: 2, Reacted: false}, {Emoji: "🎉", Count: 1, Reacted: false}}
+	if got := getChirp("").Reactions; !reflect.DeepEqual(got, want) {
+		t.Errorf("anonymous viewer sees reactions %+v, want %+v", got, want)
+	}
+
+	resp, body = doRequest(t, srv, http.MethodDelete, reactionPath(chirp.ID, "👍"), bearer(alice.Token), nil)
+	expectStatus(t, resp, body, http.StatusNoContent)
+	resp, body = doRequest(t, srv, http.MethodGet, "/api/chirps", bearer(bob.Token), nil)
+	expectStatus(t, resp, body, http.StatusOK)
+	want = []ReactionCount{{Emoji: "🎉", Count: 1, Reacted: true}, {Emoji: "👍", Count: 1, Reacted: true}}
+	if got := decodeBody[chirpsPage](t, body).Chirps[0].Reactions; !reflect.DeepEqual(got, want) {
+		t.Errorf("listed reactions %+v, want %+v", got, want)
+	}
+
+	// a reaction that is no longer allowed can still be taken back
+	cfg.allowedReactions = []string{"👍"}
+	resp, body = doRequest(t, srv, http.MethodPut, reactionPath(chirp.ID, "🎉"), bearer(bob.Token), nil)
+	expectStatus(t, resp, body, http.StatusBadRequest)
+	resp, body = doRequest(t, srv, http.MethodDelete, reactionPath(chirp.ID, "🎉"), bearer(bob.Token), nil)
+	expectStatus(t, resp, body, http.StatusNoContent)
+	want = []ReactionCount{{Emoji: "👍", Count: 1, Reacted: false}}
+	if got := getChirp(alice.Token).Reactions; !reflect.DeepEqual(got, want) {
+		t.Errorf("reactions after removing a disallowed one = %+v, want %+v", got, want)
+	}
+
+	tests := []struct {
+		name          string
+		path          string
+		authorization string
+		want          int
+	}{
+		{name: "Emoji not allowed", path: reactionPath(chirp.ID, "💩"), authorization: bearer(bob.Token), want: http.StatusBadRequest},
+		{name: "Unknown chirp", path: reactionPath(uuid.New(), "👍"), authorization: bearer(bob.Token), want: http.StatusNotFound},
+		{name: "Missing token", path: reactionPath(chirp.ID, "👍"), authorization: "", want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := doRequest(t, srv, http.MethodPut, tt.path, tt.authorization, nil)
+			expectStatus(t, resp, body, tt.want)
+		})
+	}
+
+	resp, body = doRequest(t, srv, http.MethodDelete, "/api/chirps/"+chirp.ID.String(), bearer(alice.Token), nil)
+	expectStatus(t, resp, body, http.StatusNoContent)
+	resp, body = doRequest(t, srv, http.MethodPut, reactionPath(chirp.ID, "👍"), bearer(bob.Token), nil)
+	expectStatus(t, resp, body, http.StatusNotFound)
+}
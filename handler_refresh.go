@@ -1,12 +1,57 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
 	"net/http"
 	"time"
 
+	"github.com/google/uuid"
 	internal "github.com/natretsel/chirpy/internal/auth"
+	"github.com/natretsel/chirpy/internal/database"
 )
 
+// refreshTokenLifetime is how long a refresh token stays valid. Each
+// refresh rotates the token, so an active session never runs out.
+const refreshTokenLifetime = 60 * 24 * time.Hour
+
+// issueRefreshToken stores a new refresh token for the user in the
+// given token family, recording the device r came from.
+func (cfg *apiConfig) issueRefreshToken(r *http.Request, userID, familyID uuid.UUID) (string, error) {
+	refreshToken, err := internal.MakeRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	_, err = cfg.dbQueries.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
+		TokenHash: internal.HashToken(refreshToken),
+		UpdatedAt: time.Now().UTC(),
+		UserID:    userID,
+		ExpiresAt: time.Now().UTC().Add(refreshTokenLifetime),
+		FamilyID:  familyID,
+		UserAgent: r.UserAgent(),
+		IpAddress: clientIP(r),
+	})
+	if err != nil {
+		return "", err
+	}
+	return refreshToken, nil
+}
+
+// revokeReusedFamily handles a refresh token presented after it was
+// already rotated. Either the client or an attacker holds a stale copy,
+// and we can't tell which, so every token in the family is revoked.
+func (cfg *apiConfig) revokeReusedFamily(ctx context.Context, token database.RefreshToken) error {
+	log.Printf("security: rotated refresh token reused for user %s; revoking token family %s", token.UserID, token.FamilyID)
+	err := cfg.dbQueries.RevokeRefreshTokenFamily(ctx, token.FamilyID)
+	if err != nil {
+		return err
+	}
+	cfg.sessions.set(token.FamilyID, true)
+	return nil
+}
+
 func (cfg *apiConfig) handlerValidateRefresh(w http.ResponseWriter, r *http.Request) {
 	// check for Bearer token in Header
 	refreshToken, err := internal.GetBearerToken(r.Header)
@@ -15,24 +60,74 @@ func (cfg *apiConfig) handlerValidateRefresh(w http.ResponseWriter, r *http.Requ
 		return
 	}
 	// Look up refresh token in DB
-	user, err := cfg.dbQueries.GetUserFromRefreshToken(r.Context(), refreshToken)
+	token, err := cfg.dbQueries.GetRefreshToken(r.Context(), internal.HashToken(refreshToken))
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get user for refresh token", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't get refresh token", err)
+		return
+	}
+	if token.ReplacedBy.Valid {
+		err = cfg.revokeReusedFamily(r.Context(), token)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "couldn't revoke token family", err)
+			return
+		}
+		respondWithError(w, http.StatusUnauthorized, "refresh token reused", nil)
+		return
+	}
+	if token.RevokedAt.Valid || !token.ExpiresAt.After(time.Now().UTC()) {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't get user for refresh token", nil)
+		return
+	}
+	user, err := cfg.dbQueries.GetUserByID(r.Context(), token.UserID)
 	if err != nil {
 		respondWithError(w, http.StatusUnauthorized, "Couldn't get user for refresh token", err)
 		return
 	}
+	if isSuspended(user) {
+		respondSuspended(w, user)
+		return
+	}
 
-	// otherwise return 200 and {"token":"{access token}"}
-	jwtToken, err := internal.MakeJWT(user.ID, cfg.secret, time.Hour)
+	newRefreshToken, err := cfg.issueRefreshToken(r, user.ID, token.FamilyID)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		respondWithError(w, http.StatusInternalServerError, "Error creating refresh token in DB", err)
+		return
+	}
+	_, err = cfg.dbQueries.RotateRefreshToken(r.Context(), database.RotateRefreshTokenParams{
+		TokenHash:  token.TokenHash,
+		ReplacedBy: sql.NullString{String: internal.HashToken(newRefreshToken), Valid: true},
+	})
+	// losing a race with another refresh of the same token is reuse too
+	if errors.Is(err, sql.ErrNoRows) {
+		err = cfg.revokeReusedFamily(r.Context(), token)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "couldn't revoke token family", err)
+			return
+		}
+		respondWithError(w, http.StatusUnauthorized, "refresh token reused", nil)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't rotate refresh token", err)
 		return
 	}
 
-	type AccessTokenResp struct {
-		Token string `json:"token"`
+	jwtToken, err := internal.MakeJWT(user.ID, user.Role, token.FamilyID, cfg.keys, time.Hour)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		return
 	}
 
-	respondWithJSON(w, http.StatusOK, AccessTokenResp{
-		Token: jwtToken,
+	type response struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	respondWithJSON(w, http.StatusOK, response{
+		Token:        jwtToken,
+		RefreshToken: newRefreshToken,
 	})
 }
@@ -0,0 +1,429 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	internal "github.com/natretsel/chirpy/internal/auth"
+	"github.com/natretsel/chirpy/internal/database"
+)
+
+const (
+	maxReportDetailsLength = 1000
+
+	// reportCategoryHeld marks the report a hold rule raises for a held
+	// chirp. Users can't choose it.
+	reportCategoryHeld = "held"
+
+	resolutionDismiss     = "dismiss"
+	resolutionDeleteChirp = "delete_chirp"
+	resolutionSuspendUser = "suspend_user"
+)
+
+// reportCategories are the reasons a user can give for a report.
+var reportCategories = []string{"spam", "harassment", "hate", "violence", "sexual", "misinformation", "other"}
+
+// Report is one user's report of a user, or of a chirp when ChirpID is
+// set. ReporterID is nil for reports raised by a hold rule.
+type Report struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ReporterID *uuid.UUID `json:"reporter_id"`
+	ChirpID    *uuid.UUID `json:"chirp_id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	Category   string     `json:"category"`
+	Details    string     `json:"details"`
+}
+
+func reportFromDB(report database.Report) Report {
+	r := Report{
+		ID:        report.ID,
+		CreatedAt: report.CreatedAt,
+		UserID:    report.UserID,
+		Category:  report.Category,
+		Details:   report.Details,
+	}
+	if report.ReporterID.Valid {
+		r.ReporterID = &report.ReporterID.UUID
+	}
+	if report.ChirpID.Valid {
+		r.ChirpID = &report.ChirpID.UUID
+	}
+	return r
+}
+
+// ReportGroup is every open report against the same user, or the same
+// chirp. Chirp is the reported chirp, held or not, while it exists.
+type ReportGroup struct {
+	UserID          uuid.UUID  `json:"user_id"`
+	ChirpID         *uuid.UUID `json:"chirp_id"`
+	Chirp           *Chirp     `json:"chirp"`
+	ReportCount     int32      `json:"report_count"`
+	ReporterCount   int32      `json:"reporter_count"`
+	FirstReportedAt time.Time  `json:"first_reported_at"`
+	LastReportedAt  time.Time  `json:"last_reported_at"`
+	Reports         []Report   `json:"reports"`
+}
+
+// ReportResolution records how a moderator closed a group of reports.
+type ReportResolution struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Action     string     `json:"action"`
+	Note       string     `json:"note"`
+	ChirpID    *uuid.UUID `json:"chirp_id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	ResolvedBy *uuid.UUID `json:"resolved_by"`
+}
+
+func reportResolutionFromDB(resolution database.ReportResolution) ReportResolution {
+	r := ReportResolution{
+		ID:        resolution.ID,
+		CreatedAt: resolution.CreatedAt,
+		Action:    resolution.Action,
+		Note:      resolution.Note,
+		UserID:    resolution.UserID,
+	}
+	if resolution.ChirpID.Valid {
+		r.ChirpID = &resolution.ChirpID.UUID
+	}
+	if resolution.ResolvedBy.Valid {
+		r.ResolvedBy = &resolution.ResolvedBy.UUID
+	}
+	return r
+}
+
+// decodeReport reads the category and details of a new report,
+// responding with an error and returning false if they are invalid.
+func decodeReport(w http.ResponseWriter, r *http.Request) (database.CreateReportParams, bool) {
+	type parameters struct {
+		Category string `json:"category"`
+		Details  string `json:"details"`
+	}
+	params := parameters{}
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return database.CreateReportParams{}, false
+	}
+	if !slices.Contains(reportCategories, params.Category) {
+		respondWithError(w, http.StatusBadRequest, "category must be one of spam, harassment, hate, violence, sexual, misinformation or other", nil)
+		return database.CreateReportParams{}, false
+	}
+	if utf8.RuneCountInString(params.Details) > maxReportDetailsLength {
+		respondWithError(w, http.StatusBadRequest, "details are too long", nil)
+		return database.CreateReportParams{}, false
+	}
+	return database.CreateReportParams{
+		Category: params.Category,
+		Details:  params.Details,
+	}, true
+}
+
+func (cfg *apiConfig) handlerReportChirp(w http.ResponseWriter, r *http.Request) {
+	token, err := internal.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "malformed header", err)
+		return
+	}
+	userID, err := cfg.validateAccessToken(r.Context(), token)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized", err)
+		return
+	}
+	if !cfg.requireVerified(w, r, userID, actionReport) {
+		return
+	}
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid chirp ID", err)
+		return
+	}
+	params, ok := decodeReport(w, r)
+	if !ok {
+		return
+	}
+	chirp, err := cfg.dbQueries.GetChirpByID(r.Context(), chirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Couldn't get chirp", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't get chirp by ID", err)
+		return
+	}
+	if chirp.UserID == userID {
+		respondWithError(w, http.StatusBadRequest, "you can't report your own chirp", nil)
+		return
+	}
+	params.ReporterID = uuid.NullUUID{UUID: userID, Valid: true}
+	params.ChirpID = uuid.NullUUID{UUID: chirp.ID, Valid: true}
+	params.UserID = chirp.UserID
+	report, err := cfg.dbQueries.CreateReport(r.Context(), params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't create report", err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, reportFromDB(report))
+}
+
+func (cfg *apiConfig) handlerReportUser(w http.ResponseWriter, r *http.Request) {
+	token, err := internal.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "malformed header", err)
+		return
+	}
+	userID, err := cfg.validateAccessToken(r.Context(), token)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized", err)
+		return
+	}
+	if !cfg.requireVerified(w, r, userID, actionReport) {
+		return
+	}
+	reportedID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid user ID", err)
+		return
+	}
+	if reportedID == userID {
+		respondWithError(w, http.StatusBadRequest, "you can't report yourself", nil)
+		return
+	}
+	params, ok := decodeReport(w, r)
+	if !ok {
+		return
+	}
+	_, err = cfg.dbQueries.GetUserByID(r.Context(), reportedID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Couldn't get user", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't get user", err)
+		return
+	}
+	params.ReporterID = uuid.NullUUID{UUID: userID, Valid: true}
+	params.UserID = reportedID
+	report, err := cfg.dbQueries.CreateReport(r.Context(), params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't create report", err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, reportFromDB(report))
+}
+
+// reportHeldChirp puts a chirp a hold rule caught into the moderation
+// queue.
+func reportHeldChirp(ctx context.Context, store database.Store, chirp database.Chirp) error {
+	_, err := store.CreateReport(ctx, database.CreateReportParams{
+		ChirpID:  uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		UserID:   chirp.UserID,
+		Category: reportCategoryHeld,
+		Details:  chirp.HoldReason,
+	})
+	return err
+}
+
+// reportGroupsPage is one page of the moderation queue. NextCursor is
+// empty on the last page.
+type reportGroupsPage struct {
+	Groups     []ReportGroup `json:"groups"`
+	NextCursor string        `json:"next_cursor"`
+}
+
+func (cfg *apiConfig) handlerListReports(w http.ResponseWriter, r *http.Request) {
+	limit, cursor, err := parsePage(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	params := database.ListOpenReportGroupsParams{
+		Limit: int32(limit + 1),
+	}
+	if cursor != nil {
+		params.AfterReportedAt = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+		params.AfterID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+	groups, err := cfg.dbQueries.ListOpenReportGroups(r.Context(), params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't list reports", err)
+		return
+	}
+
+	page := reportGroupsPage{Groups: []ReportGroup{}}
+	if len(groups) > limit {
+		groups = groups[:limit]
+		last := groups[len(groups)-1]
+		page.NextCursor = encodeCursor(last.FirstReportedAt, last.FirstReportID)
+	}
+	userIDs := []uuid.UUID{}
+	for _, g := range groups {
+		group := ReportGroup{
+			UserID:          g.UserID,
+			ReportCount:     g.ReportCount,
+			ReporterCount:   g.ReporterCount,
+			FirstReportedAt: g.FirstReportedAt,
+			LastReportedAt:  g.LastReportedAt,
+			Reports:         []Report{},
+		}
+		if g.ChirpID.Valid {
+			group.ChirpID = &g.ChirpID.UUID
+			// held and trashed chirps are still shown to moderators
+			chirp, err := cfg.dbQueries.GetThreadChirpByID(r.Context(), g.ChirpID.UUID)
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "couldn't get reported chirp", err)
+				return
+			}
+			c := chirpFromDB(chirp.Chirp)
+			group.Chirp = &c
+		}
+		page.Groups = append(page.Groups, group)
+		userIDs = append(userIDs, g.UserID)
+	}
+	reports, err := cfg.dbQueries.ListOpenReportsByUsers(r.Context(), userIDs)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't list reports", err)
+		return
+	}
+	for _, report := range reports {
+		for i := range page.Groups {
+			group := &page.Groups[i]
+			if group.UserID == report.UserID && sameChirp(group.ChirpID, report.ChirpID) {
+				group.Reports = append(group.Reports, reportFromDB(report))
+			}
+		}
+	}
+	setNextLink(w, r, page.NextCursor)
+	respondWithJSON(w, http.StatusOK, page)
+}
+
+func sameChirp(id *uuid.UUID, other uuid.NullUUID) bool {
+	if id == nil {
+		return !other.Valid
+	}
+	return other.Valid && *id == other.UUID
+}
+
+// handlerResolveReport closes every open report in the same group as
+// the one in the path, after applying the moderator's action:
+// dismissing releases a held chirp, delete_chirp purges the chirp and
+// suspend_user suspends its author for a while. Staff can only be
+// suspended, and users only permanently, by an admin through
+// handlerSuspendUser.
+func (cfg *apiConfig) handlerResolveReport(w http.ResponseWriter, r *http.Request) {
+	reportID, err := uuid.Parse(r.PathValue("reportID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid report ID", err)
+		return
+	}
+	type parameters struct {
+		Action string `json:"action"`
+		Note   string `json:"note"`
+		// Duration is how long suspend_user suspends the author for
+		Duration string `json:"duration"`
+	}
+	params := parameters{}
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	report, err := cfg.dbQueries.GetReport(r.Context(), reportID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Couldn't get report", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't get report", err)
+		return
+	}
+	if report.ResolutionID.Valid {
+		respondWithError(w, http.StatusConflict, "report already resolved", nil)
+		return
+	}
+
+	if !slices.Contains([]string{resolutionDismiss, resolutionDeleteChirp, resolutionSuspendUser}, params.Action) {
+		respondWithError(w, http.StatusBadRequest, "action must be dismiss, delete_chirp or suspend_user", nil)
+		return
+	}
+	if params.Action == resolutionDeleteChirp && !report.ChirpID.Valid {
+		respondWithError(w, http.StatusBadRequest, "report is not about a chirp", nil)
+		return
+	}
+	suspendedUntil := sql.NullTime{}
+	if params.Action == resolutionSuspendUser {
+		suspendedUntil, err = suspensionEnd(params.Duration)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+		author, err := cfg.dbQueries.GetUserByID(r.Context(), report.UserID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "couldn't get user", err)
+			return
+		}
+		if author.Role != internal.RoleUser {
+			respondWithError(w, http.StatusForbidden, "staff can't be suspended from a report", nil)
+			return
+		}
+	}
+
+	// the reports only close if the action works. Closing them comes
+	// first, as purging the chirp detaches its reports, and suspending
+	// last, as the access tokens it revokes can't be rolled back.
+	var resolution database.ReportResolution
+	err = cfg.dbQueries.InTx(r.Context(), func(store database.Store) error {
+		var err error
+		resolution, err = store.CreateReportResolution(r.Context(), database.CreateReportResolutionParams{
+			Action:     params.Action,
+			Note:       params.Note,
+			ChirpID:    report.ChirpID,
+			UserID:     report.UserID,
+			ResolvedBy: staffID(r),
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't record resolution: %w", err)
+		}
+		err = store.ResolveReports(r.Context(), database.ResolveReportsParams{
+			ResolutionID: resolution.ID,
+			UserID:       report.UserID,
+			ChirpID:      report.ChirpID,
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't resolve reports: %w", err)
+		}
+
+		switch params.Action {
+		case resolutionDismiss:
+			if report.ChirpID.Valid {
+				err = store.ReleaseChirpHold(r.Context(), report.ChirpID.UUID)
+			}
+		case resolutionDeleteChirp:
+			err = purgeChirp(r.Context(), store, report.ChirpID.UUID)
+		case resolutionSuspendUser:
+			_, err = cfg.suspendUser(r.Context(), store, database.SuspendUserParams{
+				ID:               report.UserID,
+				SuspendedUntil:   suspendedUntil,
+				SuspensionReason: params.Note,
+			})
+		}
+		if err != nil {
+			return fmt.Errorf("couldn't apply %v: %w", params.Action, err)
+		}
+		return nil
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't resolve reports", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, reportResolutionFromDB(resolution))
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	internal "github.com/natretsel/chirpy/internal/auth"
+	"github.com/natretsel/chirpy/internal/database"
+)
+
+func TestReports(t *testing.T) {
+	cfg, srv := newTestServer(t)
+	alice := createUser(t, srv, "alice-report@example.com", "pw")
+	bob := createUser(t, srv, "bob-report@example.com", "pw")
+	carol := createUser(t, srv, "carol-report@example.com", "pw")
+	moderator := createStaff(t, cfg, srv, "mod-report@example.com", internal.RoleModerator)
+	staff := bearer(moderator.Token)
+	chirp := createChirp(t, srv, bob.Token, "buy my stuff")
+	chirpPath := "/api/chirps/" + chirp.ID.String()
+
+	report := func(path, token string, params map[string]string, want int) Report {
+		t.Helper()
+		resp, body := doRequest(t, srv, http.MethodPost, path, bearer(token), params)
+		expectStatus(t, resp, body, want)
+		if want != http.StatusCreated {
+			return Report{}
+		}
+		return decodeBody[Report](t, body)
+	}
+	queue := func() reportGroupsPage {
+		t.Helper()
+		resp, body := doRequest(t, srv, http.MethodGet, "/admin/reports", staff, nil)
+		expectStatus(t, resp, body, http.StatusOK)
+		return decodeBody[reportGroupsPage](t, body)
+	}
+	resolve := func(id, action string, want int) ReportResolution {
+		t.Helper()
+		resp, body := doRequest(t, srv, http.MethodPost, "/admin/reports/"+id+"/resolve", staff, map[string]string{"action": action, "note": "checked", "duration": "72h"})
+		expectStatus(t, resp, body, want)
+		if want != http.StatusOK {
+			return ReportResolution{}
+		}
+		return decodeBody[ReportResolution](t, body)
+	}
+
+	report(chirpPath+"/report", alice.Token, map[string]string{"category": "nonsense"}, http.StatusBadRequest)
+	report(chirpPath+"/report", bob.Token, map[string]string{"category": "spam"}, http.StatusBadRequest)
+	report("/api/users/"+alice.ID.String()+"/report", alice.Token, map[string]string{"category": "spam"}, http.StatusBadRequest)
+	first := report(chirpPath+"/report", alice.Token, map[string]string{"category": "spam", "details": "ads"}, http.StatusCreated)
+	if first.ReporterID == nil || *first.ReporterID != alice.ID || first.ChirpID == nil || first.UserID != bob.ID {
+		t.Errorf("chirp report = %+v", first)
+	}
+	report(chirpPath+"/report", carol.Token, map[string]string{"category": "spam"}, http.StatusCreated)
+	report(chirpPath+"/report", carol.Token, map[string]string{"category": "other"}, http.StatusCreated)
+	userReport := report("/api/users/"+bob.ID.String()+"/report", alice.Token, map[string]string{"category": "harassment"}, http.StatusCreated)
+
+	resp, body := doRequest(t, srv, http.MethodGet, "/admin/reports", "", nil)
+	expectStatus(t, resp, body, http.StatusUnauthorized)
+	resp, body = doRequest(t, srv, http.MethodGet, "/admin/reports", bearer(alice.Token), nil)
+	expectStatus(t, resp, body, http.StatusForbidden)
+	page := queue()
+	if len(page.Groups) != 2 {
+		t.Fatalf("groups = %+v, want the chirp and the user", page.Groups)
+	}
+	chirpGroup := page.Groups[0]
+	if chirpGroup.ChirpID == nil || *chirpGroup.ChirpID != chirp.ID || chirpGroup.ReportCount != 3 || chirpGroup.ReporterCount != 2 || len(chirpGroup.Reports) != 3 {
+		t.Errorf("chirp group = %+v", chirpGroup)
+	}
+	if chirpGroup.Chirp == nil || chirpGroup.Chirp.Body != "buy my stuff" {
+		t.Errorf("chirp group carries chirp %+v", chirpGroup.Chirp)
+	}
+	if userGroup := page.Groups[1]; userGroup.ChirpID != nil || userGroup.ReportCount != 1 || userGroup.Reports[0].ID != userReport.ID {
+		t.Errorf("user group = %+v", userGroup)
+	}
+
+	resp, body = doRequest(t, srv, http.MethodGet, "/admin/reports?limit=1", staff, nil)
+	expectStatus(t, resp, body, http.StatusOK)
+	if got := decodeBody[reportGroupsPage](t, body); len(got.Groups) != 1 || got.NextCursor == "" {
+		t.Errorf("first page = %+v", got)
+	}
+
+	// resolving any report closes its whole group
+	resolve(first.ID.String(), "shrug", http.StatusBadRequest)
+	resolve(userReport.ID.String(), "delete_chirp", http.StatusBadRequest)
+	resolution := resolve(first.ID.String(), "delete_chirp", http.StatusOK)
+	if resolution.ResolvedBy == nil || *resolution.ResolvedBy != moderator.ID || resolution.Note != "checked" {
+		t.Errorf("resolution = %+v, want it recorded against the moderator", resolution)
+	}
+	resolve(first.ID.String(), "dismiss", http.StatusConflict)
+	resp, body = doRequest(t, srv, http.MethodGet, chirpPath, "", nil)
+	expectStatus(t, resp, body, http.StatusNotFound)
+	if got := queue().Groups; len(got) != 1 || got[0].ChirpID != nil {
+		t.Errorf("queue after deleting the chirp = %+v", got)
+	}
+
+	// suspensions from the queue are temporary and never hit staff
+	resp, body = doRequest(t, srv, http.MethodPost, "/admin/reports/"+userReport.ID.String()+"/resolve", staff, map[string]string{"action": "suspend_user"})
+	expectStatus(t, resp, body, http.StatusBadRequest)
+	staffReport := report("/api/users/"+moderator.ID.String()+"/report", alice.Token, map[string]string{"category": "other"}, http.StatusCreated)
+	resolve(staffReport.ID.String(), "suspend_user", http.StatusForbidden)
+	resolve(staffReport.ID.String(), "dismiss", http.StatusOK)
+
+	resolve(userReport.ID.String(), "suspend_user", http.StatusOK)
+	if got := queue().Groups; len(got) != 0 {
+		t.Errorf("queue after suspending = %+v", got)
+	}
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/login", "", map[string]string{"email": "bob-report@example.com", "password": "pw"})
+	expectStatus(t, resp, body, http.StatusForbidden)
+	// suspending revokes the access tokens bob already has
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/chirps", bearer(bob.Token), map[string]string{"body": "still here"})
+	expectStatus(t, resp, body, http.StatusUnauthorized)
+	if user, _ := cfg.dbQueries.GetUserByID(context.Background(), bob.ID); !user.SuspendedUntil.Valid || time.Until(user.SuspendedUntil.Time) > 72*time.Hour {
+		t.Errorf("bob suspended until %v, want 72h from now", user.SuspendedUntil)
+	}
+}
+
+func TestHeldChirpReview(t *testing.T) {
+	cfg, srv := newTestServer(t)
+	user := createUser(t, srv, "held-review@example.com", "pw")
+	admin := bearer(createStaff(t, cfg, srv, "held-admin@example.com", internal.RoleAdmin).Token)
+	resp, body := doRequest(t, srv, http.MethodPost, "/admin/moderation/rules", admin, map[string]any{
+		"kind": "word", "patterns": []string{"giveaway"}, "action": "hold", "reason": "possible scam",
+	})
+	expectStatus(t, resp, body, http.StatusCreated)
+
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/chirps", bearer(user.Token), map[string]string{"body": "giveaway time"})
+	expectStatus(t, resp, body, http.StatusAccepted)
+	held := decodeBody[Chirp](t, body)
+
+	resp, body = doRequest(t, srv, http.MethodGet, "/admin/reports", admin, nil)
+	expectStatus(t, resp, body, http.StatusOK)
+	groups := decodeBody[reportGroupsPage](t, body).Groups
+	if len(groups) != 1 || groups[0].Chirp == nil || !groups[0].Chirp.Held {
+		t.Fatalf("queue = %+v, want the held chirp", groups)
+	}
+	if got := groups[0].Reports[0]; got.ReporterID != nil || got.Category != reportCategoryHeld || got.Details != "possible scam" {
+		t.Errorf("hold report = %+v", got)
+	}
+
+	// dismissing publishes the chirp
+	resp, body = doRequest(t, srv, http.MethodPost, "/admin/reports/"+groups[0].Reports[0].ID.String()+"/resolve", admin, map[string]string{"action": "dismiss"})
+	expectStatus(t, resp, body, http.StatusOK)
+	if got := decodeBody[ReportResolution](t, body); got.Action != "dismiss" || got.ChirpID == nil || *got.ChirpID != held.ID {
+		t.Errorf("resolution = %+v", got)
+	}
+	resp, body = doRequest(t, srv, http.MethodGet, "/api/chirps/"+held.ID.String(), "", nil)
+	expectStatus(t, resp, body, http.StatusOK)
+	if got := decodeBody[Chirp](t, body); got.Held {
+		t.Errorf("released chirp = %+v", got)
+	}
+}
+
+// failingSuspendStore can't suspend anyone, inside a transaction or out.
+type failingSuspendStore struct {
+	database.Store
+}
+
+func (s failingSuspendStore) SuspendUser(ctx context.Context, arg database.SuspendUserParams) (database.User, error) {
+	return database.User{}, errors.New("suspensions are down")
+}
+
+func (s failingSuspendStore) InTx(ctx context.Context, fn func(database.Store) error) error {
+	return s.Store.InTx(ctx, func(database.Store) error { return fn(s) })
+}
+
+func TestResolveReportFailureKeepsReportsOpen(t *testing.T) {
+	cfg, srv := newTestServer(t)
+	alice := createUser(t, srv, "alice-failed@example.com", "pw")
+	bob := createUser(t, srv, "bob-failed@example.com", "pw")
+	staff := bearer(createStaff(t, cfg, srv, "mod-failed@example.com", internal.RoleModerator).Token)
+	resp, body := doRequest(t, srv, http.MethodPost, "/api/users/"+bob.ID.String()+"/report", bearer(alice.Token), map[string]string{"category": "spam"})
+	expectStatus(t, resp, body, http.StatusCreated)
+	report := decodeBody[Report](t, body)
+
+	cfg.dbQueries = failingSuspendStore{cfg.dbQueries}
+	resp, body = doRequest(t, srv, http.MethodPost, "/admin/reports/"+report.ID.String()+"/resolve", staff, map[string]string{"action": "suspend_user", "duration": "72h"})
+	expectStatus(t, resp, body, http.StatusInternalServerError)
+
+	resp, body = doRequest(t, srv, http.MethodGet, "/admin/reports", staff, nil)
+	expectStatus(t, resp, body, http.StatusOK)
+	if got := decodeBody[reportGroupsPage](t, body).Groups; len(got) != 1 || got[0].Reports[0].ID != report.ID {
+		t.Errorf("queue after a failed suspension = %+v, want the report still open", got)
+	}
+}
+
+// failingReportStore can't file reports, inside a transaction or out.
+type failingReportStore struct {
+	database.Store
+}
+
+func (s failingReportStore) CreateReport(ctx context.Context, arg database.CreateReportParams) (database.Report, error) {
+	return database.Report{}, errors.New("reports are down")
+}
+
+func (s failingReportStore) InTx(ctx context.Context, fn func(database.Store) error) error {
+	return s.Store.InTx(ctx, func(database.Store) error { return fn(s) })
+}
+
+func TestHeldChirpNeedsItsReport(t *testing.T) {
+	cfg, srv := newTestServer(t)
+	alice := createUser(t, srv, "alice-unreported@example.com", "pw")
+	admin := bearer(createStaff(t, cfg, srv, "admin-unreported@example.com", internal.RoleAdmin).Token)
+	resp, body := doRequest(t, srv, http.MethodPost, "/admin/moderation/rules", admin, map[string]any{
+		"kind": "phrase", "patterns": []string{"free money"}, "action": "hold", "reason": "possible spam",
+	})
+	expectStatus(t, resp, body, http.StatusCreated)
+	root := createChirp(t, srv, alice.Token, "nothing to see")
+
+	store := cfg.dbQueries
+	cfg.dbQueries = failingReportStore{store}
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/chirps", bearer(alice.Token), map[string]any{"body": "free money", "in_reply_to": root.ID})
+	expectStatus(t, resp, body, http.StatusInternalServerError)
+	resp, body = doRequest(t, srv, http.MethodPatch, "/api/chirps/"+root.ID.String(), bearer(alice.Token), map[string]string{"body": "free money"})
+	expectStatus(t, resp, body, http.StatusInternalServerError)
+	cfg.dbQueries = store
+
+	// neither chirp was held without a report to review it
+	if hasReplies, err := store.ChirpHasReplies(context.Background(), root.ID); err != nil || hasReplies {
+		t.Errorf("ChirpHasReplies = %v, %v; want the held reply rolled back", hasReplies, err)
+	}
+	resp, body = doRequest(t, srv, http.MethodGet, "/api/chirps/"+root.ID.String(), "", nil)
+	expectStatus(t, resp, body, http.StatusOK)
+	if got := decodeBody[Chirp](t, body); got.Held || got.Body != "nothing to see" {
+		t.Errorf("chirp after a failed edit = %+v", got)
+	}
+}
@@ -14,6 +14,7 @@ func (cfg *apiConfig) handlerReset(w http.ResponseWriter, req *http.Request) {
 	err := cfg.dbQueries.Reset(req.Context())
 	if err != nil {
 		respondWithError(w, 501, "error truncating users db", err)
+		return
 	}
 	// reset counter
 	cfg.fileserverHits.Store(0)
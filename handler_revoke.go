@@ -1,6 +1,8 @@
 package main
 
 import (
+	"database/sql"
+	"errors"
 	"net/http"
 
 	internal "github.com/natretsel/chirpy/internal/auth"
@@ -12,10 +14,18 @@ func (cfg *apiConfig) handlerRevoke(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusBadRequest, "Malformed header", err)
 		return
 	}
-	err = cfg.dbQueries.RevokeToken(r.Context(), refreshToken)
+	token, err := cfg.dbQueries.RevokeToken(r.Context(), internal.HashToken(refreshToken))
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "error revoking refresh token", err)
 		return
 	}
+	// signing out ends the session, and the access tokens issued under it
+	if !token.ReplacedBy.Valid {
+		cfg.sessions.set(token.FamilyID, true)
+	}
 	w.WriteHeader(http.StatusNoContent)
 }
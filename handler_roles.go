@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	internal "github.com/natretsel/chirpy/internal/auth"
+	"github.com/natretsel/chirpy/internal/database"
+)
+
+type contextKey string
+
+// staffIDKey holds the ID of the moderator or admin making an
+// authorized request.
+const staffIDKey contextKey = "staffID"
+
+// middlewareRequireRole only lets requests through whose access token
+// carries at least role. The caller's ID is available to next through
+// staffID.
+func (cfg *apiConfig) middlewareRequireRole(role string, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, err := internal.GetBearerToken(r.Header)
+		if err != nil {
+			respondWithError(w, http.StatusUnauthorized, "malformed header", err)
+			return
+		}
+		claims, err := cfg.parseAccessToken(r.Context(), token)
+		if err != nil {
+			respondWithError(w, http.StatusUnauthorized, "Unauthorized", err)
+			return
+		}
+		userID, err := uuid.Parse(claims.Subject)
+		if err != nil {
+			respondWithError(w, http.StatusUnauthorized, "Unauthorized", err)
+			return
+		}
+		if !internal.HasRole(claims.Role, role) {
+			respondWithError(w, http.StatusForbidden, "requires the "+role+" role", nil)
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), staffIDKey, userID)))
+	})
+}
+
+// staffID returns the caller of a request authorized by
+// middlewareRequireRole.
+func staffID(r *http.Request) uuid.NullUUID {
+	id, ok := r.Context().Value(staffIDKey).(uuid.UUID)
+	return uuid.NullUUID{UUID: id, Valid: ok}
+}
+
+// bootstrapAdmin makes the user with email an admin if there are no
+// admins yet, so a fresh deployment can get its first one. It does
+// nothing once an admin exists. The user must have verified the email,
+// or whoever signed up with it first would become admin.
+func bootstrapAdmin(ctx context.Context, store database.Store, email string) error {
+	admins, err := store.CountAdmins(ctx)
+	if err != nil {
+		return err
+	}
+	if admins > 0 {
+		return nil
+	}
+	user, err := store.GetUserByEmail(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("no user with email %v; sign up first", email)
+	}
+	if err != nil {
+		return err
+	}
+	if !user.VerifiedAt.Valid {
+		return fmt.Errorf("%v isn't verified; verify it first", email)
+	}
+	_, err = store.SetUserRole(ctx, database.SetUserRoleParams{
+		ID:   user.ID,
+		Role: internal.RoleAdmin,
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("made %v the first admin", email)
+	return nil
+}
+
+func (cfg *apiConfig) handlerSetUserRole(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid user ID", err)
+		return
+	}
+	type parameters struct {
+		Role string `json:"role"`
+	}
+	params := parameters{}
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	if !internal.HasRole(params.Role, internal.RoleUser) {
+		respondWithError(w, http.StatusBadRequest, "role must be user, moderator or admin", nil)
+		return
+	}
+	// admins can't demote themselves, so there is always one left
+	if userID == staffID(r).UUID {
+		respondWithError(w, http.StatusBadRequest, "you can't change your own role", nil)
+		return
+	}
+	// the role is carried in access tokens, so the ones the user has
+	// are revoked to make a demotion take effect at once
+	var user database.User
+	err = cfg.dbQueries.InTx(r.Context(), func(store database.Store) error {
+		var err error
+		user, err = store.SetUserRole(r.Context(), database.SetUserRoleParams{
+			ID:   userID,
+			Role: params.Role,
+		})
+		if err != nil {
+			return err
+		}
+		return cfg.revokeAccessTokens(r.Context(), store, user.ID)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Couldn't get user", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't set role", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, userFromDB(user))
+}
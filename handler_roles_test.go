@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	internal "github.com/natretsel/chirpy/internal/auth"
+)
+
+func TestBootstrapAdmin(t *testing.T) {
+	cfg, srv := newTestServer(t)
+	ctx := context.Background()
+	if err := bootstrapAdmin(ctx, cfg.dbQueries, "first@example.com"); err == nil {
+		t.Error("bootstrapping an unknown email succeeded")
+	}
+	first := createUser(t, srv, "first@example.com", "pw")
+	second := createUser(t, srv, "second@example.com", "pw")
+	if first.Role != internal.RoleUser {
+		t.Errorf("new user role = %q, want %q", first.Role, internal.RoleUser)
+	}
+
+	// signing up with ADMIN_EMAIL isn't enough, it takes verifying it
+	cfg.adminEmail = "first@example.com"
+	if err := bootstrapAdmin(ctx, cfg.dbQueries, "first@example.com"); err == nil {
+		t.Error("bootstrapping an unverified email succeeded")
+	}
+	if user, _ := cfg.dbQueries.GetUserByID(ctx, first.ID); user.Role != internal.RoleUser {
+		t.Errorf("unverified first user role = %q, want user", user.Role)
+	}
+	token := emailedTokens(t, cfg, "first@example.com", verificationSubject)[0]
+	resp, body := doRequest(t, srv, http.MethodPost, "/api/users/verify-email", "", map[string]string{"token": token})
+	expectStatus(t, resp, body, http.StatusOK)
+	if got := decodeBody[User](t, body); got.Role != internal.RoleAdmin {
+		t.Errorf("verified first user role = %q, want admin", got.Role)
+	}
+
+	// once there is an admin, bootstrapping does nothing
+	if err := bootstrapAdmin(ctx, cfg.dbQueries, "second@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if user, _ := cfg.dbQueries.GetUserByID(ctx, first.ID); user.Role != internal.RoleAdmin {
+		t.Errorf("first user role = %q, want admin", user.Role)
+	}
+	if user, _ := cfg.dbQueries.GetUserByID(ctx, second.ID); user.Role != internal.RoleUser {
+		t.Errorf("second user role = %q, want user", user.Role)
+	}
+}
+
+func TestSetUserRole(t *testing.T) {
+	cfg, srv := newTestServer(t)
+	admin := createStaff(t, cfg, srv, "roles-admin@example.com", internal.RoleAdmin)
+	user := createUser(t, srv, "roles-user@example.com", "pw")
+	path := "/admin/users/" + user.ID.String() + "/role"
+
+	resp, body := doRequest(t, srv, http.MethodPut, path, bearer(user.Token), map[string]string{"role": "admin"})
+	expectStatus(t, resp, body, http.StatusForbidden)
+	resp, body = doRequest(t, srv, http.MethodPut, path, bearer(admin.Token), map[string]string{"role": "root"})
+	expectStatus(t, resp, body, http.StatusBadRequest)
+	resp, body = doRequest(t, srv, http.MethodPut, "/admin/users/"+admin.ID.String()+"/role", bearer(admin.Token), map[string]string{"role": "user"})
+	expectStatus(t, resp, body, http.StatusBadRequest)
+
+	resp, body = doRequest(t, srv, http.MethodPut, path, bearer(admin.Token), map[string]string{"role": "moderator"})
+	expectStatus(t, resp, body, http.StatusOK)
+	if got := decodeBody[User](t, body); got.Role != internal.RoleModerator {
+		t.Errorf("updated user = %+v", got)
+	}
+
+	// the old token is revoked; logging in picks up the new role
+	resp, body = doRequest(t, srv, http.MethodGet, "/admin/reports", bearer(user.Token), nil)
+	expectStatus(t, resp, body, http.StatusUnauthorized)
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/login", "", map[string]string{"email": user.Email, "password": "pw"})
+	expectStatus(t, resp, body, http.StatusOK)
+	moderator := decodeBody[loginResponse](t, body)
+	resp, body = doRequest(t, srv, http.MethodGet, "/admin/reports", bearer(moderator.Token), nil)
+	expectStatus(t, resp, body, http.StatusOK)
+	resp, body = doRequest(t, srv, http.MethodGet, "/admin/metrics", bearer(moderator.Token), nil)
+	expectStatus(t, resp, body, http.StatusForbidden)
+}
+
+func TestDemotionTakesEffectAtOnce(t *testing.T) {
+	cfg, srv := newTestServer(t)
+	admin := createStaff(t, cfg, srv, "demote-admin@example.com", internal.RoleAdmin)
+	moderator := createStaff(t, cfg, srv, "demote-mod@example.com", internal.RoleModerator)
+	resp, body := doRequest(t, srv, http.MethodGet, "/admin/reports", bearer(moderator.Token), nil)
+	expectStatus(t, resp, body, http.StatusOK)
+
+	// tokens from the millisecond of a revocation survive it
+	time.Sleep(time.Millisecond)
+	resp, body = doRequest(t, srv, http.MethodPut, "/admin/users/"+moderator.ID.String()+"/role", bearer(admin.Token), map[string]string{"role": "user"})
+	expectStatus(t, resp, body, http.StatusOK)
+	resp, body = doRequest(t, srv, http.MethodGet, "/admin/reports", bearer(moderator.Token), nil)
+	expectStatus(t, resp, body, http.StatusUnauthorized)
+	// a refreshed token carries the new role
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/refresh", bearer(moderator.RefreshToken), nil)
+	expectStatus(t, resp, body, http.StatusOK)
+	refreshed := decodeBody[loginResponse](t, body)
+	resp, body = doRequest(t, srv, http.MethodGet, "/admin/reports", bearer(refreshed.Token), nil)
+	expectStatus(t, resp, body, http.StatusForbidden)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	internal "github.com/natretsel/chirpy/internal/auth"
+	"github.com/natretsel/chirpy/internal/database"
+)
+
+// Session is one signed-in device: a login and the refresh tokens
+// rotated from it. Current marks the session of the calling token.
+type Session struct {
+	ID         uuid.UUID `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	SignedInAt time.Time `json:"signed_in_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+func sessionFromDB(row database.ListUserSessionsRow, current uuid.UUID) Session {
+	return Session{
+		ID:         row.FamilyID,
+		UserAgent:  row.UserAgent,
+		IPAddress:  row.IpAddress,
+		SignedInAt: row.SignedInAt,
+		LastUsedAt: row.LastUsedAt,
+		ExpiresAt:  row.ExpiresAt,
+		Current:    row.FamilyID == current,
+	}
+}
+
+// clientIP is the address the request came from, without its port.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// sessionClaims authenticates a session endpoint and returns the
+// caller's access token claims and user ID.
+func (cfg *apiConfig) sessionClaims(w http.ResponseWriter, r *http.Request) (internal.Claims, uuid.UUID, bool) {
+	accessToken, err := internal.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "malformed header", err)
+		return internal.Claims{}, uuid.Nil, false
+	}
+	claims, err := cfg.parseAccessToken(r.Context(), accessToken)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "invalid access token", err)
+		return internal.Claims{}, uuid.Nil, false
+	}
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "invalid access token", err)
+		return internal.Claims{}, uuid.Nil, false
+	}
+	return claims, userID, true
+}
+
+func (cfg *apiConfig) handlerListSessions(w http.ResponseWriter, r *http.Request) {
+	claims, userID, ok := cfg.sessionClaims(w, r)
+	if !ok {
+		return
+	}
+	rows, err := cfg.dbQueries.ListUserSessions(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't list sessions", err)
+		return
+	}
+	type response struct {
+		Sessions []Session `json:"sessions"`
+	}
+	resp := response{Sessions: make([]Session, 0, len(rows))}
+	for _, row := range rows {
+		resp.Sessions = append(resp.Sessions, sessionFromDB(row, claims.Session()))
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+func (cfg *apiConfig) handlerRevokeSession(w http.ResponseWriter, r *http.Request) {
+	_, userID, ok := cfg.sessionClaims(w, r)
+	if !ok {
+		return
+	}
+	sessionID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid session ID", err)
+		return
+	}
+	revoked, err := cfg.dbQueries.RevokeUserSession(r.Context(), database.RevokeUserSessionParams{
+		FamilyID: sessionID,
+		UserID:   userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't revoke session", err)
+		return
+	}
+	if revoked == 0 {
+		respondWithError(w, http.StatusNotFound, "Couldn't get session", nil)
+		return
+	}
+	cfg.sessions.set(sessionID, true)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerRevokeOtherSessions signs the caller out everywhere except the
+// session their access token belongs to.
+func (cfg *apiConfig) handlerRevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	claims, userID, ok := cfg.sessionClaims(w, r)
+	if !ok {
+		return
+	}
+	err := cfg.revokeOtherSessions(r.Context(), cfg.dbQueries, userID, claims.Session())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't revoke sessions", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestSessions(t *testing.T) {
+	_, srv := newTestServer(t)
+	laptop := createUser(t, srv, "sessions@example.com", "pw")
+	login := func() loginResponse {
+		t.Helper()
+		resp, body := doRequest(t, srv, http.MethodPost, "/api/login", "", map[string]string{"email": "sessions@example.com", "password": "pw"})
+		expectStatus(t, resp, body, http.StatusOK)
+		return decodeBody[loginResponse](t, body)
+	}
+	sessions := func(token string) []Session {
+		t.Helper()
+		resp, body := doRequest(t, srv, http.MethodGet, "/api/sessions", bearer(token), nil)
+		expectStatus(t, resp, body, http.StatusOK)
+		return decodeBody[struct {
+			Sessions []Session `json:"sessions"`
+		}](t, body).Sessions
+	}
+	refresh := func(token string, want int) loginResponse {
+		t.Helper()
+		resp, body := doRequest(t, srv, http.MethodPost, "/api/refresh", bearer(token), nil)
+		expectStatus(t, resp, body, want)
+		if want != http.StatusOK {
+			return loginResponse{}
+		}
+		return decodeBody[loginResponse](t, body)
+	}
+	phone := login()
+
+	// refreshing rotates the token but stays in the same session
+	phone = refresh(phone.RefreshToken, http.StatusOK)
+	got := sessions(laptop.Token)
+	if len(got) != 2 {
+		t.Fatalf("sessions = %+v, want the laptop and the phone", got)
+	}
+	var current, other Session
+	for _, s := range got {
+		if s.Current {
+			current = s
+		} else {
+			other = s
+		}
+	}
+	if current.ID == other.ID || current.IPAddress == "" || current.UserAgent == "" {
+		t.Errorf("sessions = %+v, want one current with device details", got)
+	}
+	if other.LastUsedAt.Before(other.SignedInAt) {
+		t.Errorf("phone session last used %v before signing in at %v", other.LastUsedAt, other.SignedInAt)
+	}
+	if onPhone := sessions(phone.Token); len(onPhone) != 2 || onPhone[0].Current == onPhone[1].Current {
+		t.Errorf("sessions from the phone = %+v", onPhone)
+	}
+
+	stranger := createUser(t, srv, "sessions-stranger@example.com", "pw")
+	resp, body := doRequest(t, srv, http.MethodDelete, "/api/sessions/"+other.ID.String(), bearer(stranger.Token), nil)
+	expectStatus(t, resp, body, http.StatusNotFound)
+	resp, body = doRequest(t, srv, http.MethodDelete, "/api/sessions/"+other.ID.String(), bearer(laptop.Token), nil)
+	expectStatus(t, resp, body, http.StatusNoContent)
+	resp, body = doRequest(t, srv, http.MethodDelete, "/api/sessions/"+other.ID.String(), bearer(laptop.Token), nil)
+	expectStatus(t, resp, body, http.StatusNotFound)
+	refresh(phone.RefreshToken, http.StatusUnauthorized)
+
+	tablet, desktop := login(), login()
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/sessions/revoke-all", bearer(laptop.Token), nil)
+	expectStatus(t, resp, body, http.StatusNoContent)
+	refresh(tablet.RefreshToken, http.StatusUnauthorized)
+	refresh(desktop.RefreshToken, http.StatusUnauthorized)
+	refresh(laptop.RefreshToken, http.StatusOK)
+	if got := sessions(laptop.Token); len(got) != 1 || !got[0].Current {
+		t.Errorf("sessions after signing out elsewhere = %+v", got)
+	}
+	resp, body = doRequest(t, srv, http.MethodGet, "/api/sessions", "", nil)
+	expectStatus(t, resp, body, http.StatusUnauthorized)
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/natretsel/chirpy/internal/database"
+)
+
+// errCodeSuspended is the code in the error response a suspended user
+// gets, so clients can tell it apart from a bad password.
+const errCodeSuspended = "account_suspended"
+
+// Suspension describes a user's suspension. SuspendedUntil is nil for
+// a permanent suspension.
+type Suspension struct {
+	UserID         uuid.UUID  `json:"user_id"`
+	SuspendedAt    time.Time  `json:"suspended_at"`
+	SuspendedUntil *time.Time `json:"suspended_until"`
+	Reason         string     `json:"reason"`
+	HidesChirps    bool       `json:"hides_chirps"`
+}
+
+func suspensionFromDB(user database.User) Suspension {
+	s := Suspension{
+		UserID:      user.ID,
+		SuspendedAt: user.SuspendedAt.Time,
+		Reason:      user.SuspensionReason,
+		HidesChirps: user.SuspensionHidesChirps,
+	}
+	if user.SuspendedUntil.Valid {
+		s.SuspendedUntil = &user.SuspendedUntil.Time
+	}
+	return s
+}
+
+// isSuspended reports whether user is serving a suspension. Temporary
+// suspensions lapse on their own once suspended_until passes.
+func isSuspended(user database.User) bool {
+	return user.SuspendedAt.Valid && (!user.SuspendedUntil.Valid || user.SuspendedUntil.Time.After(time.Now().UTC()))
+}
+
+// respondSuspended refuses a request from a suspended user.
+func respondSuspended(w http.ResponseWriter, user database.User) {
+	type response struct {
+		Error          string     `json:"error"`
+		Code           string     `json:"code"`
+		Reason         string     `json:"reason"`
+		SuspendedUntil *time.Time `json:"suspended_until"`
+	}
+	resp := response{
+		Error:  "account suspended",
+		Code:   errCodeSuspended,
+		Reason: user.SuspensionReason,
+	}
+	if user.SuspendedUntil.Valid {
+		resp.SuspendedUntil = &user.SuspendedUntil.Time
+	}
+	respondWithJSON(w, http.StatusForbidden, resp)
+}
+
+// suspensionEnd returns when a suspension lasting duration, a Go
+// duration such as 72h, ends if it starts now.
+func suspensionEnd(duration string) (sql.NullTime, error) {
+	d, err := time.ParseDuration(duration)
+	if err != nil || d <= 0 {
+		return sql.NullTime{}, errors.New("duration must be a positive duration such as 72h")
+	}
+	return sql.NullTime{Time: time.Now().UTC().Add(d), Valid: true}, nil
+}
+
+// suspendUser suspends a user and signs them out everywhere by revoking
+// their refresh and access tokens.
+func (cfg *apiConfig) suspendUser(ctx context.Context, store database.Store, params database.SuspendUserParams) (database.User, error) {
+	user, err := store.SuspendUser(ctx, params)
+	if err != nil {
+		return database.User{}, err
+	}
+	err = store.RevokeUserRefreshTokens(ctx, user.ID)
+	if err != nil {
+		return database.User{}, err
+	}
+	err = cfg.revokeAccessTokens(ctx, store, user.ID)
+	if err != nil {
+		return database.User{}, err
+	}
+	return user, nil
+}
+
+func (cfg *apiConfig) handlerSuspendUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid user ID", err)
+		return
+	}
+	type parameters struct {
+		Reason string `json:"reason"`
+		// Duration is a Go duration such as 72h; empty is permanent
+		Duration   string `json:"duration"`
+		HideChirps bool   `json:"hide_chirps"`
+	}
+	params := parameters{}
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	if params.Reason == "" {
+		respondWithError(w, http.StatusBadRequest, "a suspension needs a reason", nil)
+		return
+	}
+	until := sql.NullTime{}
+	if params.Duration != "" {
+		until, err = suspensionEnd(params.Duration)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+	}
+	if userID == staffID(r).UUID {
+		respondWithError(w, http.StatusBadRequest, "you can't suspend yourself", nil)
+		return
+	}
+	user, err := cfg.suspendUser(r.Context(), cfg.dbQueries, database.SuspendUserParams{
+		ID:                    userID,
+		SuspendedUntil:        until,
+		SuspensionReason:      params.Reason,
+		SuspensionHidesChirps: params.HideChirps,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Couldn't get user", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't suspend user", err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, suspensionFromDB(user))
+}
+
+func (cfg *apiConfig) handlerLiftSuspension(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid user ID", err)
+		return
+	}
+	_, err = cfg.dbQueries.LiftSuspension(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Couldn't get user", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't lift suspension", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
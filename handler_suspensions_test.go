@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+	"time"
+
+	internal "github.com/natretsel/chirpy/internal/auth"
+	"github.com/natretsel/chirpy/internal/database"
+)
+
+func TestSuspensions(t *testing.T) {
+	cfg, srv := newTestServer(t)
+	alice := createUser(t, srv, "alice-suspend@example.com", "pw")
+	bob := createUser(t, srv, "bob-suspend@example.com", "pw")
+	admin := createStaff(t, cfg, srv, "admin-suspend@example.com", internal.RoleAdmin)
+	staff := bearer(admin.Token)
+	createChirp(t, srv, alice.Token, "hello from alice")
+	createChirp(t, srv, bob.Token, "hello from bob")
+	path := "/admin/users/" + alice.ID.String() + "/suspension"
+	aliceLogin := map[string]string{"email": "alice-suspend@example.com", "password": "pw"}
+
+	listed := func() []Chirp {
+		t.Helper()
+		resp, body := doRequest(t, srv, http.MethodGet, "/api/chirps", "", nil)
+		expectStatus(t, resp, body, http.StatusOK)
+		return decodeBody[chirpsPage](t, body).Chirps
+	}
+	expectSuspended := func(resp *http.Response, body []byte) {
+		t.Helper()
+		expectStatus(t, resp, body, http.StatusForbidden)
+		got := decodeBody[struct {
+			Code   string `json:"code"`
+			Reason string `json:"reason"`
+		}](t, body)
+		if got.Code != errCodeSuspended || got.Reason != "spam" {
+			t.Errorf("suspended response = %s", body)
+		}
+	}
+
+	resp, body := doRequest(t, srv, http.MethodPost, path, bearer(bob.Token), map[string]any{"reason": "spam"})
+	expectStatus(t, resp, body, http.StatusForbidden)
+	resp, body = doRequest(t, srv, http.MethodPost, path, staff, map[string]any{})
+	expectStatus(t, resp, body, http.StatusBadRequest)
+	resp, body = doRequest(t, srv, http.MethodPost, path, staff, map[string]any{"reason": "spam", "duration": "a while"})
+	expectStatus(t, resp, body, http.StatusBadRequest)
+	resp, body = doRequest(t, srv, http.MethodPost, "/admin/users/"+admin.ID.String()+"/suspension", staff, map[string]any{"reason": "spam"})
+	expectStatus(t, resp, body, http.StatusBadRequest)
+
+	resp, body = doRequest(t, srv, http.MethodPost, path, staff, map[string]any{"reason": "spam", "duration": "72h", "hide_chirps": true})
+	expectStatus(t, resp, body, http.StatusOK)
+	if got := decodeBody[Suspension](t, body); got.SuspendedUntil == nil || !got.HidesChirps || got.Reason != "spam" {
+		t.Errorf("suspension = %+v", got)
+	}
+
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/login", "", aliceLogin)
+	expectSuspended(resp, body)
+	// suspending signs the user out everywhere
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/chirps", bearer(alice.Token), map[string]string{"body": "let me back"})
+	expectStatus(t, resp, body, http.StatusUnauthorized)
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/refresh", bearer(alice.RefreshToken), nil)
+	expectStatus(t, resp, body, http.StatusUnauthorized)
+	if got := listed(); len(got) != 1 || got[0].UserID != bob.ID {
+		t.Errorf("chirps during suspension = %+v, want only bob's", got)
+	}
+
+	resp, body = doRequest(t, srv, http.MethodDelete, path, staff, nil)
+	expectStatus(t, resp, body, http.StatusNoContent)
+	if got := listed(); len(got) != 2 {
+		t.Errorf("chirps after lifting = %+v, want both", got)
+	}
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/login", "", aliceLogin)
+	expectStatus(t, resp, body, http.StatusOK)
+}
+
+func TestSuspensionLapses(t *testing.T) {
+	cfg, srv := newTestServer(t)
+	user := createUser(t, srv, "lapsed@example.com", "pw")
+	createChirp(t, srv, user.Token, "still around")
+	_, err := cfg.dbQueries.SuspendUser(context.Background(), database.SuspendUserParams{
+		ID:                    user.ID,
+		SuspendedUntil:        sql.NullTime{Time: time.Now().UTC().Add(-time.Minute), Valid: true},
+		SuspensionReason:      "spam",
+		SuspensionHidesChirps: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, body := doRequest(t, srv, http.MethodPost, "/api/login", "", map[string]string{"email": "lapsed@example.com", "password": "pw"})
+	expectStatus(t, resp, body, http.StatusOK)
+	resp, body = doRequest(t, srv, http.MethodGet, "/api/chirps", "", nil)
+	expectStatus(t, resp, body, http.StatusOK)
+	if got := decodeBody[chirpsPage](t, body).Chirps; len(got) != 1 {
+		t.Errorf("chirps after the suspension ended = %+v", got)
+	}
+}
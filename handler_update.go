@@ -1,9 +1,13 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 
+	"github.com/google/uuid"
 	internal "github.com/natretsel/chirpy/internal/auth"
 	"github.com/natretsel/chirpy/internal/database"
 )
@@ -16,7 +20,12 @@ func (cfg *apiConfig) handlerUpdateInfo(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	// Verify access token, return unauthorized if invalid
-	userId, err := internal.ValidateJWT(accessToken, cfg.secret)
+	claims, err := cfg.parseAccessToken(r.Context(), accessToken)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "invalid access token", err)
+		return
+	}
+	userId, err := uuid.Parse(claims.Subject)
 	if err != nil {
 		respondWithError(w, http.StatusUnauthorized, "invalid access token", err)
 		return
@@ -26,6 +35,7 @@ func (cfg *apiConfig) handlerUpdateInfo(w http.ResponseWriter, r *http.Request)
 	type userParameters struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		Handle   string `json:"handle"`
 	}
 
 	decoder := json.NewDecoder(r.Body)
@@ -35,21 +45,50 @@ func (cfg *apiConfig) handlerUpdateInfo(w http.ResponseWriter, r *http.Request)
 		respondWithError(w, http.StatusBadRequest, "Unable to decode request body", err)
 		return
 	}
+	email, err := emailParam(reqBody.Email)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	handle, err := handleParam(reqBody.Handle)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
 	// Get user by ID
 	userDBObj, err := cfg.dbQueries.GetUserByID(r.Context(), userId)
 	if err != nil {
 		respondWithError(w, http.StatusUnauthorized, "Invalid user", err)
+		return
 	}
 	// hash password, check if it's the same pw
 	hashedPW, err := internal.HashPassword(reqBody.Password)
 	if err != nil || hashedPW == userDBObj.HashedPassword {
 		respondWithError(w, http.StatusBadRequest, "Please use a different password", err)
+		return
+	}
+
+	// a new email stays pending until it is verified; asking for the
+	// current one again drops a pending change
+	pendingEmail := sql.NullString{}
+	if email != userDBObj.Email {
+		_, err = cfg.dbQueries.GetUserByEmail(r.Context(), email)
+		if err == nil {
+			respondWithError(w, http.StatusConflict, "email already in use", nil)
+			return
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusInternalServerError, "couldn't check email", err)
+			return
+		}
+		pendingEmail = sql.NullString{String: email, Valid: true}
 	}
 
 	// update in DB and respond with updated user resource
 	updatedUser, err := cfg.dbQueries.UpdateLoginDetailsByID(r.Context(), database.UpdateLoginDetailsByIDParams{
 		HashedPassword: hashedPW,
-		Email:          reqBody.Email,
+		Email:          userDBObj.Email,
+		Handle:         handle,
 		ID:             userId,
 	})
 
@@ -57,17 +96,39 @@ func (cfg *apiConfig) handlerUpdateInfo(w http.ResponseWriter, r *http.Request)
 		respondWithError(w, http.StatusInternalServerError, "Couldn't update password in DB", err)
 		return
 	}
+	if pendingEmail != userDBObj.PendingEmail {
+		updatedUser, err = cfg.dbQueries.SetPendingEmail(r.Context(), database.SetPendingEmailParams{
+			ID:           userId,
+			PendingEmail: pendingEmail,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "couldn't set pending email", err)
+			return
+		}
+		if pendingEmail.Valid {
+			err = cfg.sendEmailVerification(r.Context(), userId, pendingEmail.String)
+			if err != nil {
+				log.Printf("couldn't send verification email: %v", err)
+			}
+		}
+	}
+	// a new password invalidates every access token, this one included,
+	// and signs out every other device; the caller refreshes to go on
+	err = cfg.revokeAccessTokens(r.Context(), cfg.dbQueries, userId)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't revoke access tokens", err)
+		return
+	}
+	err = cfg.revokeOtherSessions(r.Context(), cfg.dbQueries, userId, claims.Session())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't revoke sessions", err)
+		return
+	}
 
 	type response struct {
 		User
 	}
 	respondWithJSON(w, http.StatusOK, response{
-		User: User{
-			ID:            updatedUser.ID,
-			CreatedAt:     updatedUser.CreatedAt,
-			UpdatedAt:     updatedUser.UpdatedAt,
-			Email:         updatedUser.Email,
-			Is_chirpy_red: updatedUser.IsChirpyRed.Bool,
-		},
+		User: userFromDB(updatedUser),
 	})
 }
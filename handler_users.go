@@ -1,8 +1,12 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,6 +20,45 @@ type User struct {
 	UpdatedAt     time.Time `json:"updated_at"`
 	Email         string    `json:"email"`
 	Is_chirpy_red bool      `json:"is_chirpy_red"`
+	Handle        *string   `json:"handle"`
+	Role          string    `json:"role"`
+	EmailVerified bool      `json:"email_verified"`
+	MFAEnabled    bool      `json:"mfa_enabled"`
+	// PendingEmail is a new email waiting to be verified
+	PendingEmail *string `json:"pending_email,omitempty"`
+}
+
+func userFromDB(user database.User) User {
+	u := User{
+		ID:            user.ID,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+		Email:         user.Email,
+		Is_chirpy_red: user.IsChirpyRed.Bool,
+		Role:          user.Role,
+		EmailVerified: user.VerifiedAt.Valid,
+		MFAEnabled:    user.TotpEnabledAt.Valid,
+	}
+	if user.Handle.Valid {
+		u.Handle = &user.Handle.String
+	}
+	if user.PendingEmail.Valid {
+		u.PendingEmail = &user.PendingEmail.String
+	}
+	return u
+}
+
+// handleParam validates the optional handle in a user payload, which is
+// stored lowercased. An empty handle gives NULL.
+func handleParam(handle string) (sql.NullString, error) {
+	if handle == "" {
+		return sql.NullString{}, nil
+	}
+	handle = strings.ToLower(strings.TrimPrefix(handle, "@"))
+	if !validHandle(handle) {
+		return sql.NullString{}, errors.New("handle must be up to 15 letters, digits or underscores")
+	}
+	return sql.NullString{String: handle, Valid: true}, nil
 }
 
 func (cfg *apiConfig) handlerCreateUser(w http.ResponseWriter, r *http.Request) {
@@ -23,6 +66,7 @@ func (cfg *apiConfig) handlerCreateUser(w http.ResponseWriter, r *http.Request)
 	type userParameters struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		Handle   string `json:"handle"`
 	}
 	type userResponse struct {
 		User
@@ -47,29 +91,41 @@ func (cfg *apiConfig) handlerCreateUser(w http.ResponseWriter, r *http.Request)
 		}
 	*/
 
+	email, err := emailParam(userParam.Email)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	handle, err := handleParam(userParam.Handle)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
 	// create user in DB with the email
 	hashedPassword, err := internal.HashPassword(userParam.Password)
 	if err != nil {
 		respondWithError(w, http.StatusBadRequest, "please use a different password", err)
+		return
 	}
 	user, err := cfg.dbQueries.CreateUser(r.Context(), database.CreateUserParams{
-		Email:          userParam.Email,
+		Email:          email,
 		HashedPassword: hashedPassword,
+		Handle:         handle,
 	})
 	if err != nil {
 		respondWithError(w, http.StatusBadRequest, "Couldn't create user", err)
 		return
 	}
+	// the account works without it, and the user can ask for another
+	err = cfg.sendEmailVerification(r.Context(), user.ID, user.Email)
+	if err != nil {
+		log.Printf("couldn't send verification email: %v", err)
+	}
 
 	// if successfully created, api response with code 201
 	userJSON := userResponse{
-		User: User{
-			ID:            user.ID,
-			CreatedAt:     user.CreatedAt,
-			UpdatedAt:     user.UpdatedAt,
-			Email:         user.Email,
-			Is_chirpy_red: user.IsChirpyRed.Bool,
-		},
+		User: userFromDB(user),
 	}
 
 	respondWithJSON(w, http.StatusCreated, userJSON)
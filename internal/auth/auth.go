@@ -2,6 +2,7 @@ package internal
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"net/http"
@@ -17,6 +18,17 @@ type TokenType string
 
 const TokenAccess TokenType = "chirpy"
 
+// issuedAtPrecision is the precision of an access token's issue time,
+// which is compared against its user's tokens-valid-after cutoff.
+const issuedAtPrecision = time.Millisecond
+
+func init() {
+	// Claims are written with a digit more than issue times need: they
+	// are read back as float seconds, which can land a hair under the
+	// millisecond that was written, and ParseJWT rounds that away.
+	jwt.TimePrecision = time.Microsecond
+}
+
 func HashPassword(password string) (string, error) {
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
@@ -29,44 +41,110 @@ func CheckPasswordHash(hash, password string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 }
 
-func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
-	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
-		Issuer:    "chirpy",
-		IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
-		Subject:   userID.String(),
-	})
-	signedJWT, err := jwtToken.SignedString([]byte(tokenSecret))
+// User roles, from least to most privileged.
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// Claims are the claims of a Chirpy access token. Every token gets a
+// unique ID (jti). Role is the user's role when the token was issued,
+// and SessionID the refresh token family of the login it was issued
+// under.
+type Claims struct {
+	jwt.RegisteredClaims
+	Role      string `json:"role,omitempty"`
+	SessionID string `json:"sid,omitempty"`
+}
+
+func MakeJWT(userID uuid.UUID, role string, sessionID uuid.UUID, keys *Keyring, expiresIn time.Duration) (string, error) {
+	now := time.Now().UTC().Truncate(issuedAtPrecision)
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "chirpy",
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiresIn)),
+			Subject:   userID.String(),
+			ID:        uuid.NewString(),
+		},
+		Role: role,
+	}
+	if sessionID != uuid.Nil {
+		claims.SessionID = sessionID.String()
+	}
+	signedJWT, err := keys.sign(claims)
 	if err != nil {
 		return "", fmt.Errorf("error signing secret token: %v", err)
 	}
 	return signedJWT, nil
 }
 
-func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	claimsStruct := jwt.RegisteredClaims{}
-	jwtToken, err := jwt.ParseWithClaims(tokenString, &claimsStruct, func(token *jwt.Token) (interface{}, error) {
-		return []byte(tokenSecret), nil
-	})
+func ValidateJWT(tokenString string, keys *Keyring) (uuid.UUID, error) {
+	id, _, err := ValidateJWTRole(tokenString, keys)
+	return id, err
+}
+
+// ValidateJWTRole is ValidateJWT that also returns the role claim.
+func ValidateJWTRole(tokenString string, keys *Keyring) (uuid.UUID, string, error) {
+	claims, err := ParseJWT(tokenString, keys)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("error parsing token string: %v", err)
+		return uuid.Nil, "", err
 	}
-	idString, err := jwtToken.Claims.GetSubject()
+	id, _ := uuid.Parse(claims.Subject)
+	return id, claims.Role, nil
+}
+
+// ParseJWT validates an access token against the keyring and returns
+// its claims. Tokens issued before roles existed carry none and count
+// as RoleUser.
+func ParseJWT(tokenString string, keys *Keyring) (Claims, error) {
+	claims := Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, &claims, keys.verificationKey,
+		jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg(), jwt.SigningMethodRS256.Alg()}))
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("error retrieving id from token: %v", err)
+		return Claims{}, fmt.Errorf("error parsing token string: %v", err)
+	}
+	if claims.Issuer != string(TokenAccess) {
+		return Claims{}, fmt.Errorf("invalid issuer")
 	}
-	issuer, err := jwtToken.Claims.GetIssuer()
+	_, err = uuid.Parse(claims.Subject)
 	if err != nil {
-		return uuid.Nil, err
+		return Claims{}, fmt.Errorf("invalid user ID: %v", err)
 	}
-	if issuer != string(TokenAccess) {
-		return uuid.Nil, fmt.Errorf("invalid issuer")
+	if claims.Role == "" {
+		claims.Role = RoleUser
 	}
-	id, err := uuid.Parse(idString)
+	if claims.IssuedAt != nil {
+		claims.IssuedAt.Time = claims.IssuedAt.Round(issuedAtPrecision)
+	}
+	return claims, nil
+}
+
+// Session returns the session the token was issued under, or uuid.Nil
+// for tokens from before sessions existed.
+func (c Claims) Session() uuid.UUID {
+	id, err := uuid.Parse(c.SessionID)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("invalid user ID: %v", err)
+		return uuid.Nil
+	}
+	return id
+}
+
+// HasRole reports whether role grants at least the privileges of want.
+func HasRole(role, want string) bool {
+	rank := func(r string) int {
+		switch r {
+		case RoleAdmin:
+			return 3
+		case RoleModerator:
+			return 2
+		case RoleUser:
+			return 1
+		}
+		return 0
 	}
-	return id, nil
+	return rank(role) >= rank(want) && rank(want) > 0
 }
 
 func GetBearerToken(headers http.Header) (string, error) {
@@ -83,14 +161,40 @@ func GetBearerToken(headers http.Header) (string, error) {
 }
 
 func MakeRefreshToken() (string, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", fmt.Errorf("unable to generate refresh token: %v", err)
+	}
+	return token, nil
+}
+
+// MakeOneTimeToken returns a token for a link emailed to a user, such
+// as a password reset.
+func MakeOneTimeToken() (string, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", fmt.Errorf("unable to generate one-time token: %v", err)
+	}
+	return token, nil
+}
+
+func randomToken() (string, error) {
 	token := make([]byte, 32)
 	_, err := rand.Read(token)
 	if err != nil {
-		return "", fmt.Errorf("unable to generate refresh token: %v", err)
+		return "", err
 	}
 	return hex.EncodeToString(token), nil
 }
 
+// HashToken returns the hex SHA-256 digest under which a refresh or
+// one-time token is stored. The tokens are random, so an unsalted
+// digest is enough to keep a database copy from being usable.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 func GetAPIKey(headers http.Header) (string, error) {
 	authorizationKV := headers.Get("Authorization")
 	if len(authorizationKV) == 0 {
@@ -63,35 +63,50 @@ func TestCheckPasswordHash(t *testing.T) {
 	}
 }
 
+// newTestKeyring returns a keyring with a fresh Ed25519 key.
+func newTestKeyring(t *testing.T, id string) *Keyring {
+	t.Helper()
+	key, err := GenerateKey(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := NewKeyring(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
 func TestValidateJWT(t *testing.T) {
 	userID := uuid.New()
-	validToken, _ := MakeJWT(userID, "secret", time.Hour)
+	keys := newTestKeyring(t, "test")
+	validToken, _ := MakeJWT(userID, RoleUser, uuid.Nil, keys, time.Hour)
 
 	tests := []struct {
 		name        string
 		tokenString string
-		tokenSecret string
+		keys        *Keyring
 		wantUserID  uuid.UUID
 		wantErr     bool
 	}{
 		{
 			name:        "Valid token",
 			tokenString: validToken,
-			tokenSecret: "secret",
+			keys:        keys,
 			wantUserID:  userID,
 			wantErr:     false,
 		},
 		{
 			name:        "Invalid token",
 			tokenString: "invalid.token.string",
-			tokenSecret: "secret",
+			keys:        keys,
 			wantUserID:  uuid.Nil,
 			wantErr:     true,
 		},
 		{
-			name:        "Wrong secret",
+			name:        "Unknown key",
 			tokenString: validToken,
-			tokenSecret: "wrong_secret",
+			keys:        newTestKeyring(t, "other"),
 			wantUserID:  uuid.Nil,
 			wantErr:     true,
 		},
@@ -99,7 +114,7 @@ func TestValidateJWT(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotUserID, err := ValidateJWT(tt.tokenString, tt.tokenSecret)
+			gotUserID, err := ValidateJWT(tt.tokenString, tt.keys)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ValidateJWT() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -111,6 +126,93 @@ func TestValidateJWT(t *testing.T) {
 	}
 }
 
+func TestValidateJWTRole(t *testing.T) {
+	userID := uuid.New()
+	keys := newTestKeyring(t, "test")
+	token, err := MakeJWT(userID, RoleModerator, uuid.Nil, keys, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotID, gotRole, err := ValidateJWTRole(token, keys)
+	if err != nil || gotID != userID || gotRole != RoleModerator {
+		t.Errorf("ValidateJWTRole() = %v, %q, %v", gotID, gotRole, err)
+	}
+
+	// tokens without a role claim belong to plain users
+	token, _ = MakeJWT(userID, "", uuid.Nil, keys, time.Hour)
+	if _, gotRole, _ = ValidateJWTRole(token, keys); gotRole != RoleUser {
+		t.Errorf("role of a token without one = %q, want %q", gotRole, RoleUser)
+	}
+}
+
+func TestParseJWTSession(t *testing.T) {
+	userID, sessionID := uuid.New(), uuid.New()
+	keys := newTestKeyring(t, "test")
+	token, err := MakeJWT(userID, RoleUser, sessionID, keys, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := ParseJWT(token, keys)
+	if err != nil || claims.Session() != sessionID {
+		t.Errorf("ParseJWT() session = %v, %v, want %v", claims.Session(), err, sessionID)
+	}
+	if _, err := uuid.Parse(claims.ID); err != nil {
+		t.Errorf("jti = %q, want a UUID", claims.ID)
+	}
+
+	token, _ = MakeJWT(userID, RoleUser, uuid.Nil, keys, time.Hour)
+	other, _ := ParseJWT(token, keys)
+	if other.SessionID != "" || other.Session() != uuid.Nil {
+		t.Errorf("token without a session has sid %q", other.SessionID)
+	}
+	if other.ID == claims.ID {
+		t.Errorf("two tokens share jti %q", claims.ID)
+	}
+}
+
+func TestParseJWTIssuedAt(t *testing.T) {
+	keys := newTestKeyring(t, "test")
+	// issue times are read back from float seconds; enough tokens cover
+	// the milliseconds that don't survive that exactly
+	for range 500 {
+		before := time.Now().UTC().Truncate(time.Millisecond)
+		token, err := MakeJWT(uuid.New(), RoleUser, uuid.Nil, keys, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		after := time.Now().UTC()
+		claims, err := ParseJWT(token, keys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		iat := claims.IssuedAt.Time
+		if !iat.Equal(iat.Truncate(time.Millisecond)) || iat.Before(before) || iat.After(after) {
+			t.Fatalf("iat = %v, want the millisecond between %v and %v", iat, before, after)
+		}
+	}
+}
+
+func TestHasRole(t *testing.T) {
+	tests := []struct {
+		role string
+		want string
+		ok   bool
+	}{
+		{role: RoleAdmin, want: RoleModerator, ok: true},
+		{role: RoleAdmin, want: RoleAdmin, ok: true},
+		{role: RoleModerator, want: RoleModerator, ok: true},
+		{role: RoleModerator, want: RoleAdmin, ok: false},
+		{role: RoleUser, want: RoleModerator, ok: false},
+		{role: "root", want: RoleUser, ok: false},
+		{role: RoleAdmin, want: "root", ok: false},
+	}
+	for _, tt := range tests {
+		if got := HasRole(tt.role, tt.want); got != tt.ok {
+			t.Errorf("HasRole(%q, %q) = %v, want %v", tt.role, tt.want, got, tt.ok)
+		}
+	}
+}
+
 func TestGetBearerToken(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -155,3 +257,10 @@ func TestGetBearerToken(t *testing.T) {
 		})
 	}
 }
+
+func TestHashToken(t *testing.T) {
+	const want = "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+	if got := HashToken("abc"); got != want {
+		t.Errorf("HashToken(abc) = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,211 @@
+package internal
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Key is an access token signing key. Its ID is sent as the kid header
+// of every token it signs. Private is nil for a retired key that is
+// only kept to verify tokens it signed earlier.
+type Key struct {
+	ID      string
+	Private crypto.Signer
+	Public  crypto.PublicKey
+}
+
+// method is the JWT signing method for the key's type: EdDSA for
+// Ed25519 keys and RS256 for RSA keys.
+func (k Key) method() (jwt.SigningMethod, error) {
+	switch k.Public.(type) {
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	}
+	return nil, fmt.Errorf("key %v: unsupported key type %T", k.ID, k.Public)
+}
+
+// Keyring holds the key that signs new access tokens and every key
+// whose tokens are still accepted.
+type Keyring struct {
+	active Key
+	keys   []Key
+}
+
+// NewKeyring returns a keyring that signs with active and also accepts
+// tokens signed by the others.
+func NewKeyring(active Key, others ...Key) (*Keyring, error) {
+	if active.Private == nil {
+		return nil, fmt.Errorf("key %v: the signing key needs its private key", active.ID)
+	}
+	keys := append([]Key{active}, others...)
+	for i, k := range keys {
+		if k.ID == "" {
+			return nil, fmt.Errorf("keys need an ID")
+		}
+		if slices.ContainsFunc(keys[:i], func(other Key) bool { return other.ID == k.ID }) {
+			return nil, fmt.Errorf("key %v: duplicate key ID", k.ID)
+		}
+		if _, err := k.method(); err != nil {
+			return nil, err
+		}
+	}
+	return &Keyring{active: active, keys: keys}, nil
+}
+
+// GenerateKey returns a new Ed25519 signing key.
+func GenerateKey(id string) (Key, error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return Key{}, fmt.Errorf("unable to generate key: %v", err)
+	}
+	return Key{ID: id, Private: private, Public: public}, nil
+}
+
+// LoadKeyring reads the keys in dir. Each <kid>.pem file holds either a
+// PKCS #8 private key or, for a retired key, just its PKIX public key;
+// Ed25519 and RSA keys are supported. The key named activeID signs new
+// tokens. If activeID is empty the private key whose ID sorts last is
+// used, so naming keys by date makes the newest one active.
+func LoadKeyring(dir, activeID string) (*Keyring, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	keys := []Key{}
+	for _, path := range paths {
+		key, err := loadKey(path)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no keys in %v", dir)
+	}
+	if activeID == "" {
+		for _, k := range keys {
+			if k.Private != nil && k.ID > activeID {
+				activeID = k.ID
+			}
+		}
+	}
+	i := slices.IndexFunc(keys, func(k Key) bool { return k.ID == activeID })
+	if i < 0 {
+		return nil, fmt.Errorf("no signing key %q in %v", activeID, dir)
+	}
+	active := keys[i]
+	return NewKeyring(active, slices.Delete(keys, i, i+1)...)
+}
+
+func loadKey(path string) (Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Key{}, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return Key{}, fmt.Errorf("%v: no PEM data", path)
+	}
+	key := Key{ID: strings.TrimSuffix(filepath.Base(path), ".pem")}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return Key{}, fmt.Errorf("%v: %v", path, err)
+		}
+		signer, ok := parsed.(crypto.Signer)
+		if !ok {
+			return Key{}, fmt.Errorf("%v: unsupported key type %T", path, parsed)
+		}
+		key.Private = signer
+		key.Public = signer.Public()
+	case "PUBLIC KEY":
+		key.Public, err = x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return Key{}, fmt.Errorf("%v: %v", path, err)
+		}
+	default:
+		return Key{}, fmt.Errorf("%v: unexpected PEM block %q", path, block.Type)
+	}
+	return key, nil
+}
+
+// sign signs claims with the active key.
+func (k *Keyring) sign(claims jwt.Claims) (string, error) {
+	method, err := k.active.method()
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = k.active.ID
+	return token.SignedString(k.active.Private)
+}
+
+// verificationKey is the jwt.Keyfunc that picks the key named by a
+// token's kid header. The token's alg has to match the key's type, so
+// an RSA public key can't be passed off as an HMAC secret.
+func (k *Keyring) verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	i := slices.IndexFunc(k.keys, func(key Key) bool { return key.ID == kid })
+	if i < 0 {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	method, err := k.keys[i].method()
+	if err != nil {
+		return nil, err
+	}
+	if token.Method.Alg() != method.Alg() {
+		return nil, fmt.Errorf("key %v doesn't sign %v tokens", kid, token.Method.Alg())
+	}
+	return k.keys[i].Public, nil
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517).
+type JWK struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	Alg     string `json:"alg"`
+	Curve   string `json:"crv,omitempty"`
+	X       string `json:"x,omitempty"`
+	N       string `json:"n,omitempty"`
+	E       string `json:"e,omitempty"`
+}
+
+// JWKS returns the public half of every key on the keyring, active key
+// first, so other services can verify access tokens.
+func (k *Keyring) JWKS() []JWK {
+	jwks := make([]JWK, 0, len(k.keys))
+	for _, key := range k.keys {
+		jwk := JWK{KeyID: key.ID, Use: "sig"}
+		switch public := key.Public.(type) {
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Alg = jwt.SigningMethodEdDSA.Alg()
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.Alg = jwt.SigningMethodRS256.Alg()
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		}
+		jwks = append(jwks, jwk)
+	}
+	return jwks
+}
@@ -0,0 +1,152 @@
+package internal
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// writeKey saves key's private half, or only its public half when
+// public is set, as dir/<kid>.pem.
+func writeKey(t *testing.T, dir string, key Key, public bool) {
+	t.Helper()
+	var block *pem.Block
+	if public {
+		der, err := x509.MarshalPKIXPublicKey(key.Public)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block = &pem.Block{Type: "PUBLIC KEY", Bytes: der}
+	} else {
+		der, err := x509.MarshalPKCS8PrivateKey(key.Private)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	}
+	err := os.WriteFile(filepath.Join(dir, key.ID+".pem"), pem.EncodeToMemory(block), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadKeyring(t *testing.T) {
+	dir := t.TempDir()
+	retired, err := GenerateKey("2026-01-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous, err := GenerateKey("2026-03-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current := Key{ID: "2026-06-01", Private: rsaKey, Public: rsaKey.Public()}
+	writeKey(t, dir, retired, true)
+	writeKey(t, dir, previous, false)
+	writeKey(t, dir, current, false)
+
+	keys, err := LoadKeyring(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID := uuid.New()
+	token, err := MakeJWT(userID, RoleUser, uuid.Nil, keys, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &Claims{})
+	if err != nil || parsed.Header["kid"] != "2026-06-01" || parsed.Method.Alg() != "RS256" {
+		t.Errorf("token header = %v, want the newest key signing RS256", parsed.Header)
+	}
+
+	// tokens signed by keys that are no longer active still validate
+	for _, old := range []Key{retired, previous} {
+		oldKeys, err := NewKeyring(old)
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, _ := MakeJWT(userID, RoleUser, uuid.Nil, oldKeys, time.Hour)
+		if got, err := ValidateJWT(token, keys); err != nil || got != userID {
+			t.Errorf("token signed by %v = %v, %v", old.ID, got, err)
+		}
+	}
+
+	keys, err = LoadKeyring(dir, "2026-03-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _ = MakeJWT(userID, RoleUser, uuid.Nil, keys, time.Hour)
+	if parsed, _, _ := jwt.NewParser().ParseUnverified(token, &Claims{}); parsed.Header["kid"] != "2026-03-01" {
+		t.Errorf("kid = %v, want the configured key", parsed.Header["kid"])
+	}
+	if _, err := LoadKeyring(dir, "2026-01-01"); err == nil {
+		t.Error("a public-only key was accepted as the signing key")
+	}
+	if _, err := LoadKeyring(dir, "missing"); err == nil {
+		t.Error("an unknown signing key ID was accepted")
+	}
+	if _, err := LoadKeyring(t.TempDir(), ""); err == nil {
+		t.Error("an empty key directory was accepted")
+	}
+}
+
+func TestParseJWTRejectsAlgorithmConfusion(t *testing.T) {
+	keys := newTestKeyring(t, "test")
+	public := keys.active.Public.(ed25519.PublicKey)
+	// an HMAC token keyed with the published public key
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    string(TokenAccess),
+			Subject:   uuid.NewString(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	})
+	forged.Header["kid"] = "test"
+	token, err := forged.SignedString([]byte(public))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseJWT(token, keys); err == nil {
+		t.Error("ParseJWT() accepted an HS256 token")
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	key, err := GenerateKey("ed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := NewKeyring(key, Key{ID: "rsa", Public: rsaKey.Public()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwks := keys.JWKS()
+	if len(jwks) != 2 {
+		t.Fatalf("JWKS() = %+v, want both keys", jwks)
+	}
+	x, err := base64.RawURLEncoding.DecodeString(jwks[0].X)
+	if err != nil || jwks[0].KeyID != "ed" || jwks[0].KeyType != "OKP" || jwks[0].Alg != "EdDSA" || !key.Public.(ed25519.PublicKey).Equal(ed25519.PublicKey(x)) {
+		t.Errorf("Ed25519 JWK = %+v", jwks[0])
+	}
+	if got := jwks[1]; got.KeyID != "rsa" || got.KeyType != "RSA" || got.Alg != "RS256" || got.E != "AQAB" || got.N == "" {
+		t.Errorf("RSA JWK = %+v", got)
+	}
+}
@@ -0,0 +1,89 @@
+package internal
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHOTPVectors(t *testing.T) {
+	// the SHA-1 test vectors of RFC 6238, appendix B
+	key := []byte("12345678901234567890")
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+	for _, tt := range tests {
+		if got := hotp(key, totpStep(time.Unix(tt.unix, 0)), 8); got != tt.want {
+			t.Errorf("hotp at %d = %v, want %v", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1_800_000_000, 0)
+	code, err := TOTPCode(secret, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	step, ok := ValidateTOTP(secret, code, now)
+	if !ok || step != totpStep(now) {
+		t.Errorf("ValidateTOTP() = %v, %v, want the current step", step, ok)
+	}
+	// a code from the previous period is still accepted, but not one
+	// from two periods ago
+	if _, ok := ValidateTOTP(secret, code, now.Add(totpPeriod)); !ok {
+		t.Error("a code from the previous period was rejected")
+	}
+	if _, ok := ValidateTOTP(secret, code, now.Add(2*totpPeriod)); ok {
+		t.Error("a code from two periods ago was accepted")
+	}
+	for _, bad := range []string{"", "12345", "1234567", "abcdef"} {
+		if _, ok := ValidateTOTP(secret, bad, now); ok {
+			t.Errorf("ValidateTOTP(%q) was accepted", bad)
+		}
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri, err := url.Parse(TOTPURI("Chirpy", "someone@example.com", "JBSWY3DPEHPK3PXP"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uri.Scheme != "otpauth" || uri.Host != "totp" || uri.Path != "/Chirpy:someone@example.com" {
+		t.Errorf("URI = %v", uri)
+	}
+	if q := uri.Query(); q.Get("secret") != "JBSWY3DPEHPK3PXP" || q.Get("issuer") != "Chirpy" {
+		t.Errorf("URI query = %v", q)
+	}
+}
+
+func TestRecoveryCode(t *testing.T) {
+	code, err := MakeRecoveryCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(code) != 19 || strings.Count(code, "-") != 3 {
+		t.Errorf("MakeRecoveryCode() = %q, want four groups of four", code)
+	}
+	other, _ := MakeRecoveryCode()
+	if code == other {
+		t.Error("MakeRecoveryCode() returned the same code twice")
+	}
+	retyped := strings.ToUpper(strings.ReplaceAll(code, "-", " "))
+	if NormalizeRecoveryCode(retyped) != NormalizeRecoveryCode(code) {
+		t.Errorf("NormalizeRecoveryCode(%q) != NormalizeRecoveryCode(%q)", retyped, code)
+	}
+}
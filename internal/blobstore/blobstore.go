@@ -0,0 +1,24 @@
+// Package blobstore stores uploaded files, such as chirp media, by key.
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned when no blob is stored under a key.
+var ErrNotFound = errors.New("blob not found")
+
+// Store is where uploaded bytes live. Keys are opaque to callers of the
+// API; a Store may reject keys it can't represent.
+type Store interface {
+	// Put stores everything read from r under key, replacing any
+	// existing blob.
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Open returns the blob stored under key, or ErrNotFound.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob under key. Deleting a missing blob is
+	// not an error.
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,298 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: chirp_entities.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const addChirpMention = `-- name: AddChirpMention :exec
+INSERT INTO chirp_mentions (chirp_id, user_id, start_offset, end_offset)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+)
+`
+
+type AddChirpMentionParams struct {
+	ChirpID     uuid.UUID
+	UserID      uuid.UUID
+	StartOffset int32
+	EndOffset   int32
+}
+
+func (q *Queries) AddChirpMention(ctx context.Context, arg AddChirpMentionParams) error {
+	_, err := q.db.ExecContext(ctx, addChirpMention,
+		arg.ChirpID,
+		arg.UserID,
+		arg.StartOffset,
+		arg.EndOffset,
+	)
+	return err
+}
+
+const addChirpTag = `-- name: AddChirpTag :exec
+INSERT INTO chirp_tags (chirp_id, tag, start_offset, end_offset)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+)
+`
+
+type AddChirpTagParams struct {
+	ChirpID     uuid.UUID
+	Tag         string
+	StartOffset int32
+	EndOffset   int32
+}
+
+func (q *Queries) AddChirpTag(ctx context.Context, arg AddChirpTagParams) error {
+	_, err := q.db.ExecContext(ctx, addChirpTag,
+		arg.ChirpID,
+		arg.Tag,
+		arg.StartOffset,
+		arg.EndOffset,
+	)
+	return err
+}
+
+const deleteChirpEntities = `-- name: DeleteChirpEntities :exec
+WITH deleted_tags AS (
+    DELETE FROM chirp_tags WHERE chirp_tags.chirp_id = $1
+)
+DELETE FROM chirp_mentions
+WHERE chirp_mentions.chirp_id = $1
+`
+
+// Clears a chirp's tags and mentions so an edited body can be parsed
+// again.
+func (q *Queries) DeleteChirpEntities(ctx context.Context, chirpID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteChirpEntities, chirpID)
+	return err
+}
+
+const getChirpMentions = `-- name: GetChirpMentions :many
+SELECT chirp_id, user_id, start_offset, end_offset
+FROM chirp_mentions
+WHERE chirp_id = ANY($1::uuid[])
+ORDER BY chirp_id, start_offset
+`
+
+func (q *Queries) GetChirpMentions(ctx context.Context, chirpIds []uuid.UUID) ([]ChirpMention, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpMentions, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ChirpMention
+	for rows.Next() {
+		var i ChirpMention
+		if err := rows.Scan(
+			&i.ChirpID,
+			&i.UserID,
+			&i.StartOffset,
+			&i.EndOffset,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpTags = `-- name: GetChirpTags :many
+SELECT chirp_id, tag, start_offset, end_offset
+FROM chirp_tags
+WHERE chirp_id = ANY($1::uuid[])
+ORDER BY chirp_id, start_offset
+`
+
+func (q *Queries) GetChirpTags(ctx context.Context, chirpIds []uuid.UUID) ([]ChirpTag, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpTags, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ChirpTag
+	for rows.Next() {
+		var i ChirpTag
+		if err := rows.Scan(
+			&i.ChirpID,
+			&i.Tag,
+			&i.StartOffset,
+			&i.EndOffset,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpsByTag = `-- name: ListChirpsByTag :many
+SELECT id, created_at, updated_at, body, user_id, search_vector, in_reply_to, tombstoned_at, rechirp_of, quote_of, deleted_at, held_at, hold_reason
+FROM chirps
+WHERE tombstoned_at IS NULL
+AND deleted_at IS NULL
+AND held_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM users AS authors
+    WHERE authors.id = chirps.user_id
+    AND authors.suspension_hides_chirps
+    AND (authors.suspended_until IS NULL OR authors.suspended_until > NOW())
+)
+AND id IN (
+    SELECT chirp_id FROM chirp_tags WHERE tag = $1
+)
+AND (
+    $3::timestamp IS NULL
+    OR (created_at, id) < ($3::timestamp, $4::uuid)
+)
+ORDER BY created_at DESC, id DESC
+LIMIT $2
+`
+
+type ListChirpsByTagParams struct {
+	Tag             string
+	Limit           int32
+	BeforeCreatedAt sql.NullTime
+	BeforeID        uuid.NullUUID
+}
+
+func (q *Queries) ListChirpsByTag(ctx context.Context, arg ListChirpsByTagParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsByTag,
+		arg.Tag,
+		arg.Limit,
+		arg.BeforeCreatedAt,
+		arg.BeforeID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.InReplyTo,
+			&i.TombstonedAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.DeletedAt,
+			&i.HeldAt,
+			&i.HoldReason,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMentions = `-- name: ListMentions :many
+SELECT id, created_at, updated_at, body, user_id, search_vector, in_reply_to, tombstoned_at, rechirp_of, quote_of, deleted_at, held_at, hold_reason
+FROM chirps
+WHERE tombstoned_at IS NULL
+AND deleted_at IS NULL
+AND held_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM users AS authors
+    WHERE authors.id = chirps.user_id
+    AND authors.suspension_hides_chirps
+    AND (authors.suspended_until IS NULL OR authors.suspended_until > NOW())
+)
+AND id IN (
+    SELECT chirp_id FROM chirp_mentions WHERE chirp_mentions.user_id = $1
+)
+AND (
+    $3::timestamp IS NULL
+    OR (created_at, id) < ($3::timestamp, $4::uuid)
+)
+ORDER BY created_at DESC, id DESC
+LIMIT $2
+`
+
+type ListMentionsParams struct {
+	UserID          uuid.UUID
+	Limit           int32
+	BeforeCreatedAt sql.NullTime
+	BeforeID        uuid.NullUUID
+}
+
+// Chirps mentioning the user, newest first.
+func (q *Queries) ListMentions(ctx context.Context, arg ListMentionsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listMentions,
+		arg.UserID,
+		arg.Limit,
+		arg.BeforeCreatedAt,
+		arg.BeforeID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.InReplyTo,
+			&i.TombstonedAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.DeletedAt,
+			&i.HeldAt,
+			&i.HoldReason,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
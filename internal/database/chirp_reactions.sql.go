@@ -0,0 +1,117 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: chirp_reactions.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const addReaction = `-- name: AddReaction :exec
+INSERT INTO chirp_reactions (chirp_id, user_id, emoji, created_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    NOW()
+)
+ON CONFLICT (chirp_id, user_id, emoji) DO NOTHING
+`
+
+type AddReactionParams struct {
+	ChirpID uuid.UUID
+	UserID  uuid.UUID
+	Emoji   string
+}
+
+func (q *Queries) AddReaction(ctx context.Context, arg AddReactionParams) error {
+	_, err := q.db.ExecContext(ctx, addReaction, arg.ChirpID, arg.UserID, arg.Emoji)
+	return err
+}
+
+const deleteReactionsByChirpID = `-- name: DeleteReactionsByChirpID :exec
+DELETE FROM chirp_reactions
+WHERE chirp_id = $1
+`
+
+func (q *Queries) DeleteReactionsByChirpID(ctx context.Context, chirpID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteReactionsByChirpID, chirpID)
+	return err
+}
+
+const getReactionCounts = `-- name: GetReactionCounts :many
+SELECT
+    chirp_id,
+    emoji,
+    COUNT(*)::int AS count,
+    COALESCE(bool_or(user_id = $1::uuid), FALSE)::bool AS reacted
+FROM chirp_reactions
+WHERE chirp_id = ANY($2::uuid[])
+GROUP BY chirp_id, emoji
+ORDER BY chirp_id, count DESC, emoji
+`
+
+type GetReactionCountsParams struct {
+	ViewerID uuid.NullUUID
+	ChirpIds []uuid.UUID
+}
+
+type GetReactionCountsRow struct {
+	ChirpID uuid.UUID
+	Emoji   string
+	Count   int32
+	Reacted bool
+}
+
+// Per-emoji totals for each chirp, and whether viewer_id is among the
+// reactors. viewer_id may be NULL for anonymous callers.
+func (q *Queries) GetReactionCounts(ctx context.Context, arg GetReactionCountsParams) ([]GetReactionCountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getReactionCounts, arg.ViewerID, pq.Array(arg.ChirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetReactionCountsRow
+	for rows.Next() {
+		var i GetReactionCountsRow
+		if err := rows.Scan(
+			&i.ChirpID,
+			&i.Emoji,
+			&i.Count,
+			&i.Reacted,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const removeReaction = `-- name: RemoveReaction :exec
+DELETE FROM chirp_reactions
+WHERE chirp_id = $1
+AND user_id = $2
+AND emoji = $3
+`
+
+type RemoveReactionParams struct {
+	ChirpID uuid.UUID
+	UserID  uuid.UUID
+	Emoji   string
+}
+
+func (q *Queries) RemoveReaction(ctx context.Context, arg RemoveReactionParams) error {
+	_, err := q.db.ExecContext(ctx, removeReaction, arg.ChirpID, arg.UserID, arg.Emoji)
+	return err
+}
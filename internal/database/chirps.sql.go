@@ -7,29 +7,64 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const chirpHasReplies = `-- name: ChirpHasReplies :one
+SELECT EXISTS (
+    SELECT 1 FROM chirps WHERE in_reply_to = $1::uuid
+)
+`
+
+func (q *Queries) ChirpHasReplies(ctx context.Context, id uuid.UUID) (bool, error) {
+	row := q.db.QueryRowContext(ctx, chirpHasReplies, id)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to, rechirp_of, quote_of, held_at, hold_reason)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    $5,
+    CASE WHEN $6::bool THEN NOW() END,
+    $7
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, search_vector, in_reply_to, tombstoned_at, rechirp_of, quote_of, deleted_at, held_at, hold_reason
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body       string
+	UserID     uuid.UUID
+	InReplyTo  uuid.NullUUID
+	RechirpOf  uuid.NullUUID
+	QuoteOf    uuid.NullUUID
+	Held       bool
+	HoldReason string
 }
 
+// A held chirp stays hidden until a moderator reviews it.
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.InReplyTo,
+		arg.RechirpOf,
+		arg.QuoteOf,
+		arg.Held,
+		arg.HoldReason,
+	)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -37,6 +72,14 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.SearchVector,
+		&i.InReplyTo,
+		&i.TombstonedAt,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.DeletedAt,
+		&i.HeldAt,
+		&i.HoldReason,
 	)
 	return i, err
 }
@@ -51,10 +94,107 @@ func (q *Queries) DeleteChirpByID(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const deleteRechirpsOf = `-- name: DeleteRechirpsOf :exec
+DELETE FROM chirps
+WHERE rechirp_of = $1::uuid
+`
+
+func (q *Queries) DeleteRechirpsOf(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteRechirpsOf, id)
+	return err
+}
+
+const detachQuotesOf = `-- name: DetachQuotesOf :exec
+UPDATE chirps
+SET quote_of = NULL
+WHERE quote_of = $1::uuid
+`
+
+func (q *Queries) DetachQuotesOf(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, detachQuotesOf, id)
+	return err
+}
+
+const getChirpAncestors = `-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors AS (
+    SELECT parent.id, parent.in_reply_to, 1 AS depth
+    FROM chirps AS child
+    JOIN chirps AS parent ON parent.id = child.in_reply_to
+    WHERE child.id = $1
+    UNION ALL
+    SELECT parent.id, parent.in_reply_to, ancestors.depth + 1
+    FROM ancestors
+    JOIN chirps AS parent ON parent.id = ancestors.in_reply_to
+)
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.search_vector, chirps.in_reply_to, chirps.tombstoned_at, chirps.rechirp_of, chirps.quote_of, chirps.deleted_at, chirps.held_at, chirps.hold_reason,
+    (
+        authors.suspension_hides_chirps
+        AND (authors.suspended_until IS NULL OR authors.suspended_until > NOW())
+    )::bool AS author_hidden
+FROM ancestors
+JOIN chirps ON chirps.id = ancestors.id
+JOIN users AS authors ON authors.id = chirps.user_id
+ORDER BY ancestors.depth DESC
+`
+
+type GetChirpAncestorsRow struct {
+	Chirp        Chirp
+	AuthorHidden bool
+}
+
+// The reply chain above a chirp, root first, tombstones and trashed
+// chirps included, with author_hidden as in GetThreadChirpByID.
+func (q *Queries) GetChirpAncestors(ctx context.Context, id uuid.UUID) ([]GetChirpAncestorsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpAncestors, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpAncestorsRow
+	for rows.Next() {
+		var i GetChirpAncestorsRow
+		if err := rows.Scan(
+			&i.Chirp.ID,
+			&i.Chirp.CreatedAt,
+			&i.Chirp.UpdatedAt,
+			&i.Chirp.Body,
+			&i.Chirp.UserID,
+			&i.Chirp.SearchVector,
+			&i.Chirp.InReplyTo,
+			&i.Chirp.TombstonedAt,
+			&i.Chirp.RechirpOf,
+			&i.Chirp.QuoteOf,
+			&i.Chirp.DeletedAt,
+			&i.Chirp.HeldAt,
+			&i.Chirp.HoldReason,
+			&i.AuthorHidden,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getChirpByID = `-- name: GetChirpByID :one
-SELECT id, created_at, updated_at, body, user_id
+SELECT id, created_at, updated_at, body, user_id, search_vector, in_reply_to, tombstoned_at, rechirp_of, quote_of, deleted_at, held_at, hold_reason
 FROM chirps
-WHERE id = $1
+WHERE chirps.id = $1
+AND tombstoned_at IS NULL
+AND deleted_at IS NULL
+AND held_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM users AS authors
+    WHERE authors.id = chirps.user_id
+    AND authors.suspension_hides_chirps
+    AND (authors.suspended_until IS NULL OR authors.suspended_until > NOW())
+)
 `
 
 func (q *Queries) GetChirpByID(ctx context.Context, id uuid.UUID) (Chirp, error) {
@@ -66,13 +206,107 @@ func (q *Queries) GetChirpByID(ctx context.Context, id uuid.UUID) (Chirp, error)
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.SearchVector,
+		&i.InReplyTo,
+		&i.TombstonedAt,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.DeletedAt,
+		&i.HeldAt,
+		&i.HoldReason,
 	)
 	return i, err
 }
 
+const getChirpDescendants = `-- name: GetChirpDescendants :many
+WITH RECURSIVE descendants AS (
+    SELECT chirps.id, 1 AS depth
+    FROM chirps
+    WHERE chirps.in_reply_to = $2::uuid
+    UNION ALL
+    SELECT chirps.id, descendants.depth + 1
+    FROM descendants
+    JOIN chirps ON chirps.in_reply_to = descendants.id
+    WHERE descendants.depth < $3::int
+)
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.search_vector, chirps.in_reply_to, chirps.tombstoned_at, chirps.rechirp_of, chirps.quote_of, chirps.deleted_at, chirps.held_at, chirps.hold_reason, descendants.depth::int AS depth,
+    (
+        authors.suspension_hides_chirps
+        AND (authors.suspended_until IS NULL OR authors.suspended_until > NOW())
+    )::bool AS author_hidden
+FROM descendants
+JOIN chirps ON chirps.id = descendants.id
+JOIN users AS authors ON authors.id = chirps.user_id
+ORDER BY descendants.depth, chirps.created_at, chirps.id
+LIMIT $1
+`
+
+type GetChirpDescendantsParams struct {
+	RowLimit int32
+	ID       uuid.UUID
+	MaxDepth int32
+}
+
+type GetChirpDescendantsRow struct {
+	Chirp        Chirp
+	Depth        int32
+	AuthorHidden bool
+}
+
+// Replies below a chirp, breadth first, at most max_depth levels deep
+// and row_limit rows in total. Tombstones and trashed chirps are
+// included, with author_hidden as in GetThreadChirpByID.
+func (q *Queries) GetChirpDescendants(ctx context.Context, arg GetChirpDescendantsParams) ([]GetChirpDescendantsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpDescendants, arg.RowLimit, arg.ID, arg.MaxDepth)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpDescendantsRow
+	for rows.Next() {
+		var i GetChirpDescendantsRow
+		if err := rows.Scan(
+			&i.Chirp.ID,
+			&i.Chirp.CreatedAt,
+			&i.Chirp.UpdatedAt,
+			&i.Chirp.Body,
+			&i.Chirp.UserID,
+			&i.Chirp.SearchVector,
+			&i.Chirp.InReplyTo,
+			&i.Chirp.TombstonedAt,
+			&i.Chirp.RechirpOf,
+			&i.Chirp.QuoteOf,
+			&i.Chirp.DeletedAt,
+			&i.Chirp.HeldAt,
+			&i.Chirp.HoldReason,
+			&i.Depth,
+			&i.AuthorHidden,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getChirps = `-- name: GetChirps :many
-SELECT id, created_at, updated_at, body, user_id 
+SELECT id, created_at, updated_at, body, user_id, search_vector, in_reply_to, tombstoned_at, rechirp_of, quote_of, deleted_at, held_at, hold_reason 
 FROM chirps
+WHERE tombstoned_at IS NULL
+AND deleted_at IS NULL
+AND held_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM users AS authors
+    WHERE authors.id = chirps.user_id
+    AND authors.suspension_hides_chirps
+    AND (authors.suspended_until IS NULL OR authors.suspended_until > NOW())
+)
 ORDER BY created_at ASC
 `
 
@@ -91,6 +325,66 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.SearchVector,
+			&i.InReplyTo,
+			&i.TombstonedAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.DeletedAt,
+			&i.HeldAt,
+			&i.HoldReason,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsByIDs = `-- name: GetChirpsByIDs :many
+SELECT id, created_at, updated_at, body, user_id, search_vector, in_reply_to, tombstoned_at, rechirp_of, quote_of, deleted_at, held_at, hold_reason
+FROM chirps
+WHERE id = ANY($1::uuid[])
+AND tombstoned_at IS NULL
+AND deleted_at IS NULL
+AND held_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM users AS authors
+    WHERE authors.id = chirps.user_id
+    AND authors.suspension_hides_chirps
+    AND (authors.suspended_until IS NULL OR authors.suspended_until > NOW())
+)
+`
+
+func (q *Queries) GetChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.InReplyTo,
+			&i.TombstonedAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.DeletedAt,
+			&i.HeldAt,
+			&i.HoldReason,
 		); err != nil {
 			return nil, err
 		}
@@ -106,9 +400,18 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 }
 
 const getChirpsByUserID = `-- name: GetChirpsByUserID :many
-SELECT id, created_at, updated_at, body, user_id
+SELECT id, created_at, updated_at, body, user_id, search_vector, in_reply_to, tombstoned_at, rechirp_of, quote_of, deleted_at, held_at, hold_reason
 FROM chirps
 WHERE user_id = $1
+AND tombstoned_at IS NULL
+AND deleted_at IS NULL
+AND held_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM users AS authors
+    WHERE authors.id = chirps.user_id
+    AND authors.suspension_hides_chirps
+    AND (authors.suspended_until IS NULL OR authors.suspended_until > NOW())
+)
 ORDER BY created_at ASC
 `
 
@@ -127,6 +430,14 @@ func (q *Queries) GetChirpsByUserID(ctx context.Context, userID uuid.UUID) ([]Ch
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.SearchVector,
+			&i.InReplyTo,
+			&i.TombstonedAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.DeletedAt,
+			&i.HeldAt,
+			&i.HoldReason,
 		); err != nil {
 			return nil, err
 		}
@@ -140,3 +451,612 @@ func (q *Queries) GetChirpsByUserID(ctx context.Context, userID uuid.UUID) ([]Ch
 	}
 	return items, nil
 }
+
+const getRechirp = `-- name: GetRechirp :one
+SELECT id, created_at, updated_at, body, user_id, search_vector, in_reply_to, tombstoned_at, rechirp_of, quote_of, deleted_at, held_at, hold_reason
+FROM chirps
+WHERE user_id = $1
+AND rechirp_of = $2::uuid
+`
+
+type GetRechirpParams struct {
+	UserID    uuid.UUID
+	RechirpOf uuid.UUID
+}
+
+func (q *Queries) GetRechirp(ctx context.Context, arg GetRechirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getRechirp, arg.UserID, arg.RechirpOf)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.SearchVector,
+		&i.InReplyTo,
+		&i.TombstonedAt,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.DeletedAt,
+		&i.HeldAt,
+		&i.HoldReason,
+	)
+	return i, err
+}
+
+const getShareCounts = `-- name: GetShareCounts :many
+SELECT
+    chirps.id AS chirp_id,
+    (COUNT(shares.id) FILTER (WHERE shares.rechirp_of = chirps.id))::int AS rechirps,
+    (COUNT(shares.id) FILTER (WHERE shares.quote_of = chirps.id))::int AS quotes
+FROM chirps
+JOIN chirps AS shares ON shares.rechirp_of = chirps.id OR shares.quote_of = chirps.id
+WHERE chirps.id = ANY($1::uuid[])
+AND shares.tombstoned_at IS NULL
+AND shares.deleted_at IS NULL
+AND shares.held_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM users AS authors
+    WHERE authors.id = shares.user_id
+    AND authors.suspension_hides_chirps
+    AND (authors.suspended_until IS NULL OR authors.suspended_until > NOW())
+)
+GROUP BY chirps.id
+`
+
+type GetShareCountsRow struct {
+	ChirpID  uuid.UUID
+	Rechirps int32
+	Quotes   int32
+}
+
+// How many times each chirp has been rechirped and quoted. Chirps that
+// were never shared are left out.
+func (q *Queries) GetShareCounts(ctx context.Context, chirpIds []uuid.UUID) ([]GetShareCountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getShareCounts, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetShareCountsRow
+	for rows.Next() {
+		var i GetShareCountsRow
+		if err := rows.Scan(&i.ChirpID, &i.Rechirps, &i.Quotes); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getThreadChirpByID = `-- name: GetThreadChirpByID :one
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.search_vector, chirps.in_reply_to, chirps.tombstoned_at, chirps.rechirp_of, chirps.quote_of, chirps.deleted_at, chirps.held_at, chirps.hold_reason,
+    (
+        authors.suspension_hides_chirps
+        AND (authors.suspended_until IS NULL OR authors.suspended_until > NOW())
+    )::bool AS author_hidden
+FROM chirps
+JOIN users AS authors ON authors.id = chirps.user_id
+WHERE chirps.id = $1
+`
+
+type GetThreadChirpByIDRow struct {
+	Chirp        Chirp
+	AuthorHidden bool
+}
+
+// Like GetChirpByID, but tombstones and trashed chirps are returned too.
+// author_hidden is set while the author serves a suspension that hides
+// their chirps.
+func (q *Queries) GetThreadChirpByID(ctx context.Context, id uuid.UUID) (GetThreadChirpByIDRow, error) {
+	row := q.db.QueryRowContext(ctx, getThreadChirpByID, id)
+	var i GetThreadChirpByIDRow
+	err := row.Scan(
+		&i.Chirp.ID,
+		&i.Chirp.CreatedAt,
+		&i.Chirp.UpdatedAt,
+		&i.Chirp.Body,
+		&i.Chirp.UserID,
+		&i.Chirp.SearchVector,
+		&i.Chirp.InReplyTo,
+		&i.Chirp.TombstonedAt,
+		&i.Chirp.RechirpOf,
+		&i.Chirp.QuoteOf,
+		&i.Chirp.DeletedAt,
+		&i.Chirp.HeldAt,
+		&i.Chirp.HoldReason,
+		&i.AuthorHidden,
+	)
+	return i, err
+}
+
+const getTrashedChirp = `-- name: GetTrashedChirp :one
+SELECT id, created_at, updated_at, body, user_id, search_vector, in_reply_to, tombstoned_at, rechirp_of, quote_of, deleted_at, held_at, hold_reason
+FROM chirps
+WHERE id = $1
+AND deleted_at IS NOT NULL
+AND tombstoned_at IS NULL
+`
+
+func (q *Queries) GetTrashedChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getTrashedChirp, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.SearchVector,
+		&i.InReplyTo,
+		&i.TombstonedAt,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.DeletedAt,
+		&i.HeldAt,
+		&i.HoldReason,
+	)
+	return i, err
+}
+
+const listChirpsAsc = `-- name: ListChirpsAsc :many
+SELECT id, created_at, updated_at, body, user_id, search_vector, in_reply_to, tombstoned_at, rechirp_of, quote_of, deleted_at, held_at, hold_reason
+FROM chirps
+WHERE tombstoned_at IS NULL
+AND deleted_at IS NULL
+AND held_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM users AS authors
+    WHERE authors.id = chirps.user_id
+    AND authors.suspension_hides_chirps
+    AND (authors.suspended_until IS NULL OR authors.suspended_until > NOW())
+)
+AND ($2::uuid IS NULL OR user_id = $2::uuid)
+AND (
+    $3::timestamp IS NULL
+    OR (created_at, id) > ($3::timestamp, $4::uuid)
+)
+ORDER BY created_at ASC, id ASC
+LIMIT $1
+`
+
+type ListChirpsAscParams struct {
+	Limit          int32
+	UserID         uuid.NullUUID
+	AfterCreatedAt sql.NullTime
+	AfterID        uuid.NullUUID
+}
+
+func (q *Queries) ListChirpsAsc(ctx context.Context, arg ListChirpsAscParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsAsc,
+		arg.Limit,
+		arg.UserID,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.InReplyTo,
+			&i.TombstonedAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.DeletedAt,
+			&i.HeldAt,
+			&i.HoldReason,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpsDesc = `-- name: ListChirpsDesc :many
+SELECT id, created_at, updated_at, body, user_id, search_vector, in_reply_to, tombstoned_at, rechirp_of, quote_of, deleted_at, held_at, hold_reason
+FROM chirps
+WHERE tombstoned_at IS NULL
+AND deleted_at IS NULL
+AND held_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM users AS authors
+    WHERE authors.id = chirps.user_id
+    AND authors.suspension_hides_chirps
+    AND (authors.suspended_until IS NULL OR authors.suspended_until > NOW())
+)
+AND ($2::uuid IS NULL OR user_id = $2::uuid)
+AND (
+    $3::timestamp IS NULL
+    OR (created_at, id) < ($3::timestamp, $4::uuid)
+)
+ORDER BY created_at DESC, id DESC
+LIMIT $1
+`
+
+type ListChirpsDescParams struct {
+	Limit           int32
+	UserID          uuid.NullUUID
+	BeforeCreatedAt sql.NullTime
+	BeforeID        uuid.NullUUID
+}
+
+func (q *Queries) ListChirpsDesc(ctx context.Context, arg ListChirpsDescParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsDesc,
+		arg.Limit,
+		arg.UserID,
+		arg.BeforeCreatedAt,
+		arg.BeforeID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.InReplyTo,
+			&i.TombstonedAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.DeletedAt,
+			&i.HeldAt,
+			&i.HoldReason,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listExpiredTrash = `-- name: ListExpiredTrash :many
+SELECT id
+FROM chirps
+WHERE deleted_at <= $2::timestamp
+AND tombstoned_at IS NULL
+ORDER BY deleted_at, id
+LIMIT $1
+`
+
+type ListExpiredTrashParams struct {
+	Limit  int32
+	Cutoff time.Time
+}
+
+// Trashed chirps deleted at or before cutoff, oldest first.
+func (q *Queries) ListExpiredTrash(ctx context.Context, arg ListExpiredTrashParams) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, listExpiredTrash, arg.Limit, arg.Cutoff)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTrash = `-- name: ListTrash :many
+SELECT id, created_at, updated_at, body, user_id, search_vector, in_reply_to, tombstoned_at, rechirp_of, quote_of, deleted_at, held_at, hold_reason
+FROM chirps
+WHERE user_id = $1
+AND rechirp_of IS NULL
+AND tombstoned_at IS NULL
+AND deleted_at > $3::timestamp
+AND (
+    $4::timestamp IS NULL
+    OR (deleted_at, id) < ($4::timestamp, $5::uuid)
+)
+ORDER BY deleted_at DESC, id DESC
+LIMIT $2
+`
+
+type ListTrashParams struct {
+	UserID          uuid.UUID
+	Limit           int32
+	DeletedAfter    time.Time
+	BeforeDeletedAt sql.NullTime
+	BeforeID        uuid.NullUUID
+}
+
+// A user's trashed chirps that are not yet due for purging, most
+// recently deleted first.
+func (q *Queries) ListTrash(ctx context.Context, arg ListTrashParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listTrash,
+		arg.UserID,
+		arg.Limit,
+		arg.DeletedAfter,
+		arg.BeforeDeletedAt,
+		arg.BeforeID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.InReplyTo,
+			&i.TombstonedAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.DeletedAt,
+			&i.HeldAt,
+			&i.HoldReason,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const releaseChirpHold = `-- name: ReleaseChirpHold :exec
+UPDATE chirps
+SET held_at = NULL, hold_reason = ''
+WHERE id = $1
+AND held_at IS NOT NULL
+`
+
+func (q *Queries) ReleaseChirpHold(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, releaseChirpHold, id)
+	return err
+}
+
+const restoreChirp = `-- name: RestoreChirp :exec
+UPDATE chirps
+SET deleted_at = NULL
+WHERE (id = $1::uuid OR rechirp_of = $1::uuid)
+AND deleted_at = (
+    SELECT trashed.deleted_at FROM chirps AS trashed WHERE trashed.id = $1::uuid
+)
+`
+
+// Takes a chirp, and the rechirps trashed along with it, out of the
+// trash.
+func (q *Queries) RestoreChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, restoreChirp, id)
+	return err
+}
+
+const searchChirps = `-- name: SearchChirps :many
+SELECT
+    chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.search_vector, chirps.in_reply_to, chirps.tombstoned_at, chirps.rechirp_of, chirps.quote_of, chirps.deleted_at, chirps.held_at, chirps.hold_reason,
+    ts_rank(chirps.search_vector, websearch_to_tsquery('english', $2))::real AS rank,
+    ts_headline(
+        'english',
+        replace(replace(replace(chirps.body, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+        websearch_to_tsquery('english', $2),
+        'StartSel=<mark>, StopSel=</mark>, HighlightAll=true'
+    )::text AS highlight
+FROM chirps
+WHERE chirps.tombstoned_at IS NULL
+AND chirps.deleted_at IS NULL
+AND chirps.held_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM users AS authors
+    WHERE authors.id = chirps.user_id
+    AND authors.suspension_hides_chirps
+    AND (authors.suspended_until IS NULL OR authors.suspended_until > NOW())
+)
+AND chirps.search_vector @@ websearch_to_tsquery('english', $2)
+AND ($3::uuid IS NULL OR chirps.user_id = $3::uuid)
+AND ($4::timestamp IS NULL OR chirps.created_at >= $4::timestamp)
+AND ($5::timestamp IS NULL OR chirps.created_at < $5::timestamp)
+AND (
+    $6::real IS NULL
+    OR (
+        ts_rank(chirps.search_vector, websearch_to_tsquery('english', $2))::real,
+        chirps.created_at,
+        chirps.id
+    ) < ($6::real, $7::timestamp, $8::uuid)
+)
+ORDER BY rank DESC, chirps.created_at DESC, chirps.id DESC
+LIMIT $1
+`
+
+type SearchChirpsParams struct {
+	Limit          int32
+	Query          string
+	UserID         uuid.NullUUID
+	Since          sql.NullTime
+	Until          sql.NullTime
+	AfterRank      sql.NullFloat64
+	AfterCreatedAt sql.NullTime
+	AfterID        uuid.NullUUID
+}
+
+type SearchChirpsRow struct {
+	Chirp     Chirp
+	Rank      float32
+	Highlight string
+}
+
+// Ranked full-text search, best match first. The body is HTML escaped
+// before ts_headline so the highlight is safe to render as markup.
+func (q *Queries) SearchChirps(ctx context.Context, arg SearchChirpsParams) ([]SearchChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchChirps,
+		arg.Limit,
+		arg.Query,
+		arg.UserID,
+		arg.Since,
+		arg.Until,
+		arg.AfterRank,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchChirpsRow
+	for rows.Next() {
+		var i SearchChirpsRow
+		if err := rows.Scan(
+			&i.Chirp.ID,
+			&i.Chirp.CreatedAt,
+			&i.Chirp.UpdatedAt,
+			&i.Chirp.Body,
+			&i.Chirp.UserID,
+			&i.Chirp.SearchVector,
+			&i.Chirp.InReplyTo,
+			&i.Chirp.TombstonedAt,
+			&i.Chirp.RechirpOf,
+			&i.Chirp.QuoteOf,
+			&i.Chirp.DeletedAt,
+			&i.Chirp.HeldAt,
+			&i.Chirp.HoldReason,
+			&i.Rank,
+			&i.Highlight,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const softDeleteChirp = `-- name: SoftDeleteChirp :exec
+UPDATE chirps
+SET deleted_at = NOW()
+WHERE (id = $1::uuid OR rechirp_of = $1::uuid)
+AND deleted_at IS NULL
+AND held_at IS NULL
+`
+
+// Moves a chirp to its author's trash. Its rechirps are hidden with it,
+// sharing its deleted_at so they come back when it is restored.
+func (q *Queries) SoftDeleteChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, softDeleteChirp, id)
+	return err
+}
+
+const tombstoneChirp = `-- name: TombstoneChirp :exec
+UPDATE chirps
+SET body = '', tombstoned_at = NOW(), updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) TombstoneChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, tombstoneChirp, id)
+	return err
+}
+
+const updateChirpBody = `-- name: UpdateChirpBody :one
+UPDATE chirps
+SET body = $1, updated_at = NOW(),
+    held_at = CASE WHEN $2::bool THEN NOW() ELSE held_at END,
+    hold_reason = CASE WHEN $2::bool THEN $3 ELSE hold_reason END
+WHERE chirps.id = $4
+AND tombstoned_at IS NULL
+AND deleted_at IS NULL
+AND held_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM users AS authors
+    WHERE authors.id = chirps.user_id
+    AND authors.suspension_hides_chirps
+    AND (authors.suspended_until IS NULL OR authors.suspended_until > NOW())
+)
+RETURNING id, created_at, updated_at, body, user_id, search_vector, in_reply_to, tombstoned_at, rechirp_of, quote_of, deleted_at, held_at, hold_reason
+`
+
+type UpdateChirpBodyParams struct {
+	Body       string
+	Held       bool
+	HoldReason string
+	ID         uuid.UUID
+}
+
+// An edit can put a chirp on hold, but never releases one.
+func (q *Queries) UpdateChirpBody(ctx context.Context, arg UpdateChirpBodyParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, updateChirpBody,
+		arg.Body,
+		arg.Held,
+		arg.HoldReason,
+		arg.ID,
+	)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.SearchVector,
+		&i.InReplyTo,
+		&i.TombstonedAt,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.DeletedAt,
+		&i.HeldAt,
+		&i.HoldReason,
+	)
+	return i, err
+}
@@ -0,0 +1,219 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: follows.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+const followUser = `-- name: FollowUser :exec
+INSERT INTO follows (follower_id, followee_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT (follower_id, followee_id) DO NOTHING
+`
+
+type FollowUserParams struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+}
+
+func (q *Queries) FollowUser(ctx context.Context, arg FollowUserParams) error {
+	_, err := q.db.ExecContext(ctx, followUser, arg.FollowerID, arg.FolloweeID)
+	return err
+}
+
+const getTimeline = `-- name: GetTimeline :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.search_vector, chirps.in_reply_to, chirps.tombstoned_at, chirps.rechirp_of, chirps.quote_of, chirps.deleted_at, chirps.held_at, chirps.hold_reason
+FROM chirps
+WHERE chirps.tombstoned_at IS NULL
+AND chirps.deleted_at IS NULL
+AND chirps.held_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM users AS authors
+    WHERE authors.id = chirps.user_id
+    AND authors.suspension_hides_chirps
+    AND (authors.suspended_until IS NULL OR authors.suspended_until > NOW())
+)
+AND (
+    chirps.user_id = $1
+    OR chirps.user_id IN (
+        SELECT followee_id FROM follows WHERE follower_id = $1
+    )
+)
+AND (
+    $3::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) < ($3::timestamp, $4::uuid)
+)
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT $2
+`
+
+type GetTimelineParams struct {
+	UserID          uuid.UUID
+	Limit           int32
+	BeforeCreatedAt sql.NullTime
+	BeforeID        uuid.NullUUID
+}
+
+// Chirps by the user and everyone they follow, newest first.
+func (q *Queries) GetTimeline(ctx context.Context, arg GetTimelineParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getTimeline,
+		arg.UserID,
+		arg.Limit,
+		arg.BeforeCreatedAt,
+		arg.BeforeID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.InReplyTo,
+			&i.TombstonedAt,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.DeletedAt,
+			&i.HeldAt,
+			&i.HoldReason,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFollowers = `-- name: ListFollowers :many
+SELECT follower_id, followee_id, created_at
+FROM follows
+WHERE followee_id = $1
+AND (
+    $3::timestamp IS NULL
+    OR (created_at, follower_id) < ($3::timestamp, $4::uuid)
+)
+ORDER BY created_at DESC, follower_id DESC
+LIMIT $2
+`
+
+type ListFollowersParams struct {
+	FolloweeID      uuid.UUID
+	Limit           int32
+	BeforeCreatedAt sql.NullTime
+	BeforeID        uuid.NullUUID
+}
+
+func (q *Queries) ListFollowers(ctx context.Context, arg ListFollowersParams) ([]Follow, error) {
+	rows, err := q.db.QueryContext(ctx, listFollowers,
+		arg.FolloweeID,
+		arg.Limit,
+		arg.BeforeCreatedAt,
+		arg.BeforeID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Follow
+	for rows.Next() {
+		var i Follow
+		if err := rows.Scan(&i.FollowerID, &i.FolloweeID, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFollowing = `-- name: ListFollowing :many
+SELECT follower_id, followee_id, created_at
+FROM follows
+WHERE follower_id = $1
+AND (
+    $3::timestamp IS NULL
+    OR (created_at, followee_id) < ($3::timestamp, $4::uuid)
+)
+ORDER BY created_at DESC, followee_id DESC
+LIMIT $2
+`
+
+type ListFollowingParams struct {
+	FollowerID      uuid.UUID
+	Limit           int32
+	BeforeCreatedAt sql.NullTime
+	BeforeID        uuid.NullUUID
+}
+
+func (q *Queries) ListFollowing(ctx context.Context, arg ListFollowingParams) ([]Follow, error) {
+	rows, err := q.db.QueryContext(ctx, listFollowing,
+		arg.FollowerID,
+		arg.Limit,
+		arg.BeforeCreatedAt,
+		arg.BeforeID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Follow
+	for rows.Next() {
+		var i Follow
+		if err := rows.Scan(&i.FollowerID, &i.FolloweeID, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const unfollowUser = `-- name: UnfollowUser :exec
+DELETE FROM follows
+WHERE follower_id = $1
+AND followee_id = $2
+`
+
+type UnfollowUserParams struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+}
+
+func (q *Queries) UnfollowUser(ctx context.Context, arg UnfollowUserParams) error {
+	_, err := q.db.ExecContext(ctx, unfollowUser, arg.FollowerID, arg.FolloweeID)
+	return err
+}
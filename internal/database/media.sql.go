@@ -0,0 +1,154 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: media.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const attachChirpMedia = `-- name: AttachChirpMedia :exec
+INSERT INTO chirp_media (chirp_id, media_id, position, alt_text)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+)
+`
+
+type AttachChirpMediaParams struct {
+	ChirpID  uuid.UUID
+	MediaID  uuid.UUID
+	Position int32
+	AltText  string
+}
+
+func (q *Queries) AttachChirpMedia(ctx context.Context, arg AttachChirpMediaParams) error {
+	_, err := q.db.ExecContext(ctx, attachChirpMedia,
+		arg.ChirpID,
+		arg.MediaID,
+		arg.Position,
+		arg.AltText,
+	)
+	return err
+}
+
+const createMedia = `-- name: CreateMedia :one
+INSERT INTO media (id, created_at, user_id, content_type, size_bytes)
+VALUES (
+    $1,
+    NOW(),
+    $2,
+    $3,
+    $4
+)
+RETURNING id, created_at, user_id, content_type, size_bytes
+`
+
+type CreateMediaParams struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	ContentType string
+	SizeBytes   int64
+}
+
+func (q *Queries) CreateMedia(ctx context.Context, arg CreateMediaParams) (Media, error) {
+	row := q.db.QueryRowContext(ctx, createMedia,
+		arg.ID,
+		arg.UserID,
+		arg.ContentType,
+		arg.SizeBytes,
+	)
+	var i Media
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UserID,
+		&i.ContentType,
+		&i.SizeBytes,
+	)
+	return i, err
+}
+
+const detachChirpMedia = `-- name: DetachChirpMedia :exec
+DELETE FROM chirp_media
+WHERE chirp_id = $1
+`
+
+// Removes a chirp's attachments, leaving the uploads themselves.
+func (q *Queries) DetachChirpMedia(ctx context.Context, chirpID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, detachChirpMedia, chirpID)
+	return err
+}
+
+const getChirpMedia = `-- name: GetChirpMedia :many
+SELECT chirp_media.chirp_id, chirp_media.position, chirp_media.alt_text, media.id, media.created_at, media.user_id, media.content_type, media.size_bytes
+FROM chirp_media
+JOIN media ON media.id = chirp_media.media_id
+WHERE chirp_media.chirp_id = ANY($1::uuid[])
+ORDER BY chirp_media.chirp_id, chirp_media.position
+`
+
+type GetChirpMediaRow struct {
+	ChirpID  uuid.UUID
+	Position int32
+	AltText  string
+	Media    Media
+}
+
+func (q *Queries) GetChirpMedia(ctx context.Context, chirpIds []uuid.UUID) ([]GetChirpMediaRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpMedia, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpMediaRow
+	for rows.Next() {
+		var i GetChirpMediaRow
+		if err := rows.Scan(
+			&i.ChirpID,
+			&i.Position,
+			&i.AltText,
+			&i.Media.ID,
+			&i.Media.CreatedAt,
+			&i.Media.UserID,
+			&i.Media.ContentType,
+			&i.Media.SizeBytes,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMediaByID = `-- name: GetMediaByID :one
+SELECT id, created_at, user_id, content_type, size_bytes
+FROM media
+WHERE id = $1
+`
+
+func (q *Queries) GetMediaByID(ctx context.Context, id uuid.UUID) (Media, error) {
+	row := q.db.QueryRowContext(ctx, getMediaByID, id)
+	var i Media
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UserID,
+		&i.ContentType,
+		&i.SizeBytes,
+	)
+	return i, err
+}
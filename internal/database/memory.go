@@ -0,0 +1,151 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// errDuplicateEmail mirrors the unique constraint on users.email.
+var errDuplicateEmail = errors.New("duplicate key value violates unique constraint \"users_email_key\"")
+
+// errDuplicateHandle mirrors the unique constraint on users.handle.
+var errDuplicateHandle = errors.New("duplicate key value violates unique constraint \"users_handle_key\"")
+
+// MemoryStore is a thread-safe, in-memory Store. Lookups that find
+// nothing return sql.ErrNoRows, the same as the sqlc queries do, and
+// deleting a user or chirp cascades to everything that references it.
+type MemoryStore struct {
+	mu sync.RWMutex
+	// txMu serializes InTx calls
+	txMu sync.Mutex
+	memoryTables
+}
+
+// memoryTables holds the rows of a MemoryStore.
+type memoryTables struct {
+	users              []User
+	chirps             []Chirp
+	refreshTokens      []RefreshToken
+	follows            []Follow
+	reactions          []ChirpReaction
+	tags               []ChirpTag
+	mentions           []ChirpMention
+	media              []Media
+	chirpMedia         []ChirpMedia
+	revisions          []ChirpRevision
+	rules              []ModerationRule
+	reports            []Report
+	resolutions        []ReportResolution
+	passwordResets     []PasswordResetToken
+	emailVerifications []EmailVerificationToken
+	recoveryCodes      []MfaRecoveryCode
+	mfaChallenges      []MfaChallenge
+}
+
+// clone copies every table, so the copy is unaffected by later writes.
+func (t memoryTables) clone() memoryTables {
+	return memoryTables{
+		users:              slices.Clone(t.users),
+		chirps:             slices.Clone(t.chirps),
+		refreshTokens:      slices.Clone(t.refreshTokens),
+		follows:            slices.Clone(t.follows),
+		reactions:          slices.Clone(t.reactions),
+		tags:               slices.Clone(t.tags),
+		mentions:           slices.Clone(t.mentions),
+		media:              slices.Clone(t.media),
+		chirpMedia:         slices.Clone(t.chirpMedia),
+		revisions:          slices.Clone(t.revisions),
+		rules:              slices.Clone(t.rules),
+		reports:            slices.Clone(t.reports),
+		resolutions:        slices.Clone(t.resolutions),
+		passwordResets:     slices.Clone(t.passwordResets),
+		emailVerifications: slices.Clone(t.emailVerifications),
+		recoveryCodes:      slices.Clone(t.recoveryCodes),
+		mfaChallenges:      slices.Clone(t.mfaChallenges),
+	}
+}
+
+// InTx runs fn against the store and, if it fails, puts every table
+// back the way it was, as rolling back a transaction would. Unlike a
+// transaction it isn't isolated: other callers see fn's writes as they
+// happen, and writes they make meanwhile are undone with fn's.
+func (m *MemoryStore) InTx(ctx context.Context, fn func(Store) error) error {
+	m.txMu.Lock()
+	defer m.txMu.Unlock()
+	m.mu.RLock()
+	saved := m.memoryTables.clone()
+	m.mu.RUnlock()
+	err := fn(memoryTx{m})
+	if err != nil {
+		m.mu.Lock()
+		m.memoryTables = saved
+		m.mu.Unlock()
+	}
+	return err
+}
+
+// memoryTx is the store handed to an InTx callback. Nested InTx calls
+// join the outer one, as they do for Queries.
+type memoryTx struct {
+	*MemoryStore
+}
+
+func (t memoryTx) InTx(ctx context.Context, fn func(Store) error) error {
+	return fn(t)
+}
+
+// NewMemoryStore returns an empty store holding only the moderation
+// rule seeded by the migrations.
+func NewMemoryStore() *MemoryStore {
+	t := now()
+	return &MemoryStore{memoryTables: memoryTables{
+		rules: []ModerationRule{{
+			ID:        uuid.New(),
+			CreatedAt: t,
+			UpdatedAt: t,
+			Kind:      "word",
+			Patterns:  []string{"kerfuffle", "sharbert", "fornax"},
+			Action:    "redact",
+			Enabled:   true,
+		}},
+	}}
+}
+
+func now() time.Time {
+	return time.Now().UTC()
+}
+
+func (m *MemoryStore) userIndex(id uuid.UUID) int {
+	return slices.IndexFunc(m.users, func(u User) bool { return u.ID == id })
+}
+
+func (m *MemoryStore) chirpIndex(id uuid.UUID) int {
+	return slices.IndexFunc(m.chirps, func(c Chirp) bool { return c.ID == id })
+}
+
+func (m *MemoryStore) tokenIndex(tokenHash string) int {
+	return slices.IndexFunc(m.refreshTokens, func(t RefreshToken) bool { return t.TokenHash == tokenHash })
+}
+
+// limitRows applies a SQL LIMIT to an already ordered result.
+func limitRows[T any](items []T, limit int32) []T {
+	if limit >= 0 && len(items) > int(limit) {
+		return items[:limit]
+	}
+	return items
+}
+
+// compareKeyset orders rows by (created_at, id), the keyset used
+// for pagination. UUIDs compare bytewise, as they do in Postgres.
+func compareKeyset(aCreatedAt time.Time, aID uuid.UUID, bCreatedAt time.Time, bID uuid.UUID) int {
+	if c := aCreatedAt.Compare(bCreatedAt); c != 0 {
+		return c
+	}
+	return bytes.Compare(aID[:], bID[:])
+}
@@ -0,0 +1,123 @@
+package database
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func (m *MemoryStore) AddChirpTag(ctx context.Context, arg AddChirpTagParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.chirpIndex(arg.ChirpID) < 0 {
+		return errors.New("insert on table \"chirp_tags\" violates foreign key constraint \"fk_chirp_id\"")
+	}
+	if slices.ContainsFunc(m.tags, func(t ChirpTag) bool {
+		return t.ChirpID == arg.ChirpID && t.StartOffset == arg.StartOffset
+	}) {
+		return errors.New("duplicate key value violates unique constraint \"chirp_tags_pkey\"")
+	}
+	m.tags = append(m.tags, ChirpTag(arg))
+	return nil
+}
+
+func (m *MemoryStore) AddChirpMention(ctx context.Context, arg AddChirpMentionParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.chirpIndex(arg.ChirpID) < 0 || m.userIndex(arg.UserID) < 0 {
+		return errors.New("insert on table \"chirp_mentions\" violates foreign key constraint")
+	}
+	if slices.ContainsFunc(m.mentions, func(cm ChirpMention) bool {
+		return cm.ChirpID == arg.ChirpID && cm.StartOffset == arg.StartOffset
+	}) {
+		return errors.New("duplicate key value violates unique constraint \"chirp_mentions_pkey\"")
+	}
+	m.mentions = append(m.mentions, ChirpMention(arg))
+	return nil
+}
+
+func (m *MemoryStore) DeleteChirpEntities(ctx context.Context, chirpID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tags = slices.DeleteFunc(m.tags, func(t ChirpTag) bool { return t.ChirpID == chirpID })
+	m.mentions = slices.DeleteFunc(m.mentions, func(cm ChirpMention) bool { return cm.ChirpID == chirpID })
+	return nil
+}
+
+func (m *MemoryStore) GetChirpTags(ctx context.Context, chirpIds []uuid.UUID) ([]ChirpTag, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var items []ChirpTag
+	for _, t := range m.tags {
+		if slices.Contains(chirpIds, t.ChirpID) {
+			items = append(items, t)
+		}
+	}
+	slices.SortFunc(items, func(a, b ChirpTag) int {
+		return cmp.Or(
+			strings.Compare(a.ChirpID.String(), b.ChirpID.String()),
+			cmp.Compare(a.StartOffset, b.StartOffset),
+		)
+	})
+	return items, nil
+}
+
+func (m *MemoryStore) GetChirpMentions(ctx context.Context, chirpIds []uuid.UUID) ([]ChirpMention, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var items []ChirpMention
+	for _, cm := range m.mentions {
+		if slices.Contains(chirpIds, cm.ChirpID) {
+			items = append(items, cm)
+		}
+	}
+	slices.SortFunc(items, func(a, b ChirpMention) int {
+		return cmp.Or(
+			strings.Compare(a.ChirpID.String(), b.ChirpID.String()),
+			cmp.Compare(a.StartOffset, b.StartOffset),
+		)
+	})
+	return items, nil
+}
+
+func (m *MemoryStore) ListChirpsByTag(ctx context.Context, arg ListChirpsByTagParams) ([]Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	tagged := func(c Chirp) bool {
+		return slices.ContainsFunc(m.tags, func(t ChirpTag) bool { return t.ChirpID == c.ID && t.Tag == arg.Tag })
+	}
+	return m.listChirpsNewestFirst(tagged, arg.BeforeCreatedAt.Valid, arg.BeforeCreatedAt.Time, arg.BeforeID.UUID, arg.Limit), nil
+}
+
+func (m *MemoryStore) ListMentions(ctx context.Context, arg ListMentionsParams) ([]Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	mentioned := func(c Chirp) bool {
+		return slices.ContainsFunc(m.mentions, func(cm ChirpMention) bool { return cm.ChirpID == c.ID && cm.UserID == arg.UserID })
+	}
+	return m.listChirpsNewestFirst(mentioned, arg.BeforeCreatedAt.Valid, arg.BeforeCreatedAt.Time, arg.BeforeID.UUID, arg.Limit), nil
+}
+
+// listChirpsNewestFirst returns the visible chirps matching keep,
+// newest first, keyed on (created_at, id).
+func (m *MemoryStore) listChirpsNewestFirst(keep func(Chirp) bool, hasCursor bool, beforeCreatedAt time.Time, beforeID uuid.UUID, limit int32) []Chirp {
+	var items []Chirp
+	for _, c := range m.chirps {
+		if !m.isVisible(c) || !keep(c) {
+			continue
+		}
+		if hasCursor && compareKeyset(c.CreatedAt, c.ID, beforeCreatedAt, beforeID) >= 0 {
+			continue
+		}
+		items = append(items, c)
+	}
+	slices.SortFunc(items, func(a, b Chirp) int {
+		return compareKeyset(b.CreatedAt, b.ID, a.CreatedAt, a.ID)
+	})
+	return limitRows(items, limit)
+}
@@ -0,0 +1,78 @@
+package database
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+func (m *MemoryStore) AddReaction(ctx context.Context, arg AddReactionParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.chirpIndex(arg.ChirpID) < 0 || m.userIndex(arg.UserID) < 0 {
+		return errors.New("insert on table \"chirp_reactions\" violates foreign key constraint")
+	}
+	exists := slices.ContainsFunc(m.reactions, func(r ChirpReaction) bool {
+		return r.ChirpID == arg.ChirpID && r.UserID == arg.UserID && r.Emoji == arg.Emoji
+	})
+	if exists {
+		return nil
+	}
+	m.reactions = append(m.reactions, ChirpReaction{
+		ChirpID:   arg.ChirpID,
+		UserID:    arg.UserID,
+		Emoji:     arg.Emoji,
+		CreatedAt: now(),
+	})
+	return nil
+}
+
+func (m *MemoryStore) RemoveReaction(ctx context.Context, arg RemoveReactionParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reactions = slices.DeleteFunc(m.reactions, func(r ChirpReaction) bool {
+		return r.ChirpID == arg.ChirpID && r.UserID == arg.UserID && r.Emoji == arg.Emoji
+	})
+	return nil
+}
+
+func (m *MemoryStore) GetReactionCounts(ctx context.Context, arg GetReactionCountsParams) ([]GetReactionCountsRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var items []GetReactionCountsRow
+	for _, r := range m.reactions {
+		if !slices.Contains(arg.ChirpIds, r.ChirpID) {
+			continue
+		}
+		i := slices.IndexFunc(items, func(row GetReactionCountsRow) bool {
+			return row.ChirpID == r.ChirpID && row.Emoji == r.Emoji
+		})
+		if i < 0 {
+			items = append(items, GetReactionCountsRow{ChirpID: r.ChirpID, Emoji: r.Emoji})
+			i = len(items) - 1
+		}
+		items[i].Count++
+		if arg.ViewerID.Valid && r.UserID == arg.ViewerID.UUID {
+			items[i].Reacted = true
+		}
+	}
+	slices.SortFunc(items, func(a, b GetReactionCountsRow) int {
+		return cmp.Or(
+			strings.Compare(a.ChirpID.String(), b.ChirpID.String()),
+			cmp.Compare(b.Count, a.Count),
+			strings.Compare(a.Emoji, b.Emoji),
+		)
+	})
+	return items, nil
+}
+
+func (m *MemoryStore) DeleteReactionsByChirpID(ctx context.Context, chirpID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reactions = slices.DeleteFunc(m.reactions, func(r ChirpReaction) bool { return r.ChirpID == chirpID })
+	return nil
+}
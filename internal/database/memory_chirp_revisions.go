@@ -0,0 +1,47 @@
+package database
+
+import (
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+func (m *MemoryStore) CreateChirpRevision(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.chirpIndex(id)
+	if i < 0 {
+		return nil
+	}
+	m.revisions = append(m.revisions, ChirpRevision{
+		ID:         uuid.New(),
+		ChirpID:    id,
+		Body:       m.chirps[i].Body,
+		CreatedAt:  m.chirps[i].UpdatedAt,
+		ReplacedAt: now(),
+	})
+	return nil
+}
+
+func (m *MemoryStore) GetChirpRevisions(ctx context.Context, chirpID uuid.UUID) ([]ChirpRevision, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var items []ChirpRevision
+	for _, r := range m.revisions {
+		if r.ChirpID == chirpID {
+			items = append(items, r)
+		}
+	}
+	slices.SortFunc(items, func(a, b ChirpRevision) int {
+		return compareKeyset(a.ReplacedAt, a.ID, b.ReplacedAt, b.ID)
+	})
+	return items, nil
+}
+
+func (m *MemoryStore) DeleteChirpRevisions(ctx context.Context, chirpID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.revisions = slices.DeleteFunc(m.revisions, func(r ChirpRevision) bool { return r.ChirpID == chirpID })
+	return nil
+}
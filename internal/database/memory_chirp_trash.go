@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+func (m *MemoryStore) SoftDeleteChirp(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t := sql.NullTime{Time: now(), Valid: true}
+	for i := range m.chirps {
+		c := &m.chirps[i]
+		if (c.ID == id || (c.RechirpOf.Valid && c.RechirpOf.UUID == id)) && !c.DeletedAt.Valid && !c.HeldAt.Valid {
+			c.DeletedAt = t
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) RestoreChirp(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.chirpIndex(id)
+	if i < 0 || !m.chirps[i].DeletedAt.Valid {
+		return nil
+	}
+	deletedAt := m.chirps[i].DeletedAt.Time
+	for i := range m.chirps {
+		c := &m.chirps[i]
+		if (c.ID == id || (c.RechirpOf.Valid && c.RechirpOf.UUID == id)) && c.DeletedAt.Valid && c.DeletedAt.Time.Equal(deletedAt) {
+			c.DeletedAt = sql.NullTime{}
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) GetTrashedChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	i := m.chirpIndex(id)
+	if i < 0 || !m.chirps[i].DeletedAt.Valid || m.chirps[i].TombstonedAt.Valid {
+		return Chirp{}, sql.ErrNoRows
+	}
+	return m.chirps[i], nil
+}
+
+func (m *MemoryStore) ListTrash(ctx context.Context, arg ListTrashParams) ([]Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var items []Chirp
+	for _, c := range m.chirps {
+		if c.UserID != arg.UserID || c.RechirpOf.Valid || c.TombstonedAt.Valid {
+			continue
+		}
+		if !c.DeletedAt.Valid || !c.DeletedAt.Time.After(arg.DeletedAfter) {
+			continue
+		}
+		if arg.BeforeDeletedAt.Valid && compareKeyset(c.DeletedAt.Time, c.ID, arg.BeforeDeletedAt.Time, arg.BeforeID.UUID) >= 0 {
+			continue
+		}
+		items = append(items, c)
+	}
+	slices.SortFunc(items, func(a, b Chirp) int {
+		return compareKeyset(b.DeletedAt.Time, b.ID, a.DeletedAt.Time, a.ID)
+	})
+	return limitRows(items, arg.Limit), nil
+}
+
+func (m *MemoryStore) ListExpiredTrash(ctx context.Context, arg ListExpiredTrashParams) ([]uuid.UUID, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var items []Chirp
+	for _, c := range m.chirps {
+		if c.DeletedAt.Valid && !c.DeletedAt.Time.After(arg.Cutoff) && !c.TombstonedAt.Valid {
+			items = append(items, c)
+		}
+	}
+	slices.SortFunc(items, func(a, b Chirp) int {
+		return compareKeyset(a.DeletedAt.Time, a.ID, b.DeletedAt.Time, b.ID)
+	})
+	var ids []uuid.UUID
+	for _, c := range limitRows(items, arg.Limit) {
+		ids = append(ids, c.ID)
+	}
+	return ids, nil
+}
@@ -0,0 +1,495 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"html"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+func (m *MemoryStore) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.userIndex(arg.UserID) < 0 {
+		return Chirp{}, errors.New("insert on table \"chirps\" violates foreign key constraint \"fk_user_id\"")
+	}
+	for _, ref := range []uuid.NullUUID{arg.InReplyTo, arg.RechirpOf, arg.QuoteOf} {
+		if ref.Valid && m.chirpIndex(ref.UUID) < 0 {
+			return Chirp{}, errors.New("insert on table \"chirps\" violates foreign key constraint")
+		}
+	}
+	if arg.RechirpOf.Valid && m.rechirpIndex(arg.UserID, arg.RechirpOf.UUID) >= 0 {
+		return Chirp{}, errors.New("duplicate key value violates unique constraint \"idx_chirps_user_id_rechirp_of\"")
+	}
+	t := now()
+	chirp := Chirp{
+		ID:        uuid.New(),
+		CreatedAt: t,
+		UpdatedAt: t,
+		Body:      arg.Body,
+		UserID:    arg.UserID,
+		InReplyTo: arg.InReplyTo,
+		RechirpOf: arg.RechirpOf,
+		QuoteOf:   arg.QuoteOf,
+	}
+	if arg.Held {
+		chirp.HeldAt = sql.NullTime{Time: t, Valid: true}
+		chirp.HoldReason = arg.HoldReason
+	}
+	m.chirps = append(m.chirps, chirp)
+	return chirp, nil
+}
+
+func (m *MemoryStore) DeleteChirpByID(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deleteChirp(id)
+	return nil
+}
+
+// deleteChirp removes a chirp and applies the foreign key actions that
+// reference it: reactions, tags, mentions, media attachments,
+// revisions and rechirps cascade, while replies and quotes are detached.
+func (m *MemoryStore) deleteChirp(id uuid.UUID) {
+	if m.chirpIndex(id) < 0 {
+		return
+	}
+	m.chirps = slices.DeleteFunc(m.chirps, func(c Chirp) bool { return c.ID == id })
+	m.reactions = slices.DeleteFunc(m.reactions, func(r ChirpReaction) bool { return r.ChirpID == id })
+	m.tags = slices.DeleteFunc(m.tags, func(t ChirpTag) bool { return t.ChirpID == id })
+	m.mentions = slices.DeleteFunc(m.mentions, func(cm ChirpMention) bool { return cm.ChirpID == id })
+	m.chirpMedia = slices.DeleteFunc(m.chirpMedia, func(cm ChirpMedia) bool { return cm.ChirpID == id })
+	m.revisions = slices.DeleteFunc(m.revisions, func(r ChirpRevision) bool { return r.ChirpID == id })
+	// reports outlive the chirp, as ON DELETE SET NULL
+	for i := range m.reports {
+		if m.reports[i].ChirpID.Valid && m.reports[i].ChirpID.UUID == id {
+			m.reports[i].ChirpID = uuid.NullUUID{}
+		}
+	}
+	var rechirps []uuid.UUID
+	for i := range m.chirps {
+		c := &m.chirps[i]
+		if c.InReplyTo.Valid && c.InReplyTo.UUID == id {
+			c.InReplyTo = uuid.NullUUID{}
+		}
+		if c.QuoteOf.Valid && c.QuoteOf.UUID == id {
+			c.QuoteOf = uuid.NullUUID{}
+		}
+		if c.RechirpOf.Valid && c.RechirpOf.UUID == id {
+			rechirps = append(rechirps, c.ID)
+		}
+	}
+	for _, rechirpID := range rechirps {
+		m.deleteChirp(rechirpID)
+	}
+}
+
+func (m *MemoryStore) rechirpIndex(userID, rechirpOf uuid.UUID) int {
+	return slices.IndexFunc(m.chirps, func(c Chirp) bool {
+		return c.UserID == userID && c.RechirpOf.Valid && c.RechirpOf.UUID == rechirpOf
+	})
+}
+
+func (m *MemoryStore) DeleteRechirpsOf(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var rechirps []uuid.UUID
+	for _, c := range m.chirps {
+		if c.RechirpOf.Valid && c.RechirpOf.UUID == id {
+			rechirps = append(rechirps, c.ID)
+		}
+	}
+	for _, rechirpID := range rechirps {
+		m.deleteChirp(rechirpID)
+	}
+	return nil
+}
+
+func (m *MemoryStore) DetachQuotesOf(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.chirps {
+		if m.chirps[i].QuoteOf.Valid && m.chirps[i].QuoteOf.UUID == id {
+			m.chirps[i].QuoteOf = uuid.NullUUID{}
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) GetRechirp(ctx context.Context, arg GetRechirpParams) (Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	i := m.rechirpIndex(arg.UserID, arg.RechirpOf)
+	if i < 0 {
+		return Chirp{}, sql.ErrNoRows
+	}
+	return m.chirps[i], nil
+}
+
+func (m *MemoryStore) GetChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var items []Chirp
+	for _, c := range m.chirps {
+		if m.isVisible(c) && slices.Contains(ids, c.ID) {
+			items = append(items, c)
+		}
+	}
+	return items, nil
+}
+
+func (m *MemoryStore) GetShareCounts(ctx context.Context, chirpIds []uuid.UUID) ([]GetShareCountsRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var items []GetShareCountsRow
+	for _, id := range chirpIds {
+		if m.chirpIndex(id) < 0 || slices.ContainsFunc(items, func(row GetShareCountsRow) bool { return row.ChirpID == id }) {
+			continue
+		}
+		row := GetShareCountsRow{ChirpID: id}
+		for _, c := range m.chirps {
+			if !m.isVisible(c) {
+				continue
+			}
+			if c.RechirpOf.Valid && c.RechirpOf.UUID == id {
+				row.Rechirps++
+			}
+			if c.QuoteOf.Valid && c.QuoteOf.UUID == id {
+				row.Quotes++
+			}
+		}
+		if row.Rechirps > 0 || row.Quotes > 0 {
+			items = append(items, row)
+		}
+	}
+	return items, nil
+}
+
+// isVisible reports whether a chirp shows up in listings and lookups.
+func (m *MemoryStore) isVisible(c Chirp) bool {
+	return !c.TombstonedAt.Valid && !c.DeletedAt.Valid && !c.HeldAt.Valid && !m.chirpsHidden(c.UserID)
+}
+
+// chirpsHidden reports whether the user is serving a suspension that
+// hides their chirps.
+func (m *MemoryStore) chirpsHidden(userID uuid.UUID) bool {
+	i := m.userIndex(userID)
+	if i < 0 {
+		return false
+	}
+	u := m.users[i]
+	return u.SuspensionHidesChirps && (!u.SuspendedUntil.Valid || u.SuspendedUntil.Time.After(now()))
+}
+
+func (m *MemoryStore) TombstoneChirp(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.chirpIndex(id)
+	if i < 0 {
+		return nil
+	}
+	t := now()
+	m.chirps[i].Body = ""
+	m.chirps[i].TombstonedAt = sql.NullTime{Time: t, Valid: true}
+	m.chirps[i].UpdatedAt = t
+	return nil
+}
+
+func (m *MemoryStore) UpdateChirpBody(ctx context.Context, arg UpdateChirpBodyParams) (Chirp, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.chirpIndex(arg.ID)
+	if i < 0 || !m.isVisible(m.chirps[i]) {
+		return Chirp{}, sql.ErrNoRows
+	}
+	t := now()
+	m.chirps[i].Body = arg.Body
+	m.chirps[i].UpdatedAt = t
+	if arg.Held {
+		m.chirps[i].HeldAt = sql.NullTime{Time: t, Valid: true}
+		m.chirps[i].HoldReason = arg.HoldReason
+	}
+	return m.chirps[i], nil
+}
+
+func (m *MemoryStore) ChirpHasReplies(ctx context.Context, id uuid.UUID) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return slices.ContainsFunc(m.chirps, func(c Chirp) bool { return c.InReplyTo.Valid && c.InReplyTo.UUID == id }), nil
+}
+
+func (m *MemoryStore) GetThreadChirpByID(ctx context.Context, id uuid.UUID) (GetThreadChirpByIDRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	i := m.chirpIndex(id)
+	if i < 0 {
+		return GetThreadChirpByIDRow{}, sql.ErrNoRows
+	}
+	c := m.chirps[i]
+	return GetThreadChirpByIDRow{Chirp: c, AuthorHidden: m.chirpsHidden(c.UserID)}, nil
+}
+
+func (m *MemoryStore) GetChirpAncestors(ctx context.Context, id uuid.UUID) ([]GetChirpAncestorsRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	i := m.chirpIndex(id)
+	if i < 0 {
+		return nil, nil
+	}
+	var items []GetChirpAncestorsRow
+	for parent := m.chirps[i].InReplyTo; parent.Valid; {
+		p := m.chirpIndex(parent.UUID)
+		if p < 0 {
+			break
+		}
+		items = append(items, GetChirpAncestorsRow{Chirp: m.chirps[p], AuthorHidden: m.chirpsHidden(m.chirps[p].UserID)})
+		parent = m.chirps[p].InReplyTo
+	}
+	slices.Reverse(items)
+	return items, nil
+}
+
+func (m *MemoryStore) GetChirpDescendants(ctx context.Context, arg GetChirpDescendantsParams) ([]GetChirpDescendantsRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var items []GetChirpDescendantsRow
+	parents := []uuid.UUID{arg.ID}
+	for depth := int32(1); depth <= arg.MaxDepth && len(parents) > 0; depth++ {
+		var level []GetChirpDescendantsRow
+		for _, c := range m.chirps {
+			if c.InReplyTo.Valid && slices.Contains(parents, c.InReplyTo.UUID) {
+				level = append(level, GetChirpDescendantsRow{Chirp: c, Depth: depth, AuthorHidden: m.chirpsHidden(c.UserID)})
+			}
+		}
+		slices.SortFunc(level, func(a, b GetChirpDescendantsRow) int {
+			return compareKeyset(a.Chirp.CreatedAt, a.Chirp.ID, b.Chirp.CreatedAt, b.Chirp.ID)
+		})
+		parents = parents[:0]
+		for _, row := range level {
+			parents = append(parents, row.Chirp.ID)
+		}
+		items = append(items, level...)
+	}
+	return limitRows(items, arg.RowLimit), nil
+}
+
+func (m *MemoryStore) GetChirpByID(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	i := m.chirpIndex(id)
+	if i < 0 || !m.isVisible(m.chirps[i]) {
+		return Chirp{}, sql.ErrNoRows
+	}
+	return m.chirps[i], nil
+}
+
+func (m *MemoryStore) GetChirps(ctx context.Context) ([]Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.sortedChirps(func(Chirp) bool { return true }), nil
+}
+
+func (m *MemoryStore) GetChirpsByUserID(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.sortedChirps(func(c Chirp) bool { return c.UserID == userID }), nil
+}
+
+// sortedChirps returns the chirps matching keep, oldest first. Like the
+// sqlc queries it returns nil rather than an empty slice when nothing
+// matches.
+func (m *MemoryStore) sortedChirps(keep func(Chirp) bool) []Chirp {
+	var items []Chirp
+	for _, c := range m.chirps {
+		if m.isVisible(c) && keep(c) {
+			items = append(items, c)
+		}
+	}
+	slices.SortStableFunc(items, func(a, b Chirp) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return items
+}
+
+func (m *MemoryStore) ListChirpsAsc(ctx context.Context, arg ListChirpsAscParams) ([]Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var items []Chirp
+	for _, c := range m.chirps {
+		if !m.isVisible(c) {
+			continue
+		}
+		if arg.UserID.Valid && c.UserID != arg.UserID.UUID {
+			continue
+		}
+		if arg.AfterCreatedAt.Valid && compareKeyset(c.CreatedAt, c.ID, arg.AfterCreatedAt.Time, arg.AfterID.UUID) <= 0 {
+			continue
+		}
+		items = append(items, c)
+	}
+	slices.SortFunc(items, func(a, b Chirp) int {
+		return compareKeyset(a.CreatedAt, a.ID, b.CreatedAt, b.ID)
+	})
+	return limitRows(items, arg.Limit), nil
+}
+
+func (m *MemoryStore) ListChirpsDesc(ctx context.Context, arg ListChirpsDescParams) ([]Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var items []Chirp
+	for _, c := range m.chirps {
+		if !m.isVisible(c) {
+			continue
+		}
+		if arg.UserID.Valid && c.UserID != arg.UserID.UUID {
+			continue
+		}
+		if arg.BeforeCreatedAt.Valid && compareKeyset(c.CreatedAt, c.ID, arg.BeforeCreatedAt.Time, arg.BeforeID.UUID) >= 0 {
+			continue
+		}
+		items = append(items, c)
+	}
+	slices.SortFunc(items, func(a, b Chirp) int {
+		return compareKeyset(b.CreatedAt, b.ID, a.CreatedAt, a.ID)
+	})
+	return limitRows(items, arg.Limit), nil
+}
+
+// SearchChirps approximates the Postgres full-text search: every query
+// word (or none of a -negated word) must appear in the body, compared
+// case-insensitively without stemming, and rank is the share of body
+// words that matched.
+func (m *MemoryStore) SearchChirps(ctx context.Context, arg SearchChirpsParams) ([]SearchChirpsRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var include, exclude []string
+	for _, term := range strings.Fields(strings.ToLower(arg.Query)) {
+		negated := strings.HasPrefix(term, "-")
+		for _, word := range searchWords(term) {
+			if negated {
+				exclude = append(exclude, word)
+			} else {
+				include = append(include, word)
+			}
+		}
+	}
+	if len(include) == 0 {
+		return nil, nil
+	}
+
+	var items []SearchChirpsRow
+	for _, c := range m.chirps {
+		if !m.isVisible(c) {
+			continue
+		}
+		if arg.UserID.Valid && c.UserID != arg.UserID.UUID {
+			continue
+		}
+		if arg.Since.Valid && c.CreatedAt.Before(arg.Since.Time) {
+			continue
+		}
+		if arg.Until.Valid && !c.CreatedAt.Before(arg.Until.Time) {
+			continue
+		}
+		words := searchWords(c.Body)
+		if !containsAll(words, include) || containsAny(words, exclude) {
+			continue
+		}
+		matched := 0
+		for _, w := range words {
+			if slices.Contains(include, w) {
+				matched++
+			}
+		}
+		row := SearchChirpsRow{
+			Chirp:     c,
+			Rank:      float32(matched) / float32(len(words)),
+			Highlight: highlightWords(c.Body, include),
+		}
+		if arg.AfterRank.Valid && compareSearchRows(row, float32(arg.AfterRank.Float64), arg.AfterCreatedAt.Time, arg.AfterID.UUID) >= 0 {
+			continue
+		}
+		items = append(items, row)
+	}
+	slices.SortFunc(items, func(a, b SearchChirpsRow) int {
+		return compareSearchRows(b, a.Rank, a.Chirp.CreatedAt, a.Chirp.ID)
+	})
+	return limitRows(items, arg.Limit), nil
+}
+
+// compareSearchRows orders a search row against a (rank, created_at, id)
+// key, the keyset SearchChirps pages through in descending order.
+func compareSearchRows(row SearchChirpsRow, rank float32, createdAt time.Time, id uuid.UUID) int {
+	if row.Rank != rank {
+		if row.Rank < rank {
+			return -1
+		}
+		return 1
+	}
+	return compareKeyset(row.Chirp.CreatedAt, row.Chirp.ID, createdAt, id)
+}
+
+func isSearchWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func searchWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool { return !isSearchWordRune(r) })
+}
+
+func containsAll(words, terms []string) bool {
+	for _, t := range terms {
+		if !slices.Contains(words, t) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsAny(words, terms []string) bool {
+	return slices.ContainsFunc(terms, func(t string) bool { return slices.Contains(words, t) })
+}
+
+// highlightWords HTML escapes body and wraps every word found in terms
+// in <mark> tags, matching the ts_headline options used by SearchChirps.
+func highlightWords(body string, terms []string) string {
+	var sb strings.Builder
+	runes := []rune(body)
+	for i := 0; i < len(runes); {
+		if !isSearchWordRune(runes[i]) {
+			sb.WriteString(html.EscapeString(string(runes[i])))
+			i++
+			continue
+		}
+		j := i
+		for j < len(runes) && isSearchWordRune(runes[j]) {
+			j++
+		}
+		word := string(runes[i:j])
+		if slices.Contains(terms, strings.ToLower(word)) {
+			sb.WriteString("<mark>" + html.EscapeString(word) + "</mark>")
+		} else {
+			sb.WriteString(html.EscapeString(word))
+		}
+		i = j
+	}
+	return sb.String()
+}
+
+func (m *MemoryStore) ReleaseChirpHold(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.chirpIndex(id)
+	if i < 0 || !m.chirps[i].HeldAt.Valid {
+		return nil
+	}
+	m.chirps[i].HeldAt = sql.NullTime{}
+	m.chirps[i].HoldReason = ""
+	return nil
+}
@@ -0,0 +1,43 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+)
+
+func (m *MemoryStore) CreateEmailVerificationToken(ctx context.Context, arg CreateEmailVerificationTokenParams) (EmailVerificationToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.userIndex(arg.UserID) < 0 {
+		return EmailVerificationToken{}, errors.New("insert on table \"email_verification_tokens\" violates foreign key constraint \"email_verification_tokens_user_id_fkey\"")
+	}
+	if slices.ContainsFunc(m.emailVerifications, func(t EmailVerificationToken) bool { return t.TokenHash == arg.TokenHash }) {
+		return EmailVerificationToken{}, errors.New("duplicate key value violates unique constraint \"email_verification_tokens_pkey\"")
+	}
+	token := EmailVerificationToken{
+		TokenHash: arg.TokenHash,
+		UserID:    arg.UserID,
+		Email:     arg.Email,
+		CreatedAt: now(),
+		ExpiresAt: arg.ExpiresAt,
+	}
+	m.emailVerifications = append(m.emailVerifications, token)
+	return token, nil
+}
+
+func (m *MemoryStore) UseEmailVerificationToken(ctx context.Context, tokenHash string) (EmailVerificationToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := slices.IndexFunc(m.emailVerifications, func(t EmailVerificationToken) bool { return t.TokenHash == tokenHash })
+	if i < 0 {
+		return EmailVerificationToken{}, sql.ErrNoRows
+	}
+	t := &m.emailVerifications[i]
+	if t.UsedAt.Valid || !t.ExpiresAt.After(now()) {
+		return EmailVerificationToken{}, sql.ErrNoRows
+	}
+	t.UsedAt = sql.NullTime{Time: now(), Valid: true}
+	return *t, nil
+}
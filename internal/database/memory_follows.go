@@ -0,0 +1,106 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func (m *MemoryStore) FollowUser(ctx context.Context, arg FollowUserParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if arg.FollowerID == arg.FolloweeID {
+		return errors.New("new row for relation \"follows\" violates check constraint \"chk_no_self_follow\"")
+	}
+	if m.userIndex(arg.FollowerID) < 0 || m.userIndex(arg.FolloweeID) < 0 {
+		return errors.New("insert on table \"follows\" violates foreign key constraint")
+	}
+	if m.isFollowing(arg.FollowerID, arg.FolloweeID) {
+		return nil
+	}
+	m.follows = append(m.follows, Follow{
+		FollowerID: arg.FollowerID,
+		FolloweeID: arg.FolloweeID,
+		CreatedAt:  now(),
+	})
+	return nil
+}
+
+func (m *MemoryStore) isFollowing(followerID, followeeID uuid.UUID) bool {
+	return slices.ContainsFunc(m.follows, func(f Follow) bool {
+		return f.FollowerID == followerID && f.FolloweeID == followeeID
+	})
+}
+
+func (m *MemoryStore) UnfollowUser(ctx context.Context, arg UnfollowUserParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.follows = slices.DeleteFunc(m.follows, func(f Follow) bool {
+		return f.FollowerID == arg.FollowerID && f.FolloweeID == arg.FolloweeID
+	})
+	return nil
+}
+
+func (m *MemoryStore) ListFollowers(ctx context.Context, arg ListFollowersParams) ([]Follow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.listFollows(
+		func(f Follow) bool { return f.FolloweeID == arg.FolloweeID },
+		func(f Follow) uuid.UUID { return f.FollowerID },
+		arg.BeforeCreatedAt.Valid, arg.BeforeCreatedAt.Time, arg.BeforeID.UUID, arg.Limit,
+	), nil
+}
+
+func (m *MemoryStore) ListFollowing(ctx context.Context, arg ListFollowingParams) ([]Follow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.listFollows(
+		func(f Follow) bool { return f.FollowerID == arg.FollowerID },
+		func(f Follow) uuid.UUID { return f.FolloweeID },
+		arg.BeforeCreatedAt.Valid, arg.BeforeCreatedAt.Time, arg.BeforeID.UUID, arg.Limit,
+	), nil
+}
+
+// listFollows returns the follows matching keep, newest first, keyed
+// on (created_at, other) where other picks the user on the far side.
+func (m *MemoryStore) listFollows(keep func(Follow) bool, other func(Follow) uuid.UUID, hasCursor bool, beforeCreatedAt time.Time, beforeID uuid.UUID, limit int32) []Follow {
+	var items []Follow
+	for _, f := range m.follows {
+		if !keep(f) {
+			continue
+		}
+		if hasCursor && compareKeyset(f.CreatedAt, other(f), beforeCreatedAt, beforeID) >= 0 {
+			continue
+		}
+		items = append(items, f)
+	}
+	slices.SortFunc(items, func(a, b Follow) int {
+		return compareKeyset(b.CreatedAt, other(b), a.CreatedAt, other(a))
+	})
+	return limitRows(items, limit)
+}
+
+func (m *MemoryStore) GetTimeline(ctx context.Context, arg GetTimelineParams) ([]Chirp, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var items []Chirp
+	for _, c := range m.chirps {
+		if !m.isVisible(c) {
+			continue
+		}
+		if c.UserID != arg.UserID && !m.isFollowing(arg.UserID, c.UserID) {
+			continue
+		}
+		if arg.BeforeCreatedAt.Valid && compareKeyset(c.CreatedAt, c.ID, arg.BeforeCreatedAt.Time, arg.BeforeID.UUID) >= 0 {
+			continue
+		}
+		items = append(items, c)
+	}
+	slices.SortFunc(items, func(a, b Chirp) int {
+		return compareKeyset(b.CreatedAt, b.ID, a.CreatedAt, a.ID)
+	})
+	return limitRows(items, arg.Limit), nil
+}
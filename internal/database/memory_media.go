@@ -0,0 +1,92 @@
+package database
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+func (m *MemoryStore) CreateMedia(ctx context.Context, arg CreateMediaParams) (Media, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.userIndex(arg.UserID) < 0 {
+		return Media{}, errors.New("insert on table \"media\" violates foreign key constraint \"fk_user_id\"")
+	}
+	if m.mediaIndex(arg.ID) >= 0 {
+		return Media{}, errors.New("duplicate key value violates unique constraint \"media_pkey\"")
+	}
+	media := Media{
+		ID:          arg.ID,
+		CreatedAt:   now(),
+		UserID:      arg.UserID,
+		ContentType: arg.ContentType,
+		SizeBytes:   arg.SizeBytes,
+	}
+	m.media = append(m.media, media)
+	return media, nil
+}
+
+func (m *MemoryStore) mediaIndex(id uuid.UUID) int {
+	return slices.IndexFunc(m.media, func(media Media) bool { return media.ID == id })
+}
+
+func (m *MemoryStore) GetMediaByID(ctx context.Context, id uuid.UUID) (Media, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	i := m.mediaIndex(id)
+	if i < 0 {
+		return Media{}, sql.ErrNoRows
+	}
+	return m.media[i], nil
+}
+
+func (m *MemoryStore) AttachChirpMedia(ctx context.Context, arg AttachChirpMediaParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.chirpIndex(arg.ChirpID) < 0 || m.mediaIndex(arg.MediaID) < 0 {
+		return errors.New("insert on table \"chirp_media\" violates foreign key constraint")
+	}
+	if slices.ContainsFunc(m.chirpMedia, func(cm ChirpMedia) bool {
+		return cm.ChirpID == arg.ChirpID && cm.Position == arg.Position
+	}) {
+		return errors.New("duplicate key value violates unique constraint \"chirp_media_pkey\"")
+	}
+	m.chirpMedia = append(m.chirpMedia, ChirpMedia(arg))
+	return nil
+}
+
+func (m *MemoryStore) DetachChirpMedia(ctx context.Context, chirpID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.chirpMedia = slices.DeleteFunc(m.chirpMedia, func(cm ChirpMedia) bool { return cm.ChirpID == chirpID })
+	return nil
+}
+
+func (m *MemoryStore) GetChirpMedia(ctx context.Context, chirpIds []uuid.UUID) ([]GetChirpMediaRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var items []GetChirpMediaRow
+	for _, cm := range m.chirpMedia {
+		if !slices.Contains(chirpIds, cm.ChirpID) {
+			continue
+		}
+		items = append(items, GetChirpMediaRow{
+			ChirpID:  cm.ChirpID,
+			Position: cm.Position,
+			AltText:  cm.AltText,
+			Media:    m.media[m.mediaIndex(cm.MediaID)],
+		})
+	}
+	slices.SortFunc(items, func(a, b GetChirpMediaRow) int {
+		return cmp.Or(
+			strings.Compare(a.ChirpID.String(), b.ChirpID.String()),
+			cmp.Compare(a.Position, b.Position),
+		)
+	})
+	return items, nil
+}
@@ -0,0 +1,101 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+func (m *MemoryStore) CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.userIndex(arg.UserID) < 0 {
+		return errors.New("insert on table \"mfa_recovery_codes\" violates foreign key constraint \"mfa_recovery_codes_user_id_fkey\"")
+	}
+	if slices.ContainsFunc(m.recoveryCodes, func(c MfaRecoveryCode) bool { return c.CodeHash == arg.CodeHash }) {
+		return errors.New("duplicate key value violates unique constraint \"mfa_recovery_codes_pkey\"")
+	}
+	m.recoveryCodes = append(m.recoveryCodes, MfaRecoveryCode{
+		CodeHash:  arg.CodeHash,
+		UserID:    arg.UserID,
+		CreatedAt: now(),
+	})
+	return nil
+}
+
+func (m *MemoryStore) DeleteUserRecoveryCodes(ctx context.Context, userID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.recoveryCodes = slices.DeleteFunc(m.recoveryCodes, func(c MfaRecoveryCode) bool { return c.UserID == userID })
+	return nil
+}
+
+func (m *MemoryStore) UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := slices.IndexFunc(m.recoveryCodes, func(c MfaRecoveryCode) bool {
+		return c.UserID == arg.UserID && c.CodeHash == arg.CodeHash && !c.UsedAt.Valid
+	})
+	if i < 0 {
+		return 0, nil
+	}
+	m.recoveryCodes[i].UsedAt = sql.NullTime{Time: now(), Valid: true}
+	return 1, nil
+}
+
+func (m *MemoryStore) CreateMFAChallenge(ctx context.Context, arg CreateMFAChallengeParams) (MfaChallenge, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.userIndex(arg.UserID) < 0 {
+		return MfaChallenge{}, errors.New("insert on table \"mfa_challenges\" violates foreign key constraint \"mfa_challenges_user_id_fkey\"")
+	}
+	if slices.ContainsFunc(m.mfaChallenges, func(c MfaChallenge) bool { return c.TokenHash == arg.TokenHash }) {
+		return MfaChallenge{}, errors.New("duplicate key value violates unique constraint \"mfa_challenges_pkey\"")
+	}
+	challenge := MfaChallenge{
+		TokenHash: arg.TokenHash,
+		UserID:    arg.UserID,
+		CreatedAt: now(),
+		ExpiresAt: arg.ExpiresAt,
+	}
+	m.mfaChallenges = append(m.mfaChallenges, challenge)
+	return challenge, nil
+}
+
+func (m *MemoryStore) GetMFAChallenge(ctx context.Context, arg GetMFAChallengeParams) (MfaChallenge, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	i := slices.IndexFunc(m.mfaChallenges, func(c MfaChallenge) bool { return c.TokenHash == arg.TokenHash })
+	if i < 0 {
+		return MfaChallenge{}, sql.ErrNoRows
+	}
+	c := m.mfaChallenges[i]
+	if c.UsedAt.Valid || !c.ExpiresAt.After(now()) || c.FailedAttempts >= arg.FailedAttempts {
+		return MfaChallenge{}, sql.ErrNoRows
+	}
+	return c, nil
+}
+
+func (m *MemoryStore) RecordMFAChallengeFailure(ctx context.Context, tokenHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := slices.IndexFunc(m.mfaChallenges, func(c MfaChallenge) bool { return c.TokenHash == tokenHash })
+	if i >= 0 {
+		m.mfaChallenges[i].FailedAttempts++
+	}
+	return nil
+}
+
+func (m *MemoryStore) UseMFAChallenge(ctx context.Context, tokenHash string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := slices.IndexFunc(m.mfaChallenges, func(c MfaChallenge) bool { return c.TokenHash == tokenHash && !c.UsedAt.Valid })
+	if i < 0 {
+		return 0, nil
+	}
+	m.mfaChallenges[i].UsedAt = sql.NullTime{Time: now(), Valid: true}
+	return 1, nil
+}
@@ -0,0 +1,75 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+func (m *MemoryStore) CreateModerationRule(ctx context.Context, arg CreateModerationRuleParams) (ModerationRule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t := now()
+	rule := ModerationRule{
+		ID:        uuid.New(),
+		CreatedAt: t,
+		UpdatedAt: t,
+		Kind:      arg.Kind,
+		Patterns:  slices.Clone(arg.Patterns),
+		Action:    arg.Action,
+		Reason:    arg.Reason,
+		Enabled:   arg.Enabled,
+	}
+	m.rules = append(m.rules, rule)
+	return rule, nil
+}
+
+func (m *MemoryStore) ListModerationRules(ctx context.Context) ([]ModerationRule, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	items := slices.Clone(m.rules)
+	slices.SortFunc(items, func(a, b ModerationRule) int {
+		return compareKeyset(a.CreatedAt, a.ID, b.CreatedAt, b.ID)
+	})
+	return items, nil
+}
+
+func (m *MemoryStore) ruleIndex(id uuid.UUID) int {
+	return slices.IndexFunc(m.rules, func(r ModerationRule) bool { return r.ID == id })
+}
+
+func (m *MemoryStore) GetModerationRule(ctx context.Context, id uuid.UUID) (ModerationRule, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	i := m.ruleIndex(id)
+	if i < 0 {
+		return ModerationRule{}, sql.ErrNoRows
+	}
+	return m.rules[i], nil
+}
+
+func (m *MemoryStore) UpdateModerationRule(ctx context.Context, arg UpdateModerationRuleParams) (ModerationRule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.ruleIndex(arg.ID)
+	if i < 0 {
+		return ModerationRule{}, sql.ErrNoRows
+	}
+	rule := &m.rules[i]
+	rule.Kind = arg.Kind
+	rule.Patterns = slices.Clone(arg.Patterns)
+	rule.Action = arg.Action
+	rule.Reason = arg.Reason
+	rule.Enabled = arg.Enabled
+	rule.UpdatedAt = now()
+	return *rule, nil
+}
+
+func (m *MemoryStore) DeleteModerationRule(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rules = slices.DeleteFunc(m.rules, func(r ModerationRule) bool { return r.ID == id })
+	return nil
+}
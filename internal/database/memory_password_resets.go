@@ -0,0 +1,56 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+func (m *MemoryStore) CreatePasswordResetToken(ctx context.Context, arg CreatePasswordResetTokenParams) (PasswordResetToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.userIndex(arg.UserID) < 0 {
+		return PasswordResetToken{}, errors.New("insert on table \"password_reset_tokens\" violates foreign key constraint \"password_reset_tokens_user_id_fkey\"")
+	}
+	if slices.ContainsFunc(m.passwordResets, func(t PasswordResetToken) bool { return t.TokenHash == arg.TokenHash }) {
+		return PasswordResetToken{}, errors.New("duplicate key value violates unique constraint \"password_reset_tokens_pkey\"")
+	}
+	token := PasswordResetToken{
+		TokenHash: arg.TokenHash,
+		UserID:    arg.UserID,
+		CreatedAt: now(),
+		ExpiresAt: arg.ExpiresAt,
+	}
+	m.passwordResets = append(m.passwordResets, token)
+	return token, nil
+}
+
+func (m *MemoryStore) UsePasswordResetToken(ctx context.Context, tokenHash string) (PasswordResetToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := slices.IndexFunc(m.passwordResets, func(t PasswordResetToken) bool { return t.TokenHash == tokenHash })
+	if i < 0 {
+		return PasswordResetToken{}, sql.ErrNoRows
+	}
+	t := &m.passwordResets[i]
+	if t.UsedAt.Valid || !t.ExpiresAt.After(now()) {
+		return PasswordResetToken{}, sql.ErrNoRows
+	}
+	t.UsedAt = sql.NullTime{Time: now(), Valid: true}
+	return *t, nil
+}
+
+func (m *MemoryStore) ExpireUserPasswordResetTokens(ctx context.Context, userID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.passwordResets {
+		t := &m.passwordResets[i]
+		if t.UserID == userID && !t.UsedAt.Valid {
+			t.UsedAt = sql.NullTime{Time: now(), Valid: true}
+		}
+	}
+	return nil
+}
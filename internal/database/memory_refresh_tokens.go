@@ -0,0 +1,191 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func (m *MemoryStore) CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (RefreshToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.userIndex(arg.UserID) < 0 {
+		return RefreshToken{}, errors.New("insert on table \"refresh_tokens\" violates foreign key constraint \"fk_user_id\"")
+	}
+	if m.tokenIndex(arg.TokenHash) >= 0 {
+		return RefreshToken{}, errors.New("duplicate key value violates unique constraint \"refresh_tokens_pkey\"")
+	}
+	token := RefreshToken{
+		TokenHash:  arg.TokenHash,
+		CreatedAt:  now(),
+		UpdatedAt:  arg.UpdatedAt,
+		UserID:     arg.UserID,
+		ExpiresAt:  arg.ExpiresAt,
+		FamilyID:   arg.FamilyID,
+		UserAgent:  arg.UserAgent,
+		IpAddress:  arg.IpAddress,
+		LastUsedAt: now(),
+	}
+	m.refreshTokens = append(m.refreshTokens, token)
+	return token, nil
+}
+
+func (m *MemoryStore) ListUserSessions(ctx context.Context, userID uuid.UUID) ([]ListUserSessionsRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	signedIn := map[uuid.UUID]time.Time{}
+	for _, t := range m.refreshTokens {
+		if first, ok := signedIn[t.FamilyID]; !ok || t.CreatedAt.Before(first) {
+			signedIn[t.FamilyID] = t.CreatedAt
+		}
+	}
+	sessions := []ListUserSessionsRow{}
+	for _, t := range m.refreshTokens {
+		if t.UserID != userID || t.RevokedAt.Valid || !t.ExpiresAt.After(now()) {
+			continue
+		}
+		sessions = append(sessions, ListUserSessionsRow{
+			FamilyID:   t.FamilyID,
+			UserAgent:  t.UserAgent,
+			IpAddress:  t.IpAddress,
+			LastUsedAt: t.LastUsedAt,
+			ExpiresAt:  t.ExpiresAt,
+			SignedInAt: signedIn[t.FamilyID],
+		})
+	}
+	slices.SortFunc(sessions, func(a, b ListUserSessionsRow) int {
+		return compareKeyset(b.LastUsedAt, a.FamilyID, a.LastUsedAt, b.FamilyID)
+	})
+	return sessions, nil
+}
+
+func (m *MemoryStore) RevokeUserSession(ctx context.Context, arg RevokeUserSessionParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t := now()
+	var revoked int64
+	for i := range m.refreshTokens {
+		rt := &m.refreshTokens[i]
+		if rt.FamilyID == arg.FamilyID && rt.UserID == arg.UserID && !rt.RevokedAt.Valid && rt.ExpiresAt.After(t) {
+			rt.RevokedAt = sql.NullTime{Time: t, Valid: true}
+			rt.UpdatedAt = t
+			revoked++
+		}
+	}
+	return revoked, nil
+}
+
+func (m *MemoryStore) RevokeOtherUserSessions(ctx context.Context, arg RevokeOtherUserSessionsParams) ([]uuid.UUID, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t := now()
+	var families []uuid.UUID
+	for i := range m.refreshTokens {
+		rt := &m.refreshTokens[i]
+		if rt.UserID == arg.UserID && rt.FamilyID != arg.FamilyID && !rt.RevokedAt.Valid {
+			rt.RevokedAt = sql.NullTime{Time: t, Valid: true}
+			rt.UpdatedAt = t
+			families = append(families, rt.FamilyID)
+		}
+	}
+	return families, nil
+}
+
+func (m *MemoryStore) IsSessionRevoked(ctx context.Context, familyID uuid.UUID) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return slices.ContainsFunc(m.refreshTokens, func(rt RefreshToken) bool {
+		return rt.FamilyID == familyID && rt.RevokedAt.Valid && !rt.ReplacedBy.Valid
+	}), nil
+}
+
+func (m *MemoryStore) GetRefreshToken(ctx context.Context, tokenHash string) (RefreshToken, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	i := m.tokenIndex(tokenHash)
+	if i < 0 {
+		return RefreshToken{}, sql.ErrNoRows
+	}
+	return m.refreshTokens[i], nil
+}
+
+func (m *MemoryStore) RotateRefreshToken(ctx context.Context, arg RotateRefreshTokenParams) (RefreshToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.tokenIndex(arg.TokenHash)
+	if i < 0 {
+		return RefreshToken{}, sql.ErrNoRows
+	}
+	rt := &m.refreshTokens[i]
+	if rt.RevokedAt.Valid || !rt.ExpiresAt.After(now()) {
+		return RefreshToken{}, sql.ErrNoRows
+	}
+	t := now()
+	rt.RevokedAt = sql.NullTime{Time: t, Valid: true}
+	rt.UpdatedAt = t
+	rt.ReplacedBy = arg.ReplacedBy
+	return *rt, nil
+}
+
+func (m *MemoryStore) RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t := now()
+	for i := range m.refreshTokens {
+		rt := &m.refreshTokens[i]
+		if rt.FamilyID == familyID && !rt.RevokedAt.Valid {
+			rt.RevokedAt = sql.NullTime{Time: t, Valid: true}
+			rt.UpdatedAt = t
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) GetUserFromRefreshToken(ctx context.Context, tokenHash string) (User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	i := m.tokenIndex(tokenHash)
+	if i < 0 {
+		return User{}, sql.ErrNoRows
+	}
+	t := m.refreshTokens[i]
+	if t.RevokedAt.Valid || !t.ExpiresAt.After(now()) {
+		return User{}, sql.ErrNoRows
+	}
+	u := m.userIndex(t.UserID)
+	if u < 0 {
+		return User{}, sql.ErrNoRows
+	}
+	return m.users[u], nil
+}
+
+func (m *MemoryStore) RevokeToken(ctx context.Context, tokenHash string) (RefreshToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.tokenIndex(tokenHash)
+	if i < 0 {
+		return RefreshToken{}, sql.ErrNoRows
+	}
+	t := now()
+	m.refreshTokens[i].RevokedAt = sql.NullTime{Time: t, Valid: true}
+	m.refreshTokens[i].UpdatedAt = t
+	return m.refreshTokens[i], nil
+}
+
+func (m *MemoryStore) RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t := now()
+	for i := range m.refreshTokens {
+		rt := &m.refreshTokens[i]
+		if rt.UserID == userID && !rt.RevokedAt.Valid {
+			rt.RevokedAt = sql.NullTime{Time: t, Valid: true}
+			rt.UpdatedAt = t
+		}
+	}
+	return nil
+}
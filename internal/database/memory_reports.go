@@ -0,0 +1,130 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+func (m *MemoryStore) CreateReport(ctx context.Context, arg CreateReportParams) (Report, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	report := Report{
+		ID:         uuid.New(),
+		CreatedAt:  now(),
+		ReporterID: arg.ReporterID,
+		ChirpID:    arg.ChirpID,
+		UserID:     arg.UserID,
+		Category:   arg.Category,
+		Details:    arg.Details,
+	}
+	m.reports = append(m.reports, report)
+	return report, nil
+}
+
+func (m *MemoryStore) GetReport(ctx context.Context, id uuid.UUID) (Report, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	i := slices.IndexFunc(m.reports, func(r Report) bool { return r.ID == id })
+	if i < 0 {
+		return Report{}, sql.ErrNoRows
+	}
+	return m.reports[i], nil
+}
+
+// openReports returns the unresolved reports in (created_at, id) order.
+func (m *MemoryStore) openReports() []Report {
+	var items []Report
+	for _, r := range m.reports {
+		if !r.ResolutionID.Valid {
+			items = append(items, r)
+		}
+	}
+	slices.SortFunc(items, func(a, b Report) int {
+		return compareKeyset(a.CreatedAt, a.ID, b.CreatedAt, b.ID)
+	})
+	return items
+}
+
+func (m *MemoryStore) ListOpenReportGroups(ctx context.Context, arg ListOpenReportGroupsParams) ([]ListOpenReportGroupsRow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	type groupKey struct {
+		userID  uuid.UUID
+		chirpID uuid.NullUUID
+	}
+	var items []ListOpenReportGroupsRow
+	groups := map[groupKey]int{}
+	reporters := map[groupKey]map[uuid.UUID]bool{}
+	// reports arrive oldest first, so a group's first report creates it
+	for _, r := range m.openReports() {
+		key := groupKey{userID: r.UserID, chirpID: r.ChirpID}
+		i, ok := groups[key]
+		if !ok {
+			i = len(items)
+			groups[key] = i
+			reporters[key] = map[uuid.UUID]bool{}
+			items = append(items, ListOpenReportGroupsRow{
+				UserID:          r.UserID,
+				ChirpID:         r.ChirpID,
+				FirstReportedAt: r.CreatedAt,
+				FirstReportID:   r.ID,
+			})
+		}
+		items[i].ReportCount++
+		items[i].LastReportedAt = r.CreatedAt
+		if r.ReporterID.Valid && !reporters[key][r.ReporterID.UUID] {
+			reporters[key][r.ReporterID.UUID] = true
+			items[i].ReporterCount++
+		}
+	}
+	if arg.AfterReportedAt.Valid {
+		items = slices.DeleteFunc(items, func(g ListOpenReportGroupsRow) bool {
+			return compareKeyset(g.FirstReportedAt, g.FirstReportID, arg.AfterReportedAt.Time, arg.AfterID.UUID) <= 0
+		})
+	}
+	return limitRows(items, arg.Limit), nil
+}
+
+func (m *MemoryStore) ListOpenReportsByUsers(ctx context.Context, userIds []uuid.UUID) ([]Report, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return slices.DeleteFunc(m.openReports(), func(r Report) bool {
+		return !slices.Contains(userIds, r.UserID)
+	}), nil
+}
+
+func (m *MemoryStore) CreateReportResolution(ctx context.Context, arg CreateReportResolutionParams) (ReportResolution, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	resolution := ReportResolution{
+		ID:         uuid.New(),
+		CreatedAt:  now(),
+		Action:     arg.Action,
+		Note:       arg.Note,
+		ChirpID:    arg.ChirpID,
+		UserID:     arg.UserID,
+		ResolvedBy: arg.ResolvedBy,
+	}
+	m.resolutions = append(m.resolutions, resolution)
+	return resolution, nil
+}
+
+func (m *MemoryStore) ResolveReports(ctx context.Context, arg ResolveReportsParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.reports {
+		r := &m.reports[i]
+		if !r.ResolutionID.Valid && r.UserID == arg.UserID && sameNullUUID(r.ChirpID, arg.ChirpID) {
+			r.ResolutionID = uuid.NullUUID{UUID: arg.ResolutionID, Valid: true}
+		}
+	}
+	return nil
+}
+
+// sameNullUUID compares like IS NOT DISTINCT FROM.
+func sameNullUUID(a, b uuid.NullUUID) bool {
+	return a.Valid == b.Valid && (!a.Valid || a.UUID == b.UUID)
+}
@@ -0,0 +1,345 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+func (m *MemoryStore) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if slices.ContainsFunc(m.users, func(u User) bool { return u.Email == arg.Email }) {
+		return User{}, errDuplicateEmail
+	}
+	if m.handleTaken(arg.Handle, uuid.Nil) {
+		return User{}, errDuplicateHandle
+	}
+	t := now()
+	user := User{
+		ID:             uuid.New(),
+		CreatedAt:      t,
+		UpdatedAt:      t,
+		Email:          arg.Email,
+		HashedPassword: arg.HashedPassword,
+		IsChirpyRed:    sql.NullBool{Bool: false, Valid: true},
+		Handle:         arg.Handle,
+		Role:           "user",
+	}
+	m.users = append(m.users, user)
+	return user, nil
+}
+
+func (m *MemoryStore) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	i := slices.IndexFunc(m.users, func(u User) bool { return u.Email == email })
+	if i < 0 {
+		return User{}, sql.ErrNoRows
+	}
+	return m.users[i], nil
+}
+
+func (m *MemoryStore) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	i := m.userIndex(id)
+	if i < 0 {
+		return User{}, sql.ErrNoRows
+	}
+	return m.users[i], nil
+}
+
+// handleTaken reports whether a user other than self already has
+// handle. A NULL handle never conflicts.
+func (m *MemoryStore) handleTaken(handle sql.NullString, self uuid.UUID) bool {
+	return handle.Valid && slices.ContainsFunc(m.users, func(u User) bool {
+		return u.Handle.Valid && u.Handle.String == handle.String && u.ID != self
+	})
+}
+
+func (m *MemoryStore) GetUsersByHandles(ctx context.Context, handles []string) ([]User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var items []User
+	for _, u := range m.users {
+		if u.Handle.Valid && slices.Contains(handles, u.Handle.String) {
+			items = append(items, u)
+		}
+	}
+	return items, nil
+}
+
+func (m *MemoryStore) Reset(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.users = nil
+	m.chirps = nil
+	m.refreshTokens = nil
+	m.follows = nil
+	m.reactions = nil
+	m.tags = nil
+	m.mentions = nil
+	m.media = nil
+	m.chirpMedia = nil
+	m.revisions = nil
+	m.reports = nil
+	m.resolutions = nil
+	m.passwordResets = nil
+	m.emailVerifications = nil
+	m.recoveryCodes = nil
+	m.mfaChallenges = nil
+	return nil
+}
+
+func (m *MemoryStore) UpdateLoginDetailsByID(ctx context.Context, arg UpdateLoginDetailsByIDParams) (User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.userIndex(arg.ID)
+	if i < 0 {
+		return User{}, sql.ErrNoRows
+	}
+	if slices.ContainsFunc(m.users, func(u User) bool { return u.Email == arg.Email && u.ID != arg.ID }) {
+		return User{}, errDuplicateEmail
+	}
+	if m.handleTaken(arg.Handle, arg.ID) {
+		return User{}, errDuplicateHandle
+	}
+	m.users[i].HashedPassword = arg.HashedPassword
+	m.users[i].Email = arg.Email
+	if arg.Handle.Valid {
+		m.users[i].Handle = arg.Handle
+	}
+	m.users[i].UpdatedAt = now()
+	return m.users[i], nil
+}
+
+func (m *MemoryStore) UpgradeChirpyRedByID(ctx context.Context, id uuid.UUID) (User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.userIndex(id)
+	if i < 0 {
+		return User{}, sql.ErrNoRows
+	}
+	m.users[i].IsChirpyRed = sql.NullBool{Bool: true, Valid: true}
+	return m.users[i], nil
+}
+
+func (m *MemoryStore) SuspendUser(ctx context.Context, arg SuspendUserParams) (User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.userIndex(arg.ID)
+	if i < 0 {
+		return User{}, sql.ErrNoRows
+	}
+	t := now()
+	u := &m.users[i]
+	u.SuspendedAt = sql.NullTime{Time: t, Valid: true}
+	u.SuspendedUntil = arg.SuspendedUntil
+	u.SuspensionReason = arg.SuspensionReason
+	u.SuspensionHidesChirps = arg.SuspensionHidesChirps
+	u.UpdatedAt = t
+	return *u, nil
+}
+
+func (m *MemoryStore) LiftSuspension(ctx context.Context, id uuid.UUID) (User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.userIndex(id)
+	if i < 0 {
+		return User{}, sql.ErrNoRows
+	}
+	u := &m.users[i]
+	u.SuspendedAt = sql.NullTime{}
+	u.SuspendedUntil = sql.NullTime{}
+	u.SuspensionReason = ""
+	u.SuspensionHidesChirps = false
+	u.UpdatedAt = now()
+	return *u, nil
+}
+
+func (m *MemoryStore) SetUserRole(ctx context.Context, arg SetUserRoleParams) (User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.userIndex(arg.ID)
+	if i < 0 {
+		return User{}, sql.ErrNoRows
+	}
+	m.users[i].Role = arg.Role
+	m.users[i].UpdatedAt = now()
+	return m.users[i], nil
+}
+
+func (m *MemoryStore) CountAdmins(ctx context.Context) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var n int64
+	for _, u := range m.users {
+		if u.Role == "admin" {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *MemoryStore) GetTokensValidAfter(ctx context.Context, id uuid.UUID) (sql.NullTime, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	i := m.userIndex(id)
+	if i < 0 {
+		return sql.NullTime{}, sql.ErrNoRows
+	}
+	return m.users[i].TokensValidAfter, nil
+}
+
+func (m *MemoryStore) RevokeUserAccessTokens(ctx context.Context, arg RevokeUserAccessTokensParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.userIndex(arg.ID)
+	if i >= 0 {
+		m.users[i].TokensValidAfter = arg.TokensValidAfter
+	}
+	return nil
+}
+
+func (m *MemoryStore) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.userIndex(arg.ID)
+	if i >= 0 {
+		m.users[i].HashedPassword = arg.HashedPassword
+		m.users[i].UpdatedAt = now()
+	}
+	return nil
+}
+
+func (m *MemoryStore) SetPendingEmail(ctx context.Context, arg SetPendingEmailParams) (User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.userIndex(arg.ID)
+	if i < 0 {
+		return User{}, sql.ErrNoRows
+	}
+	m.users[i].PendingEmail = arg.PendingEmail
+	m.users[i].UpdatedAt = now()
+	return m.users[i], nil
+}
+
+func (m *MemoryStore) VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.userIndex(arg.ID)
+	if i < 0 || m.users[i].Email != arg.Email {
+		return User{}, sql.ErrNoRows
+	}
+	t := now()
+	m.users[i].VerifiedAt = sql.NullTime{Time: t, Valid: true}
+	m.users[i].UpdatedAt = t
+	return m.users[i], nil
+}
+
+func (m *MemoryStore) ApplyPendingEmail(ctx context.Context, arg ApplyPendingEmailParams) (User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.userIndex(arg.ID)
+	if i < 0 || !arg.PendingEmail.Valid || m.users[i].PendingEmail != arg.PendingEmail {
+		return User{}, sql.ErrNoRows
+	}
+	email := arg.PendingEmail.String
+	if slices.ContainsFunc(m.users, func(u User) bool { return u.Email == email && u.ID != arg.ID }) {
+		return User{}, errDuplicateEmail
+	}
+	t := now()
+	u := &m.users[i]
+	u.Email = email
+	u.PendingEmail = sql.NullString{}
+	u.VerifiedAt = sql.NullTime{Time: t, Valid: true}
+	u.UpdatedAt = t
+	return *u, nil
+}
+
+func (m *MemoryStore) StartTOTPEnrollment(ctx context.Context, arg StartTOTPEnrollmentParams) (User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.userIndex(arg.ID)
+	if i < 0 || m.users[i].TotpEnabledAt.Valid {
+		return User{}, sql.ErrNoRows
+	}
+	u := &m.users[i]
+	u.TotpSecret = arg.TotpSecret
+	u.TotpLastStep = sql.NullInt64{}
+	u.UpdatedAt = now()
+	return *u, nil
+}
+
+func (m *MemoryStore) EnableTOTP(ctx context.Context, arg EnableTOTPParams) (User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.userIndex(arg.ID)
+	if i < 0 || !m.users[i].TotpSecret.Valid || m.users[i].TotpEnabledAt.Valid {
+		return User{}, sql.ErrNoRows
+	}
+	t := now()
+	u := &m.users[i]
+	u.TotpEnabledAt = sql.NullTime{Time: t, Valid: true}
+	u.TotpLastStep = arg.TotpLastStep
+	u.UpdatedAt = t
+	return *u, nil
+}
+
+func (m *MemoryStore) DisableTOTP(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.userIndex(id)
+	if i >= 0 {
+		u := &m.users[i]
+		u.TotpSecret = sql.NullString{}
+		u.TotpEnabledAt = sql.NullTime{}
+		u.TotpLastStep = sql.NullInt64{}
+		u.UpdatedAt = now()
+	}
+	return nil
+}
+
+func (m *MemoryStore) UseTOTPStep(ctx context.Context, arg UseTOTPStepParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.userIndex(arg.ID)
+	if i < 0 || !m.users[i].TotpEnabledAt.Valid {
+		return 0, nil
+	}
+	last := m.users[i].TotpLastStep
+	if last.Valid && last.Int64 >= arg.TotpLastStep.Int64 {
+		return 0, nil
+	}
+	m.users[i].TotpLastStep = arg.TotpLastStep
+	return 1, nil
+}
+
+func (m *MemoryStore) RecordMFAFailure(ctx context.Context, arg RecordMFAFailureParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.userIndex(arg.ID)
+	if i < 0 {
+		return nil
+	}
+	m.users[i].MfaFailedAttempts++
+	if m.users[i].MfaFailedAttempts >= arg.MaxFailures {
+		m.users[i].MfaLockedUntil = sql.NullTime{Time: arg.LockedUntil, Valid: true}
+	}
+	return nil
+}
+
+func (m *MemoryStore) ResetMFAFailures(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.userIndex(id)
+	if i < 0 {
+		return nil
+	}
+	m.users[i].MfaFailedAttempts = 0
+	m.users[i].MfaLockedUntil = sql.NullTime{}
+	return nil
+}
@@ -0,0 +1,142 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: mfa.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createMFAChallenge = `-- name: CreateMFAChallenge :one
+INSERT INTO mfa_challenges (token_hash, user_id, created_at, expires_at)
+VALUES ($1, $2, NOW(), $3)
+RETURNING token_hash, user_id, created_at, expires_at, failed_attempts, used_at
+`
+
+type CreateMFAChallengeParams struct {
+	TokenHash string
+	UserID    uuid.UUID
+	ExpiresAt time.Time
+}
+
+func (q *Queries) CreateMFAChallenge(ctx context.Context, arg CreateMFAChallengeParams) (MfaChallenge, error) {
+	row := q.db.QueryRowContext(ctx, createMFAChallenge, arg.TokenHash, arg.UserID, arg.ExpiresAt)
+	var i MfaChallenge
+	err := row.Scan(
+		&i.TokenHash,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.FailedAttempts,
+		&i.UsedAt,
+	)
+	return i, err
+}
+
+const createRecoveryCode = `-- name: CreateRecoveryCode :exec
+INSERT INTO mfa_recovery_codes (code_hash, user_id, created_at)
+VALUES ($1, $2, NOW())
+`
+
+type CreateRecoveryCodeParams struct {
+	CodeHash string
+	UserID   uuid.UUID
+}
+
+func (q *Queries) CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) error {
+	_, err := q.db.ExecContext(ctx, createRecoveryCode, arg.CodeHash, arg.UserID)
+	return err
+}
+
+const deleteUserRecoveryCodes = `-- name: DeleteUserRecoveryCodes :exec
+DELETE FROM mfa_recovery_codes
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteUserRecoveryCodes(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteUserRecoveryCodes, userID)
+	return err
+}
+
+const getMFAChallenge = `-- name: GetMFAChallenge :one
+SELECT token_hash, user_id, created_at, expires_at, failed_attempts, used_at FROM mfa_challenges
+WHERE token_hash = $1
+AND used_at IS NULL
+AND expires_at > NOW()
+AND failed_attempts < $2
+`
+
+type GetMFAChallengeParams struct {
+	TokenHash      string
+	FailedAttempts int32
+}
+
+// Returns a challenge that is unused, unexpired and has had fewer than
+// the given number of wrong codes.
+func (q *Queries) GetMFAChallenge(ctx context.Context, arg GetMFAChallengeParams) (MfaChallenge, error) {
+	row := q.db.QueryRowContext(ctx, getMFAChallenge, arg.TokenHash, arg.FailedAttempts)
+	var i MfaChallenge
+	err := row.Scan(
+		&i.TokenHash,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+		&i.FailedAttempts,
+		&i.UsedAt,
+	)
+	return i, err
+}
+
+const recordMFAChallengeFailure = `-- name: RecordMFAChallengeFailure :exec
+UPDATE mfa_challenges
+SET failed_attempts = failed_attempts + 1
+WHERE token_hash = $1
+`
+
+func (q *Queries) RecordMFAChallengeFailure(ctx context.Context, tokenHash string) error {
+	_, err := q.db.ExecContext(ctx, recordMFAChallengeFailure, tokenHash)
+	return err
+}
+
+const useMFAChallenge = `-- name: UseMFAChallenge :execrows
+UPDATE mfa_challenges
+SET used_at = NOW()
+WHERE token_hash = $1
+AND used_at IS NULL
+`
+
+func (q *Queries) UseMFAChallenge(ctx context.Context, tokenHash string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, useMFAChallenge, tokenHash)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const useRecoveryCode = `-- name: UseRecoveryCode :execrows
+UPDATE mfa_recovery_codes
+SET used_at = NOW()
+WHERE user_id = $1
+AND code_hash = $2
+AND used_at IS NULL
+`
+
+type UseRecoveryCodeParams struct {
+	UserID   uuid.UUID
+	CodeHash string
+}
+
+// Marks one of the user's unused codes used. A code can only be used
+// once, even by concurrent requests.
+func (q *Queries) UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, useRecoveryCode, arg.UserID, arg.CodeHash)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
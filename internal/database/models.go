@@ -12,27 +12,169 @@ import (
 )
 
 type Chirp struct {
-	ID        uuid.UUID
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Body         string
+	UserID       uuid.UUID
+	SearchVector string
+	InReplyTo    uuid.NullUUID
+	TombstonedAt sql.NullTime
+	RechirpOf    uuid.NullUUID
+	QuoteOf      uuid.NullUUID
+	DeletedAt    sql.NullTime
+	HeldAt       sql.NullTime
+	HoldReason   string
+}
+
+type ChirpMedia struct {
+	ChirpID  uuid.UUID
+	MediaID  uuid.UUID
+	Position int32
+	AltText  string
+}
+
+type ChirpMention struct {
+	ChirpID     uuid.UUID
+	UserID      uuid.UUID
+	StartOffset int32
+	EndOffset   int32
+}
+
+type ChirpReaction struct {
+	ChirpID   uuid.UUID
+	UserID    uuid.UUID
+	Emoji     string
 	CreatedAt time.Time
-	UpdatedAt time.Time
-	Body      string
+}
+
+type ChirpRevision struct {
+	ID         uuid.UUID
+	ChirpID    uuid.UUID
+	Body       string
+	CreatedAt  time.Time
+	ReplacedAt time.Time
+}
+
+type ChirpTag struct {
+	ChirpID     uuid.UUID
+	Tag         string
+	StartOffset int32
+	EndOffset   int32
+}
+
+type EmailVerificationToken struct {
+	TokenHash string
 	UserID    uuid.UUID
+	Email     string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
 }
 
-type RefreshToken struct {
-	Token     string
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type Media struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UserID      uuid.UUID
+	ContentType string
+	SizeBytes   int64
+}
+
+type MfaChallenge struct {
+	TokenHash      string
+	UserID         uuid.UUID
+	CreatedAt      time.Time
+	ExpiresAt      time.Time
+	FailedAttempts int32
+	UsedAt         sql.NullTime
+}
+
+type MfaRecoveryCode struct {
+	CodeHash  string
+	UserID    uuid.UUID
+	CreatedAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type ModerationRule struct {
+	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	Kind      string
+	Patterns  []string
+	Action    string
+	Reason    string
+	Enabled   bool
+}
+
+type PasswordResetToken struct {
+	TokenHash string
 	UserID    uuid.UUID
+	CreatedAt time.Time
 	ExpiresAt time.Time
-	RevokedAt sql.NullTime
+	UsedAt    sql.NullTime
+}
+
+type RefreshToken struct {
+	TokenHash  string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	ExpiresAt  time.Time
+	RevokedAt  sql.NullTime
+	FamilyID   uuid.UUID
+	ReplacedBy sql.NullString
+	UserAgent  string
+	IpAddress  string
+	LastUsedAt time.Time
+}
+
+type Report struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	ReporterID   uuid.NullUUID
+	ChirpID      uuid.NullUUID
+	UserID       uuid.UUID
+	Category     string
+	Details      string
+	ResolutionID uuid.NullUUID
+}
+
+type ReportResolution struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	Action     string
+	Note       string
+	ChirpID    uuid.NullUUID
+	UserID     uuid.UUID
+	ResolvedBy uuid.NullUUID
 }
 
 type User struct {
-	ID             uuid.UUID
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
-	Email          string
-	HashedPassword string
-	IsChirpyRed    sql.NullBool
+	ID                    uuid.UUID
+	CreatedAt             time.Time
+	UpdatedAt             time.Time
+	Email                 string
+	HashedPassword        string
+	IsChirpyRed           sql.NullBool
+	Handle                sql.NullString
+	SuspendedAt           sql.NullTime
+	SuspensionReason      string
+	Role                  string
+	SuspendedUntil        sql.NullTime
+	SuspensionHidesChirps bool
+	TokensValidAfter      sql.NullTime
+	VerifiedAt            sql.NullTime
+	PendingEmail          sql.NullString
+	TotpSecret            sql.NullString
+	TotpEnabledAt         sql.NullTime
+	TotpLastStep          sql.NullInt64
+	MfaFailedAttempts     int32
+	MfaLockedUntil        sql.NullTime
 }
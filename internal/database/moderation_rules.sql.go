@@ -0,0 +1,167 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: moderation_rules.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const createModerationRule = `-- name: CreateModerationRule :one
+INSERT INTO moderation_rules (id, created_at, updated_at, kind, patterns, action, reason, enabled)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+RETURNING id, created_at, updated_at, kind, patterns, action, reason, enabled
+`
+
+type CreateModerationRuleParams struct {
+	Kind     string
+	Patterns []string
+	Action   string
+	Reason   string
+	Enabled  bool
+}
+
+func (q *Queries) CreateModerationRule(ctx context.Context, arg CreateModerationRuleParams) (ModerationRule, error) {
+	row := q.db.QueryRowContext(ctx, createModerationRule,
+		arg.Kind,
+		pq.Array(arg.Patterns),
+		arg.Action,
+		arg.Reason,
+		arg.Enabled,
+	)
+	var i ModerationRule
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Kind,
+		pq.Array(&i.Patterns),
+		&i.Action,
+		&i.Reason,
+		&i.Enabled,
+	)
+	return i, err
+}
+
+const deleteModerationRule = `-- name: DeleteModerationRule :exec
+DELETE FROM moderation_rules
+WHERE id = $1
+`
+
+func (q *Queries) DeleteModerationRule(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteModerationRule, id)
+	return err
+}
+
+const getModerationRule = `-- name: GetModerationRule :one
+SELECT id, created_at, updated_at, kind, patterns, action, reason, enabled
+FROM moderation_rules
+WHERE id = $1
+`
+
+func (q *Queries) GetModerationRule(ctx context.Context, id uuid.UUID) (ModerationRule, error) {
+	row := q.db.QueryRowContext(ctx, getModerationRule, id)
+	var i ModerationRule
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Kind,
+		pq.Array(&i.Patterns),
+		&i.Action,
+		&i.Reason,
+		&i.Enabled,
+	)
+	return i, err
+}
+
+const listModerationRules = `-- name: ListModerationRules :many
+SELECT id, created_at, updated_at, kind, patterns, action, reason, enabled
+FROM moderation_rules
+ORDER BY created_at, id
+`
+
+func (q *Queries) ListModerationRules(ctx context.Context) ([]ModerationRule, error) {
+	rows, err := q.db.QueryContext(ctx, listModerationRules)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ModerationRule
+	for rows.Next() {
+		var i ModerationRule
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Kind,
+			pq.Array(&i.Patterns),
+			&i.Action,
+			&i.Reason,
+			&i.Enabled,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateModerationRule = `-- name: UpdateModerationRule :one
+UPDATE moderation_rules
+SET kind = $2, patterns = $3, action = $4, reason = $5, enabled = $6, updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, kind, patterns, action, reason, enabled
+`
+
+type UpdateModerationRuleParams struct {
+	ID       uuid.UUID
+	Kind     string
+	Patterns []string
+	Action   string
+	Reason   string
+	Enabled  bool
+}
+
+func (q *Queries) UpdateModerationRule(ctx context.Context, arg UpdateModerationRuleParams) (ModerationRule, error) {
+	row := q.db.QueryRowContext(ctx, updateModerationRule,
+		arg.ID,
+		arg.Kind,
+		pq.Array(arg.Patterns),
+		arg.Action,
+		arg.Reason,
+		arg.Enabled,
+	)
+	var i ModerationRule
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Kind,
+		pq.Array(&i.Patterns),
+		&i.Action,
+		&i.Reason,
+		&i.Enabled,
+	)
+	return i, err
+}
@@ -0,0 +1,180 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+type Querier interface {
+	AddChirpMention(ctx context.Context, arg AddChirpMentionParams) error
+	AddChirpTag(ctx context.Context, arg AddChirpTagParams) error
+	AddReaction(ctx context.Context, arg AddReactionParams) error
+	// Replaces the account's email with the pending one the token was sent
+	// to, which is verified by that.
+	ApplyPendingEmail(ctx context.Context, arg ApplyPendingEmailParams) (User, error)
+	AttachChirpMedia(ctx context.Context, arg AttachChirpMediaParams) error
+	ChirpHasReplies(ctx context.Context, id uuid.UUID) (bool, error)
+	CountAdmins(ctx context.Context) (int64, error)
+	// A held chirp stays hidden until a moderator reviews it.
+	CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error)
+	// Keeps a chirp's current body before an edit replaces it.
+	CreateChirpRevision(ctx context.Context, id uuid.UUID) error
+	CreateEmailVerificationToken(ctx context.Context, arg CreateEmailVerificationTokenParams) (EmailVerificationToken, error)
+	CreateMFAChallenge(ctx context.Context, arg CreateMFAChallengeParams) (MfaChallenge, error)
+	CreateMedia(ctx context.Context, arg CreateMediaParams) (Media, error)
+	CreateModerationRule(ctx context.Context, arg CreateModerationRuleParams) (ModerationRule, error)
+	CreatePasswordResetToken(ctx context.Context, arg CreatePasswordResetTokenParams) (PasswordResetToken, error)
+	CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) error
+	CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (RefreshToken, error)
+	CreateReport(ctx context.Context, arg CreateReportParams) (Report, error)
+	CreateReportResolution(ctx context.Context, arg CreateReportResolutionParams) (ReportResolution, error)
+	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	DeleteChirpByID(ctx context.Context, id uuid.UUID) error
+	// Clears a chirp's tags and mentions so an edited body can be parsed
+	// again.
+	DeleteChirpEntities(ctx context.Context, chirpID uuid.UUID) error
+	DeleteChirpRevisions(ctx context.Context, chirpID uuid.UUID) error
+	DeleteModerationRule(ctx context.Context, id uuid.UUID) error
+	DeleteReactionsByChirpID(ctx context.Context, chirpID uuid.UUID) error
+	DeleteRechirpsOf(ctx context.Context, id uuid.UUID) error
+	DeleteUserRecoveryCodes(ctx context.Context, userID uuid.UUID) error
+	// Removes a chirp's attachments, leaving the uploads themselves.
+	DetachChirpMedia(ctx context.Context, chirpID uuid.UUID) error
+	DetachQuotesOf(ctx context.Context, id uuid.UUID) error
+	DisableTOTP(ctx context.Context, id uuid.UUID) error
+	EnableTOTP(ctx context.Context, arg EnableTOTPParams) (User, error)
+	ExpireUserPasswordResetTokens(ctx context.Context, userID uuid.UUID) error
+	FollowUser(ctx context.Context, arg FollowUserParams) error
+	// The reply chain above a chirp, root first, tombstones and trashed
+	// chirps included, with author_hidden as in GetThreadChirpByID.
+	GetChirpAncestors(ctx context.Context, id uuid.UUID) ([]GetChirpAncestorsRow, error)
+	GetChirpByID(ctx context.Context, id uuid.UUID) (Chirp, error)
+	// Replies below a chirp, breadth first, at most max_depth levels deep
+	// and row_limit rows in total. Tombstones and trashed chirps are
+	// included, with author_hidden as in GetThreadChirpByID.
+	GetChirpDescendants(ctx context.Context, arg GetChirpDescendantsParams) ([]GetChirpDescendantsRow, error)
+	GetChirpMedia(ctx context.Context, chirpIds []uuid.UUID) ([]GetChirpMediaRow, error)
+	GetChirpMentions(ctx context.Context, chirpIds []uuid.UUID) ([]ChirpMention, error)
+	GetChirpRevisions(ctx context.Context, chirpID uuid.UUID) ([]ChirpRevision, error)
+	GetChirpTags(ctx context.Context, chirpIds []uuid.UUID) ([]ChirpTag, error)
+	GetChirps(ctx context.Context) ([]Chirp, error)
+	GetChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]Chirp, error)
+	GetChirpsByUserID(ctx context.Context, userID uuid.UUID) ([]Chirp, error)
+	// Returns a challenge that is unused, unexpired and has had fewer than
+	// the given number of wrong codes.
+	GetMFAChallenge(ctx context.Context, arg GetMFAChallengeParams) (MfaChallenge, error)
+	GetMediaByID(ctx context.Context, id uuid.UUID) (Media, error)
+	GetModerationRule(ctx context.Context, id uuid.UUID) (ModerationRule, error)
+	// Per-emoji totals for each chirp, and whether viewer_id is among the
+	// reactors. viewer_id may be NULL for anonymous callers.
+	GetReactionCounts(ctx context.Context, arg GetReactionCountsParams) ([]GetReactionCountsRow, error)
+	GetRechirp(ctx context.Context, arg GetRechirpParams) (Chirp, error)
+	GetRefreshToken(ctx context.Context, tokenHash string) (RefreshToken, error)
+	GetReport(ctx context.Context, id uuid.UUID) (Report, error)
+	// How many times each chirp has been rechirped and quoted. Chirps that
+	// were never shared are left out.
+	GetShareCounts(ctx context.Context, chirpIds []uuid.UUID) ([]GetShareCountsRow, error)
+	// Like GetChirpByID, but tombstones and trashed chirps are returned too.
+	// author_hidden is set while the author serves a suspension that hides
+	// their chirps.
+	GetThreadChirpByID(ctx context.Context, id uuid.UUID) (GetThreadChirpByIDRow, error)
+	// Chirps by the user and everyone they follow, newest first.
+	GetTimeline(ctx context.Context, arg GetTimelineParams) ([]Chirp, error)
+	GetTokensValidAfter(ctx context.Context, id uuid.UUID) (sql.NullTime, error)
+	GetTrashedChirp(ctx context.Context, id uuid.UUID) (Chirp, error)
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+	GetUserByID(ctx context.Context, id uuid.UUID) (User, error)
+	GetUserFromRefreshToken(ctx context.Context, tokenHash string) (User, error)
+	GetUsersByHandles(ctx context.Context, handles []string) ([]User, error)
+	// A session is signed out once one of its tokens is revoked without
+	// being replaced; rotation revokes tokens too, but always replaces them.
+	IsSessionRevoked(ctx context.Context, familyID uuid.UUID) (bool, error)
+	LiftSuspension(ctx context.Context, id uuid.UUID) (User, error)
+	ListChirpsAsc(ctx context.Context, arg ListChirpsAscParams) ([]Chirp, error)
+	ListChirpsByTag(ctx context.Context, arg ListChirpsByTagParams) ([]Chirp, error)
+	ListChirpsDesc(ctx context.Context, arg ListChirpsDescParams) ([]Chirp, error)
+	// Trashed chirps deleted at or before cutoff, oldest first.
+	ListExpiredTrash(ctx context.Context, arg ListExpiredTrashParams) ([]uuid.UUID, error)
+	ListFollowers(ctx context.Context, arg ListFollowersParams) ([]Follow, error)
+	ListFollowing(ctx context.Context, arg ListFollowingParams) ([]Follow, error)
+	// Chirps mentioning the user, newest first.
+	ListMentions(ctx context.Context, arg ListMentionsParams) ([]Chirp, error)
+	ListModerationRules(ctx context.Context) ([]ModerationRule, error)
+	// Open reports grouped by what they report, longest waiting first. A
+	// group is keyset paginated on its first report.
+	ListOpenReportGroups(ctx context.Context, arg ListOpenReportGroupsParams) ([]ListOpenReportGroupsRow, error)
+	ListOpenReportsByUsers(ctx context.Context, userIds []uuid.UUID) ([]Report, error)
+	// A user's trashed chirps that are not yet due for purging, most
+	// recently deleted first.
+	ListTrash(ctx context.Context, arg ListTrashParams) ([]Chirp, error)
+	// Each live token is the head of one session; signed_in_at is when
+	// its family's first token was issued.
+	ListUserSessions(ctx context.Context, userID uuid.UUID) ([]ListUserSessionsRow, error)
+	RecordMFAChallengeFailure(ctx context.Context, tokenHash string) error
+	// Counts a wrong second factor. Once the count reaches max_failures,
+	// each wrong one locks the user's second factor until locked_until.
+	RecordMFAFailure(ctx context.Context, arg RecordMFAFailureParams) error
+	ReleaseChirpHold(ctx context.Context, id uuid.UUID) error
+	RemoveReaction(ctx context.Context, arg RemoveReactionParams) error
+	Reset(ctx context.Context) error
+	ResetMFAFailures(ctx context.Context, id uuid.UUID) error
+	// Closes every open report in a group.
+	ResolveReports(ctx context.Context, arg ResolveReportsParams) error
+	// Takes a chirp, and the rechirps trashed along with it, out of the
+	// trash.
+	RestoreChirp(ctx context.Context, id uuid.UUID) error
+	RevokeOtherUserSessions(ctx context.Context, arg RevokeOtherUserSessionsParams) ([]uuid.UUID, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error
+	RevokeToken(ctx context.Context, tokenHash string) (RefreshToken, error)
+	// The cutoff comes from the server's clock, which also stamps the
+	// tokens it is compared with.
+	RevokeUserAccessTokens(ctx context.Context, arg RevokeUserAccessTokensParams) error
+	RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error
+	RevokeUserSession(ctx context.Context, arg RevokeUserSessionParams) (int64, error)
+	// Only a live token can be rotated, so of two concurrent refreshes
+	// with the same token exactly one wins.
+	RotateRefreshToken(ctx context.Context, arg RotateRefreshTokenParams) (RefreshToken, error)
+	// Ranked full-text search, best match first. The body is HTML escaped
+	// before ts_headline so the highlight is safe to render as markup.
+	SearchChirps(ctx context.Context, arg SearchChirpsParams) ([]SearchChirpsRow, error)
+	SetPendingEmail(ctx context.Context, arg SetPendingEmailParams) (User, error)
+	SetUserRole(ctx context.Context, arg SetUserRoleParams) (User, error)
+	// Moves a chirp to its author's trash. Its rechirps are hidden with it,
+	// sharing its deleted_at so they come back when it is restored.
+	SoftDeleteChirp(ctx context.Context, id uuid.UUID) error
+	// Stores a new secret for a user who hasn't enabled TOTP yet, replacing
+	// the one from an enrollment they didn't finish.
+	StartTOTPEnrollment(ctx context.Context, arg StartTOTPEnrollmentParams) (User, error)
+	// Suspends a user until suspended_until, or for good when it is NULL.
+	SuspendUser(ctx context.Context, arg SuspendUserParams) (User, error)
+	TombstoneChirp(ctx context.Context, id uuid.UUID) error
+	UnfollowUser(ctx context.Context, arg UnfollowUserParams) error
+	// An edit can put a chirp on hold, but never releases one.
+	UpdateChirpBody(ctx context.Context, arg UpdateChirpBodyParams) (Chirp, error)
+	UpdateLoginDetailsByID(ctx context.Context, arg UpdateLoginDetailsByIDParams) (User, error)
+	UpdateModerationRule(ctx context.Context, arg UpdateModerationRuleParams) (ModerationRule, error)
+	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error
+	UpgradeChirpyRedByID(ctx context.Context, id uuid.UUID) (User, error)
+	UseEmailVerificationToken(ctx context.Context, tokenHash string) (EmailVerificationToken, error)
+	UseMFAChallenge(ctx context.Context, tokenHash string) (int64, error)
+	// Marks a live token used and returns it. A token can only be used
+	// once, even by concurrent requests.
+	UsePasswordResetToken(ctx context.Context, tokenHash string) (PasswordResetToken, error)
+	// Marks one of the user's unused codes used. A code can only be used
+	// once, even by concurrent requests.
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (int64, error)
+	// Records the time step of an accepted code. Nothing is updated if a
+	// code from that step or a later one was already used.
+	UseTOTPStep(ctx context.Context, arg UseTOTPStepParams) (int64, error)
+	// Verifies the account's current email, if it is still the one the
+	// token was sent to.
+	VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (User, error)
+}
+
+var _ Querier = (*Queries)(nil)
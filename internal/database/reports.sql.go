@@ -0,0 +1,254 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: reports.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const createReport = `-- name: CreateReport :one
+INSERT INTO reports (id, created_at, reporter_id, chirp_id, user_id, category, details)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+RETURNING id, created_at, reporter_id, chirp_id, user_id, category, details, resolution_id
+`
+
+type CreateReportParams struct {
+	ReporterID uuid.NullUUID
+	ChirpID    uuid.NullUUID
+	UserID     uuid.UUID
+	Category   string
+	Details    string
+}
+
+func (q *Queries) CreateReport(ctx context.Context, arg CreateReportParams) (Report, error) {
+	row := q.db.QueryRowContext(ctx, createReport,
+		arg.ReporterID,
+		arg.ChirpID,
+		arg.UserID,
+		arg.Category,
+		arg.Details,
+	)
+	var i Report
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.ReporterID,
+		&i.ChirpID,
+		&i.UserID,
+		&i.Category,
+		&i.Details,
+		&i.ResolutionID,
+	)
+	return i, err
+}
+
+const createReportResolution = `-- name: CreateReportResolution :one
+INSERT INTO report_resolutions (id, created_at, action, note, chirp_id, user_id, resolved_by)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+RETURNING id, created_at, action, note, chirp_id, user_id, resolved_by
+`
+
+type CreateReportResolutionParams struct {
+	Action     string
+	Note       string
+	ChirpID    uuid.NullUUID
+	UserID     uuid.UUID
+	ResolvedBy uuid.NullUUID
+}
+
+func (q *Queries) CreateReportResolution(ctx context.Context, arg CreateReportResolutionParams) (ReportResolution, error) {
+	row := q.db.QueryRowContext(ctx, createReportResolution,
+		arg.Action,
+		arg.Note,
+		arg.ChirpID,
+		arg.UserID,
+		arg.ResolvedBy,
+	)
+	var i ReportResolution
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.Action,
+		&i.Note,
+		&i.ChirpID,
+		&i.UserID,
+		&i.ResolvedBy,
+	)
+	return i, err
+}
+
+const getReport = `-- name: GetReport :one
+SELECT id, created_at, reporter_id, chirp_id, user_id, category, details, resolution_id
+FROM reports
+WHERE id = $1
+`
+
+func (q *Queries) GetReport(ctx context.Context, id uuid.UUID) (Report, error) {
+	row := q.db.QueryRowContext(ctx, getReport, id)
+	var i Report
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.ReporterID,
+		&i.ChirpID,
+		&i.UserID,
+		&i.Category,
+		&i.Details,
+		&i.ResolutionID,
+	)
+	return i, err
+}
+
+const listOpenReportGroups = `-- name: ListOpenReportGroups :many
+SELECT
+    reports.user_id,
+    reports.chirp_id,
+    COUNT(*)::int AS report_count,
+    COUNT(DISTINCT reports.reporter_id)::int AS reporter_count,
+    MIN(reports.created_at)::timestamp AS first_reported_at,
+    MAX(reports.created_at)::timestamp AS last_reported_at,
+    (array_agg(reports.id ORDER BY reports.created_at, reports.id))[1]::uuid AS first_report_id
+FROM reports
+WHERE reports.resolution_id IS NULL
+GROUP BY reports.user_id, reports.chirp_id
+HAVING $2::timestamp IS NULL
+OR (
+    MIN(reports.created_at),
+    (array_agg(reports.id ORDER BY reports.created_at, reports.id))[1]
+) > ($2::timestamp, $3::uuid)
+ORDER BY first_reported_at, first_report_id
+LIMIT $1
+`
+
+type ListOpenReportGroupsParams struct {
+	Limit           int32
+	AfterReportedAt sql.NullTime
+	AfterID         uuid.NullUUID
+}
+
+type ListOpenReportGroupsRow struct {
+	UserID          uuid.UUID
+	ChirpID         uuid.NullUUID
+	ReportCount     int32
+	ReporterCount   int32
+	FirstReportedAt time.Time
+	LastReportedAt  time.Time
+	FirstReportID   uuid.UUID
+}
+
+// Open reports grouped by what they report, longest waiting first. A
+// group is keyset paginated on its first report.
+func (q *Queries) ListOpenReportGroups(ctx context.Context, arg ListOpenReportGroupsParams) ([]ListOpenReportGroupsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listOpenReportGroups, arg.Limit, arg.AfterReportedAt, arg.AfterID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListOpenReportGroupsRow
+	for rows.Next() {
+		var i ListOpenReportGroupsRow
+		if err := rows.Scan(
+			&i.UserID,
+			&i.ChirpID,
+			&i.ReportCount,
+			&i.ReporterCount,
+			&i.FirstReportedAt,
+			&i.LastReportedAt,
+			&i.FirstReportID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOpenReportsByUsers = `-- name: ListOpenReportsByUsers :many
+SELECT id, created_at, reporter_id, chirp_id, user_id, category, details, resolution_id
+FROM reports
+WHERE resolution_id IS NULL
+AND user_id = ANY($1::uuid[])
+ORDER BY created_at, id
+`
+
+func (q *Queries) ListOpenReportsByUsers(ctx context.Context, userIds []uuid.UUID) ([]Report, error) {
+	rows, err := q.db.QueryContext(ctx, listOpenReportsByUsers, pq.Array(userIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Report
+	for rows.Next() {
+		var i Report
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.ReporterID,
+			&i.ChirpID,
+			&i.UserID,
+			&i.Category,
+			&i.Details,
+			&i.ResolutionID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const resolveReports = `-- name: ResolveReports :exec
+UPDATE reports
+SET resolution_id = $1::uuid
+WHERE resolution_id IS NULL
+AND user_id = $2
+AND chirp_id IS NOT DISTINCT FROM $3::uuid
+`
+
+type ResolveReportsParams struct {
+	ResolutionID uuid.UUID
+	UserID       uuid.UUID
+	ChirpID      uuid.NullUUID
+}
+
+// Closes every open report in a group.
+func (q *Queries) ResolveReports(ctx context.Context, arg ResolveReportsParams) error {
+	_, err := q.db.ExecContext(ctx, resolveReports, arg.ResolutionID, arg.UserID, arg.ChirpID)
+	return err
+}
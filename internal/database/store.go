@@ -0,0 +1,42 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Store is the persistence surface the HTTP handlers depend on.
+// The sqlc generated *Queries implements it against Postgres, while
+// MemoryStore implements it in-process so handlers can be tested
+// without a live database.
+type Store interface {
+	Querier
+	// InTx runs fn with a Store whose queries all happen in one
+	// transaction, which commits if fn returns nil and rolls back if
+	// it returns an error.
+	InTx(ctx context.Context, fn func(Store) error) error
+}
+
+// InTx begins a transaction, unless q already runs in one, in which
+// case fn joins it.
+func (q *Queries) InTx(ctx context.Context, fn func(Store) error) error {
+	db, ok := q.db.(*sql.DB)
+	if !ok {
+		return fn(q)
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	err = fn(q.WithTx(tx))
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+var (
+	_ Store = (*Queries)(nil)
+	_ Store = (*MemoryStore)(nil)
+)
@@ -7,29 +7,90 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const applyPendingEmail = `-- name: ApplyPendingEmail :one
+UPDATE users
+SET email = pending_email, pending_email = NULL, verified_at = NOW(), updated_at = NOW()
+WHERE id = $1
+AND pending_email = $2
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, suspended_at, suspension_reason, role, suspended_until, suspension_hides_chirps, tokens_valid_after, verified_at, pending_email, totp_secret, totp_enabled_at, totp_last_step, mfa_failed_attempts, mfa_locked_until
+`
+
+type ApplyPendingEmailParams struct {
+	ID           uuid.UUID
+	PendingEmail sql.NullString
+}
+
+// Replaces the account's email with the pending one the token was sent
+// to, which is verified by that.
+func (q *Queries) ApplyPendingEmail(ctx context.Context, arg ApplyPendingEmailParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, applyPendingEmail, arg.ID, arg.PendingEmail)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Handle,
+		&i.SuspendedAt,
+		&i.SuspensionReason,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionHidesChirps,
+		&i.TokensValidAfter,
+		&i.VerifiedAt,
+		&i.PendingEmail,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.MfaFailedAttempts,
+		&i.MfaLockedUntil,
+	)
+	return i, err
+}
+
+const countAdmins = `-- name: CountAdmins :one
+SELECT COUNT(*)
+FROM users
+WHERE role = 'admin'
+`
+
+func (q *Queries) CountAdmins(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countAdmins)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3
 )
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, suspended_at, suspension_reason, role, suspended_until, suspension_hides_chirps, tokens_valid_after, verified_at, pending_email, totp_secret, totp_enabled_at, totp_last_step, mfa_failed_attempts, mfa_locked_until
 `
 
 type CreateUserParams struct {
 	Email          string
 	HashedPassword string
+	Handle         sql.NullString
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword)
+	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword, arg.Handle)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -38,12 +99,92 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Handle,
+		&i.SuspendedAt,
+		&i.SuspensionReason,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionHidesChirps,
+		&i.TokensValidAfter,
+		&i.VerifiedAt,
+		&i.PendingEmail,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.MfaFailedAttempts,
+		&i.MfaLockedUntil,
 	)
 	return i, err
 }
 
+const disableTOTP = `-- name: DisableTOTP :exec
+UPDATE users
+SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL, updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) DisableTOTP(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, disableTOTP, id)
+	return err
+}
+
+const enableTOTP = `-- name: EnableTOTP :one
+UPDATE users
+SET totp_enabled_at = NOW(), totp_last_step = $2, updated_at = NOW()
+WHERE id = $1
+AND totp_secret IS NOT NULL
+AND totp_enabled_at IS NULL
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, suspended_at, suspension_reason, role, suspended_until, suspension_hides_chirps, tokens_valid_after, verified_at, pending_email, totp_secret, totp_enabled_at, totp_last_step, mfa_failed_attempts, mfa_locked_until
+`
+
+type EnableTOTPParams struct {
+	ID           uuid.UUID
+	TotpLastStep sql.NullInt64
+}
+
+func (q *Queries) EnableTOTP(ctx context.Context, arg EnableTOTPParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, enableTOTP, arg.ID, arg.TotpLastStep)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Handle,
+		&i.SuspendedAt,
+		&i.SuspensionReason,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionHidesChirps,
+		&i.TokensValidAfter,
+		&i.VerifiedAt,
+		&i.PendingEmail,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.MfaFailedAttempts,
+		&i.MfaLockedUntil,
+	)
+	return i, err
+}
+
+const getTokensValidAfter = `-- name: GetTokensValidAfter :one
+SELECT tokens_valid_after
+FROM users
+WHERE id = $1
+`
+
+func (q *Queries) GetTokensValidAfter(ctx context.Context, id uuid.UUID) (sql.NullTime, error) {
+	row := q.db.QueryRowContext(ctx, getTokensValidAfter, id)
+	var tokens_valid_after sql.NullTime
+	err := row.Scan(&tokens_valid_after)
+	return tokens_valid_after, err
+}
+
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red 
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, suspended_at, suspension_reason, role, suspended_until, suspension_hides_chirps, tokens_valid_after, verified_at, pending_email, totp_secret, totp_enabled_at, totp_last_step, mfa_failed_attempts, mfa_locked_until 
 FROM users
 WHERE email = $1
 `
@@ -58,12 +199,26 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Handle,
+		&i.SuspendedAt,
+		&i.SuspensionReason,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionHidesChirps,
+		&i.TokensValidAfter,
+		&i.VerifiedAt,
+		&i.PendingEmail,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.MfaFailedAttempts,
+		&i.MfaLockedUntil,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, suspended_at, suspension_reason, role, suspended_until, suspension_hides_chirps, tokens_valid_after, verified_at, pending_email, totp_secret, totp_enabled_at, totp_last_step, mfa_failed_attempts, mfa_locked_until
 FROM users
 WHERE id = $1
 `
@@ -78,10 +233,136 @@ func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Handle,
+		&i.SuspendedAt,
+		&i.SuspensionReason,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionHidesChirps,
+		&i.TokensValidAfter,
+		&i.VerifiedAt,
+		&i.PendingEmail,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.MfaFailedAttempts,
+		&i.MfaLockedUntil,
 	)
 	return i, err
 }
 
+const getUsersByHandles = `-- name: GetUsersByHandles :many
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, suspended_at, suspension_reason, role, suspended_until, suspension_hides_chirps, tokens_valid_after, verified_at, pending_email, totp_secret, totp_enabled_at, totp_last_step, mfa_failed_attempts, mfa_locked_until
+FROM users
+WHERE handle = ANY($1::text[])
+`
+
+func (q *Queries) GetUsersByHandles(ctx context.Context, handles []string) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, getUsersByHandles, pq.Array(handles))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.HashedPassword,
+			&i.IsChirpyRed,
+			&i.Handle,
+			&i.SuspendedAt,
+			&i.SuspensionReason,
+			&i.Role,
+			&i.SuspendedUntil,
+			&i.SuspensionHidesChirps,
+			&i.TokensValidAfter,
+			&i.VerifiedAt,
+			&i.PendingEmail,
+			&i.TotpSecret,
+			&i.TotpEnabledAt,
+			&i.TotpLastStep,
+			&i.MfaFailedAttempts,
+			&i.MfaLockedUntil,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const liftSuspension = `-- name: LiftSuspension :one
+UPDATE users
+SET suspended_at = NULL,
+    suspended_until = NULL,
+    suspension_reason = '',
+    suspension_hides_chirps = FALSE,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, suspended_at, suspension_reason, role, suspended_until, suspension_hides_chirps, tokens_valid_after, verified_at, pending_email, totp_secret, totp_enabled_at, totp_last_step, mfa_failed_attempts, mfa_locked_until
+`
+
+func (q *Queries) LiftSuspension(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, liftSuspension, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Handle,
+		&i.SuspendedAt,
+		&i.SuspensionReason,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionHidesChirps,
+		&i.TokensValidAfter,
+		&i.VerifiedAt,
+		&i.PendingEmail,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.MfaFailedAttempts,
+		&i.MfaLockedUntil,
+	)
+	return i, err
+}
+
+const recordMFAFailure = `-- name: RecordMFAFailure :exec
+UPDATE users
+SET mfa_failed_attempts = mfa_failed_attempts + 1,
+    mfa_locked_until = CASE
+        WHEN mfa_failed_attempts + 1 >= $1::integer THEN $2::timestamp
+        ELSE mfa_locked_until
+    END
+WHERE id = $3
+`
+
+type RecordMFAFailureParams struct {
+	MaxFailures int32
+	LockedUntil time.Time
+	ID          uuid.UUID
+}
+
+// Counts a wrong second factor. Once the count reaches max_failures,
+// each wrong one locks the user's second factor until locked_until.
+func (q *Queries) RecordMFAFailure(ctx context.Context, arg RecordMFAFailureParams) error {
+	_, err := q.db.ExecContext(ctx, recordMFAFailure, arg.MaxFailures, arg.LockedUntil, arg.ID)
+	return err
+}
+
 const reset = `-- name: Reset :exec
 DELETE FROM users
 `
@@ -91,21 +372,232 @@ func (q *Queries) Reset(ctx context.Context) error {
 	return err
 }
 
+const resetMFAFailures = `-- name: ResetMFAFailures :exec
+UPDATE users
+SET mfa_failed_attempts = 0, mfa_locked_until = NULL
+WHERE id = $1
+`
+
+func (q *Queries) ResetMFAFailures(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, resetMFAFailures, id)
+	return err
+}
+
+const revokeUserAccessTokens = `-- name: RevokeUserAccessTokens :exec
+UPDATE users
+SET tokens_valid_after = $2
+WHERE id = $1
+`
+
+type RevokeUserAccessTokensParams struct {
+	ID               uuid.UUID
+	TokensValidAfter sql.NullTime
+}
+
+// The cutoff comes from the server's clock, which also stamps the
+// tokens it is compared with.
+func (q *Queries) RevokeUserAccessTokens(ctx context.Context, arg RevokeUserAccessTokensParams) error {
+	_, err := q.db.ExecContext(ctx, revokeUserAccessTokens, arg.ID, arg.TokensValidAfter)
+	return err
+}
+
+const setPendingEmail = `-- name: SetPendingEmail :one
+UPDATE users
+SET pending_email = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, suspended_at, suspension_reason, role, suspended_until, suspension_hides_chirps, tokens_valid_after, verified_at, pending_email, totp_secret, totp_enabled_at, totp_last_step, mfa_failed_attempts, mfa_locked_until
+`
+
+type SetPendingEmailParams struct {
+	ID           uuid.UUID
+	PendingEmail sql.NullString
+}
+
+func (q *Queries) SetPendingEmail(ctx context.Context, arg SetPendingEmailParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setPendingEmail, arg.ID, arg.PendingEmail)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Handle,
+		&i.SuspendedAt,
+		&i.SuspensionReason,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionHidesChirps,
+		&i.TokensValidAfter,
+		&i.VerifiedAt,
+		&i.PendingEmail,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.MfaFailedAttempts,
+		&i.MfaLockedUntil,
+	)
+	return i, err
+}
+
+const setUserRole = `-- name: SetUserRole :one
+UPDATE users
+SET role = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, suspended_at, suspension_reason, role, suspended_until, suspension_hides_chirps, tokens_valid_after, verified_at, pending_email, totp_secret, totp_enabled_at, totp_last_step, mfa_failed_attempts, mfa_locked_until
+`
+
+type SetUserRoleParams struct {
+	ID   uuid.UUID
+	Role string
+}
+
+func (q *Queries) SetUserRole(ctx context.Context, arg SetUserRoleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserRole, arg.ID, arg.Role)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Handle,
+		&i.SuspendedAt,
+		&i.SuspensionReason,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionHidesChirps,
+		&i.TokensValidAfter,
+		&i.VerifiedAt,
+		&i.PendingEmail,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.MfaFailedAttempts,
+		&i.MfaLockedUntil,
+	)
+	return i, err
+}
+
+const startTOTPEnrollment = `-- name: StartTOTPEnrollment :one
+UPDATE users
+SET totp_secret = $2, totp_last_step = NULL, updated_at = NOW()
+WHERE id = $1
+AND totp_enabled_at IS NULL
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, suspended_at, suspension_reason, role, suspended_until, suspension_hides_chirps, tokens_valid_after, verified_at, pending_email, totp_secret, totp_enabled_at, totp_last_step, mfa_failed_attempts, mfa_locked_until
+`
+
+type StartTOTPEnrollmentParams struct {
+	ID         uuid.UUID
+	TotpSecret sql.NullString
+}
+
+// Stores a new secret for a user who hasn't enabled TOTP yet, replacing
+// the one from an enrollment they didn't finish.
+func (q *Queries) StartTOTPEnrollment(ctx context.Context, arg StartTOTPEnrollmentParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, startTOTPEnrollment, arg.ID, arg.TotpSecret)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Handle,
+		&i.SuspendedAt,
+		&i.SuspensionReason,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionHidesChirps,
+		&i.TokensValidAfter,
+		&i.VerifiedAt,
+		&i.PendingEmail,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.MfaFailedAttempts,
+		&i.MfaLockedUntil,
+	)
+	return i, err
+}
+
+const suspendUser = `-- name: SuspendUser :one
+UPDATE users
+SET suspended_at = NOW(),
+    suspended_until = $1,
+    suspension_reason = $2,
+    suspension_hides_chirps = $3,
+    updated_at = NOW()
+WHERE id = $4
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, suspended_at, suspension_reason, role, suspended_until, suspension_hides_chirps, tokens_valid_after, verified_at, pending_email, totp_secret, totp_enabled_at, totp_last_step, mfa_failed_attempts, mfa_locked_until
+`
+
+type SuspendUserParams struct {
+	SuspendedUntil        sql.NullTime
+	SuspensionReason      string
+	SuspensionHidesChirps bool
+	ID                    uuid.UUID
+}
+
+// Suspends a user until suspended_until, or for good when it is NULL.
+func (q *Queries) SuspendUser(ctx context.Context, arg SuspendUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, suspendUser,
+		arg.SuspendedUntil,
+		arg.SuspensionReason,
+		arg.SuspensionHidesChirps,
+		arg.ID,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Handle,
+		&i.SuspendedAt,
+		&i.SuspensionReason,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionHidesChirps,
+		&i.TokensValidAfter,
+		&i.VerifiedAt,
+		&i.PendingEmail,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.MfaFailedAttempts,
+		&i.MfaLockedUntil,
+	)
+	return i, err
+}
+
 const updateLoginDetailsByID = `-- name: UpdateLoginDetailsByID :one
 UPDATE users
-SET hashed_password = $1, email=$2, updated_at = NOW()
-WHERE id = $3
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+SET hashed_password = $1, email = $2,
+    handle = COALESCE($3, handle), updated_at = NOW()
+WHERE id = $4
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, suspended_at, suspension_reason, role, suspended_until, suspension_hides_chirps, tokens_valid_after, verified_at, pending_email, totp_secret, totp_enabled_at, totp_last_step, mfa_failed_attempts, mfa_locked_until
 `
 
 type UpdateLoginDetailsByIDParams struct {
 	HashedPassword string
 	Email          string
+	Handle         sql.NullString
 	ID             uuid.UUID
 }
 
 func (q *Queries) UpdateLoginDetailsByID(ctx context.Context, arg UpdateLoginDetailsByIDParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, updateLoginDetailsByID, arg.HashedPassword, arg.Email, arg.ID)
+	row := q.db.QueryRowContext(ctx, updateLoginDetailsByID,
+		arg.HashedPassword,
+		arg.Email,
+		arg.Handle,
+		arg.ID,
+	)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -114,15 +606,45 @@ func (q *Queries) UpdateLoginDetailsByID(ctx context.Context, arg UpdateLoginDet
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Handle,
+		&i.SuspendedAt,
+		&i.SuspensionReason,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionHidesChirps,
+		&i.TokensValidAfter,
+		&i.VerifiedAt,
+		&i.PendingEmail,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.MfaFailedAttempts,
+		&i.MfaLockedUntil,
 	)
 	return i, err
 }
 
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users
+SET hashed_password = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateUserPasswordParams struct {
+	ID             uuid.UUID
+	HashedPassword string
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.ID, arg.HashedPassword)
+	return err
+}
+
 const upgradeChirpyRedByID = `-- name: UpgradeChirpyRedByID :one
 UPDATE users
 SET is_chirpy_red = TRUE
 WHERE id = $1
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, suspended_at, suspension_reason, role, suspended_until, suspension_hides_chirps, tokens_valid_after, verified_at, pending_email, totp_secret, totp_enabled_at, totp_last_step, mfa_failed_attempts, mfa_locked_until
 `
 
 func (q *Queries) UpgradeChirpyRedByID(ctx context.Context, id uuid.UUID) (User, error) {
@@ -135,6 +657,86 @@ func (q *Queries) UpgradeChirpyRedByID(ctx context.Context, id uuid.UUID) (User,
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Handle,
+		&i.SuspendedAt,
+		&i.SuspensionReason,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionHidesChirps,
+		&i.TokensValidAfter,
+		&i.VerifiedAt,
+		&i.PendingEmail,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.MfaFailedAttempts,
+		&i.MfaLockedUntil,
+	)
+	return i, err
+}
+
+const useTOTPStep = `-- name: UseTOTPStep :execrows
+UPDATE users
+SET totp_last_step = $2
+WHERE id = $1
+AND totp_enabled_at IS NOT NULL
+AND (totp_last_step IS NULL OR totp_last_step < $2)
+`
+
+type UseTOTPStepParams struct {
+	ID           uuid.UUID
+	TotpLastStep sql.NullInt64
+}
+
+// Records the time step of an accepted code. Nothing is updated if a
+// code from that step or a later one was already used.
+func (q *Queries) UseTOTPStep(ctx context.Context, arg UseTOTPStepParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, useTOTPStep, arg.ID, arg.TotpLastStep)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const verifyUserEmail = `-- name: VerifyUserEmail :one
+UPDATE users
+SET verified_at = NOW(), updated_at = NOW()
+WHERE id = $1
+AND email = $2
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, handle, suspended_at, suspension_reason, role, suspended_until, suspension_hides_chirps, tokens_valid_after, verified_at, pending_email, totp_secret, totp_enabled_at, totp_last_step, mfa_failed_attempts, mfa_locked_until
+`
+
+type VerifyUserEmailParams struct {
+	ID    uuid.UUID
+	Email string
+}
+
+// Verifies the account's current email, if it is still the one the
+// token was sent to.
+func (q *Queries) VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, verifyUserEmail, arg.ID, arg.Email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.Handle,
+		&i.SuspendedAt,
+		&i.SuspensionReason,
+		&i.Role,
+		&i.SuspendedUntil,
+		&i.SuspensionHidesChirps,
+		&i.TokensValidAfter,
+		&i.VerifiedAt,
+		&i.PendingEmail,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.MfaFailedAttempts,
+		&i.MfaLockedUntil,
 	)
 	return i, err
 }
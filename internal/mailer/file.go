@@ -0,0 +1,50 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// FileMailer writes each message to its own .eml file in a directory
+// instead of sending it, for development and tests.
+type FileMailer struct {
+	dir  string
+	from string
+	sent atomic.Int64
+}
+
+// NewFileMailer returns a Mailer that writes into dir, creating it if
+// needed.
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+// Dir is the directory messages are written to.
+func (m *FileMailer) Dir() string {
+	return m.dir
+}
+
+// Send writes msg as <time>-<n>.eml, so file names sort in the order
+// messages were sent.
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	now := time.Now()
+	data, err := format(m.from, msg, now)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%06d.eml", now.UTC().Format("20060102T150405.000000000"), m.sent.Add(1))
+	path := filepath.Join(m.dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return err
+	}
+	log.Printf("mail to %s written to %s", msg.To, path)
+	return nil
+}
@@ -0,0 +1,43 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileMailer(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	m, err := NewFileMailer(dir, "Chirpy <noreply@example.com>")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, subject := range []string{"first", "second"} {
+		err := m.Send(ctx, Message{To: "user@example.com", Subject: subject, Body: "line one\nline two"})
+		if err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(paths) != 2 {
+		t.Fatalf("mail files = %v, %v; want 2", paths, err)
+	}
+	data, err := os.ReadFile(paths[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"From: Chirpy <noreply@example.com>\r\n", "To: user@example.com\r\n", "Subject: second\r\n", "\r\n\r\nline one\r\nline two"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("message %q lacks %q", data, want)
+		}
+	}
+
+	err = m.Send(ctx, Message{To: "user@example.com\r\nBcc: victim@example.com", Subject: "hi"})
+	if err == nil {
+		t.Error("Send accepted a recipient with a line break")
+	}
+}
@@ -0,0 +1,89 @@
+package mailer
+
+import (
+	"context"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSMTPServer accepts one message on a local port and sends what it
+// was given on the returned channel: the envelope commands, then the
+// message itself.
+func fakeSMTPServer(t *testing.T) (string, <-chan []string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tp := textproto.NewConn(conn)
+		var got []string
+		tp.PrintfLine("220 localhost ESMTP")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			switch command := strings.ToUpper(strings.Fields(line + " ")[0]); command {
+			case "EHLO", "HELO":
+				tp.PrintfLine("250 localhost")
+			case "MAIL", "RCPT":
+				got = append(got, line)
+				tp.PrintfLine("250 OK")
+			case "DATA":
+				tp.PrintfLine("354 go ahead")
+				data, err := tp.ReadDotBytes()
+				if err != nil {
+					return
+				}
+				got = append(got, string(data))
+				tp.PrintfLine("250 OK")
+			case "QUIT":
+				tp.PrintfLine("221 bye")
+				received <- got
+				return
+			default:
+				tp.PrintfLine("502 not implemented")
+			}
+		}
+	}()
+	return ln.Addr().String(), received
+}
+
+func TestSMTPMailer(t *testing.T) {
+	addr, received := fakeSMTPServer(t)
+	m, err := NewSMTPMailer(addr, "Chirpy <noreply@example.com>", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = m.Send(ctx, Message{To: "user@example.com", Subject: "hi", Body: "hello"})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	got := <-received
+	if len(got) != 3 || got[0] != "MAIL FROM:<noreply@example.com>" || got[1] != "RCPT TO:<user@example.com>" {
+		t.Fatalf("envelope = %q", got)
+	}
+	// the display name only goes in the header
+	if !strings.Contains(got[2], "From: \"Chirpy\" <noreply@example.com>\n") {
+		t.Errorf("message = %q", got[2])
+	}
+}
+
+func TestSMTPMailerRejectsInvalidFrom(t *testing.T) {
+	if _, err := NewSMTPMailer("localhost:25", "Chirpy noreply", "", ""); err == nil {
+		t.Error("an invalid from address was accepted")
+	}
+}
@@ -1,25 +1,54 @@
 package main
 
 import (
+	"context"
 	"database/sql"
-	"fmt"
+	"errors"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"sync"
 	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
+	internal "github.com/natretsel/chirpy/internal/auth"
+	"github.com/natretsel/chirpy/internal/blobstore"
 	"github.com/natretsel/chirpy/internal/database"
+	"github.com/natretsel/chirpy/internal/mailer"
 )
 
+// shutdownTimeout is how long requests in flight get to finish once
+// the server is asked to stop.
+const shutdownTimeout = 30 * time.Second
+
 type apiConfig struct {
 	//atomic.Int32 allows for safe increment across multiple go routines
-	fileserverHits atomic.Int32
-	dbQueries      *database.Queries
-	platform       string
-	secret         string
-	polka_key      string
+	fileserverHits   atomic.Int32
+	dbQueries        database.Store
+	platform         string
+	keys             *internal.Keyring
+	polka_key        string
+	allowedReactions []string
+	// unverifiedActions are the actions users may take before they
+	// verify their email
+	unverifiedActions []string
+	media             blobstore.Store
+	editWindow        time.Duration
+	trashRetention    time.Duration
+	moderation        *moderationRules
+	tokenCutoffs      *tokenCutoffs
+	sessions          *sessionStates
+	mailer            mailer.Mailer
+	// adminEmail is made the first admin once it is verified
+	adminEmail string
+	// background tracks the work handlers leave running after they
+	// respond, which main waits for on shutdown
+	background sync.WaitGroup
 }
 
 func main() {
@@ -32,18 +61,76 @@ func main() {
 	if platform == "" {
 		log.Fatal("PLATFORM environment variable is not set")
 	}
-	secretToken := os.Getenv("SECRET")
-	if secretToken == "" {
-		log.Fatal("SECRET environment variable is not set")
+	keysDir := os.Getenv("JWT_KEYS_DIR")
+	if keysDir == "" {
+		log.Fatal("JWT_KEYS_DIR environment variable is not set")
+	}
+	keys, err := internal.LoadKeyring(keysDir, os.Getenv("JWT_SIGNING_KEY_ID"))
+	if err != nil {
+		log.Fatalf("couldn't load JWT signing keys: %v", err)
 	}
 	polka_key := os.Getenv("POLKA_KEY")
 	if polka_key == "" {
 		log.Fatal("POLKA_KEY environment variable is not set")
 	}
+	allowedReactions := os.Getenv("ALLOWED_REACTIONS")
+	if allowedReactions == "" {
+		allowedReactions = defaultReactions
+	}
+	unverifiedList := os.Getenv("UNVERIFIED_ACTIONS")
+	if unverifiedList == "" {
+		unverifiedList = defaultUnverifiedActions
+	}
+	unverifiedActions, err := parseUnverifiedActions(unverifiedList)
+	if err != nil {
+		log.Fatalf("UNVERIFIED_ACTIONS: %v", err)
+	}
+	mediaDir := os.Getenv("MEDIA_DIR")
+	if mediaDir == "" {
+		mediaDir = "media"
+	}
+	mediaStore, err := blobstore.NewLocalStore(mediaDir)
+	if err != nil {
+		log.Fatalf("couldn't open media directory %v: %v", mediaDir, err)
+	}
+	editWindow := defaultEditWindow
+	if s := os.Getenv("CHIRP_EDIT_WINDOW"); s != "" {
+		editWindow, err = time.ParseDuration(s)
+		if err != nil || editWindow < 0 {
+			log.Fatalf("CHIRP_EDIT_WINDOW must be a duration such as 30m: %v", s)
+		}
+	}
+	trashRetentionDays := defaultTrashRetentionDays
+	if s := os.Getenv("TRASH_RETENTION_DAYS"); s != "" {
+		trashRetentionDays, err = strconv.Atoi(s)
+		if err != nil || trashRetentionDays < 0 {
+			log.Fatalf("TRASH_RETENTION_DAYS must be a whole number of days: %v", s)
+		}
+	}
+	mailFrom := os.Getenv("MAIL_FROM")
+	if mailFrom == "" {
+		mailFrom = "Chirpy <noreply@localhost>"
+	}
+	var mail mailer.Mailer
+	if smtpAddr := os.Getenv("SMTP_ADDR"); smtpAddr != "" {
+		mail, err = mailer.NewSMTPMailer(smtpAddr, mailFrom, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"))
+		if err != nil {
+			log.Fatalf("SMTP_ADDR must be a host:port and MAIL_FROM an email address: %v", err)
+		}
+	} else {
+		mailDir := os.Getenv("MAIL_DIR")
+		if mailDir == "" {
+			mailDir = "mail"
+		}
+		mail, err = mailer.NewFileMailer(mailDir, mailFrom)
+		if err != nil {
+			log.Fatalf("couldn't open mail directory %v: %v", mailDir, err)
+		}
+		log.Printf("SMTP_ADDR is not set; writing emails to %v", mailDir)
+	}
 	db, err := sql.Open("postgres", dbURL)
 	if err != nil {
-		fmt.Printf("error trying to establish connection to db %v :, %v", dbURL, err)
-		os.Exit(1)
+		log.Fatalf("couldn't open database: %v", err)
 	}
 	dbQueries := database.New(db)
 
@@ -51,34 +138,120 @@ func main() {
 	const filepathroot = "."
 
 	apiCfg := &apiConfig{
-		fileserverHits: atomic.Int32{},
-		dbQueries:      dbQueries,
-		platform:       platform,
-		secret:         secretToken,
-		polka_key:      polka_key,
+		fileserverHits:    atomic.Int32{},
+		dbQueries:         dbQueries,
+		platform:          platform,
+		keys:              keys,
+		polka_key:         polka_key,
+		allowedReactions:  parseReactions(allowedReactions),
+		unverifiedActions: unverifiedActions,
+		media:             mediaStore,
+		editWindow:        editWindow,
+		trashRetention:    time.Duration(trashRetentionDays) * 24 * time.Hour,
+		moderation:        &moderationRules{},
+		tokenCutoffs:      newTokenCutoffs(),
+		sessions:          newSessionStates(),
+		mailer:            mail,
+		adminEmail:        os.Getenv("ADMIN_EMAIL"),
+	}
+	if apiCfg.adminEmail != "" {
+		err = bootstrapAdmin(context.Background(), dbQueries, apiCfg.adminEmail)
+		if err != nil {
+			log.Printf("couldn't bootstrap admin: %v", err)
+		}
+	}
+	err = apiCfg.moderation.load(context.Background(), dbQueries)
+	if err != nil {
+		log.Fatalf("couldn't load moderation rules: %v", err)
 	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go apiCfg.runTrashPurger(ctx, trashPurgeInterval)
+	go apiCfg.runModerationRefresher(ctx, moderationRefreshInterval)
 
-	mux := http.NewServeMux()
-	mux.Handle("/app/", apiCfg.middlewareMetricsInc(http.StripPrefix("/app", http.FileServer(http.Dir(filepathroot)))))
-	mux.HandleFunc("GET /api/healthz", handlerReadiness)
-	mux.HandleFunc("GET /admin/metrics", apiCfg.handlerMetrics)
-	mux.HandleFunc("POST /admin/reset", apiCfg.handlerReset)
-	mux.HandleFunc("POST /api/chirps", apiCfg.handlerChirpsCreate)
-	mux.HandleFunc("GET /api/chirps", apiCfg.handlerChirpsGet)
-	mux.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.handlerChirpsGetByID)
-	mux.HandleFunc("POST /api/users", apiCfg.handlerCreateUser)
-	mux.HandleFunc("POST /api/login", apiCfg.handlerLogin)
-	mux.HandleFunc("POST /api/refresh", apiCfg.handlerValidateRefresh)
-	mux.HandleFunc("POST /api/revoke", apiCfg.handlerRevoke)
-	mux.HandleFunc("PUT /api/users", apiCfg.handlerUpdateInfo)
-	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.handlerDeleteChirp)
-	mux.HandleFunc("POST /api/polka/webhooks", apiCfg.handlerUpgradeChirpyRed)
 	srv := &http.Server{
 		Addr:    ":" + port,
-		Handler: mux,
+		Handler: apiCfg.routes(filepathroot),
 	}
 
 	log.Printf("Serving on port: %s\n", port)
 
-	srv.ListenAndServe()
+	go func() {
+		err := srv.ListenAndServe()
+		if !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("couldn't serve: %v", err)
+		}
+	}()
+	<-ctx.Done()
+	log.Print("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	err = srv.Shutdown(shutdownCtx)
+	if err != nil {
+		log.Printf("couldn't finish serving open requests: %v", err)
+	}
+	// emails queued by handlers still go out
+	apiCfg.background.Wait()
+}
+
+// routes registers every handler on a new mux. filepathroot is the
+// directory served under /app/.
+func (cfg *apiConfig) routes(filepathroot string) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/app/", cfg.middlewareMetricsInc(http.StripPrefix("/app", http.FileServer(http.Dir(filepathroot)))))
+	mux.HandleFunc("GET /api/healthz", handlerReadiness)
+	mux.HandleFunc("GET /.well-known/jwks.json", cfg.handlerJWKS)
+	mux.Handle("GET /admin/metrics", cfg.middlewareRequireRole(internal.RoleAdmin, cfg.handlerMetrics))
+	mux.Handle("POST /admin/reset", cfg.middlewareRequireRole(internal.RoleAdmin, cfg.handlerReset))
+	mux.Handle("PUT /admin/users/{id}/role", cfg.middlewareRequireRole(internal.RoleAdmin, cfg.handlerSetUserRole))
+	mux.Handle("POST /admin/users/{id}/suspension", cfg.middlewareRequireRole(internal.RoleAdmin, cfg.handlerSuspendUser))
+	mux.Handle("DELETE /admin/users/{id}/suspension", cfg.middlewareRequireRole(internal.RoleAdmin, cfg.handlerLiftSuspension))
+	mux.Handle("GET /admin/moderation/rules", cfg.middlewareRequireRole(internal.RoleModerator, cfg.handlerListModerationRules))
+	mux.Handle("POST /admin/moderation/rules", cfg.middlewareRequireRole(internal.RoleAdmin, cfg.handlerCreateModerationRule))
+	mux.Handle("PUT /admin/moderation/rules/{ruleID}", cfg.middlewareRequireRole(internal.RoleAdmin, cfg.handlerUpdateModerationRule))
+	mux.Handle("DELETE /admin/moderation/rules/{ruleID}", cfg.middlewareRequireRole(internal.RoleAdmin, cfg.handlerDeleteModerationRule))
+	mux.Handle("GET /admin/reports", cfg.middlewareRequireRole(internal.RoleModerator, cfg.handlerListReports))
+	mux.Handle("POST /admin/reports/{reportID}/resolve", cfg.middlewareRequireRole(internal.RoleModerator, cfg.handlerResolveReport))
+	mux.HandleFunc("POST /api/chirps", cfg.handlerChirpsCreate)
+	mux.HandleFunc("GET /api/chirps", cfg.handlerChirpsGet)
+	mux.HandleFunc("GET /api/chirps/search", cfg.handlerChirpsSearch)
+	mux.HandleFunc("GET /api/chirps/{chirpID}", cfg.handlerChirpsGetByID)
+	mux.HandleFunc("POST /api/users", cfg.handlerCreateUser)
+	mux.HandleFunc("POST /api/login", cfg.handlerLogin)
+	mux.HandleFunc("POST /api/login/mfa", cfg.handlerLoginMFA)
+	mux.HandleFunc("POST /api/mfa/totp", cfg.handlerTOTPEnroll)
+	mux.HandleFunc("POST /api/mfa/totp/confirm", cfg.handlerTOTPConfirm)
+	mux.HandleFunc("DELETE /api/mfa/totp", cfg.handlerTOTPDisable)
+	mux.HandleFunc("POST /api/mfa/recovery-codes", cfg.handlerRegenerateRecoveryCodes)
+	mux.HandleFunc("POST /api/refresh", cfg.handlerValidateRefresh)
+	mux.HandleFunc("POST /api/revoke", cfg.handlerRevoke)
+	mux.HandleFunc("POST /api/password-reset/request", cfg.handlerPasswordResetRequest)
+	mux.HandleFunc("POST /api/password-reset/confirm", cfg.handlerPasswordResetConfirm)
+	mux.HandleFunc("GET /api/sessions", cfg.handlerListSessions)
+	mux.HandleFunc("DELETE /api/sessions/{id}", cfg.handlerRevokeSession)
+	mux.HandleFunc("POST /api/sessions/revoke-all", cfg.handlerRevokeOtherSessions)
+	mux.HandleFunc("PUT /api/users", cfg.handlerUpdateInfo)
+	mux.HandleFunc("POST /api/users/verify-email", cfg.handlerVerifyEmail)
+	mux.HandleFunc("POST /api/users/verify-email/resend", cfg.handlerResendEmailVerification)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}", cfg.handlerDeleteChirp)
+	mux.HandleFunc("PATCH /api/chirps/{chirpID}", cfg.handlerChirpsEdit)
+	mux.HandleFunc("GET /api/chirps/{chirpID}/revisions", cfg.handlerChirpRevisions)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/restore", cfg.handlerRestoreChirp)
+	mux.HandleFunc("GET /api/me/trash", cfg.handlerTrash)
+	mux.HandleFunc("GET /api/chirps/{chirpID}/thread", cfg.handlerChirpThread)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/report", cfg.handlerReportChirp)
+	mux.HandleFunc("POST /api/users/{id}/report", cfg.handlerReportUser)
+	mux.HandleFunc("PUT /api/chirps/{chirpID}/reactions/{emoji}", cfg.handlerReactionAdd)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/reactions/{emoji}", cfg.handlerReactionRemove)
+	mux.HandleFunc("POST /api/polka/webhooks", cfg.handlerUpgradeChirpyRed)
+	mux.HandleFunc("POST /api/users/{id}/follow", cfg.handlerFollowUser)
+	mux.HandleFunc("DELETE /api/users/{id}/follow", cfg.handlerUnfollowUser)
+	mux.HandleFunc("GET /api/users/{id}/followers", cfg.handlerListFollowers)
+	mux.HandleFunc("GET /api/users/{id}/following", cfg.handlerListFollowing)
+	mux.HandleFunc("GET /api/timeline", cfg.handlerTimeline)
+	mux.HandleFunc("GET /api/tags/{tag}/chirps", cfg.handlerTagChirps)
+	mux.HandleFunc("GET /api/users/{id}/mentions", cfg.handlerUserMentions)
+	mux.HandleFunc("POST /api/media", cfg.handlerMediaUpload)
+	mux.HandleFunc("GET /api/media/{mediaID}", cfg.handlerMediaGet)
+	return mux
 }
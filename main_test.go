@@ -0,0 +1,560 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/natretsel/chirpy/chirplen"
+	internal "github.com/natretsel/chirpy/internal/auth"
+	"github.com/natretsel/chirpy/internal/blobstore"
+	"github.com/natretsel/chirpy/internal/database"
+	"github.com/natretsel/chirpy/internal/mailer"
+)
+
+const testPolkaKey = "test-polka-key"
+
+// newTestServer starts the full mux against an empty in-memory store.
+func newTestServer(t *testing.T) (*apiConfig, *httptest.Server) {
+	t.Helper()
+	media, err := blobstore.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mail, err := mailer.NewFileMailer(t.TempDir(), "Chirpy <noreply@example.com>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := internal.GenerateKey("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := internal.NewKeyring(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &apiConfig{
+		dbQueries:        database.NewMemoryStore(),
+		platform:         "dev",
+		keys:             keys,
+		polka_key:        testPolkaKey,
+		allowedReactions: parseReactions(defaultReactions),
+		// test users never verify their email, so they may do anything;
+		// the restrictions have their own tests
+		unverifiedActions: unverifiedActionNames,
+		media:             media,
+		editWindow:        defaultEditWindow,
+		trashRetention:    defaultTrashRetentionDays * 24 * time.Hour,
+		moderation:        &moderationRules{},
+		tokenCutoffs:      newTokenCutoffs(),
+		sessions:          newSessionStates(),
+		mailer:            mail,
+	}
+	err = cfg.moderation.load(context.Background(), cfg.dbQueries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(cfg.routes("."))
+	t.Cleanup(srv.Close)
+	return cfg, srv
+}
+
+// doRequest sends body as JSON (unless nil) with an optional
+// Authorization header and returns the response with its body read.
+func doRequest(t *testing.T, srv *httptest.Server, method, path, authorization string, body any) (*http.Response, []byte) {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("marshal request body: %v", err)
+		}
+		reader = bytes.NewReader(buf)
+	}
+	req, err := http.NewRequest(method, srv.URL+path, reader)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read response body: %v", err)
+	}
+	return resp, respBody
+}
+
+func decodeBody[T any](t *testing.T, body []byte) T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal(body, &v); err != nil {
+		t.Fatalf("decode %q: %v", body, err)
+	}
+	return v
+}
+
+func expectStatus(t *testing.T, resp *http.Response, body []byte, want int) {
+	t.Helper()
+	if resp.StatusCode != want {
+		t.Fatalf("%s %s: status = %d, want %d (body %s)", resp.Request.Method, resp.Request.URL.Path, resp.StatusCode, want, body)
+	}
+}
+
+type loginResponse struct {
+	User
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// createUser signs up and logs in a user, returning the login response.
+func createUser(t *testing.T, srv *httptest.Server, email, password string) loginResponse {
+	t.Helper()
+	creds := map[string]string{"email": email, "password": password}
+	resp, body := doRequest(t, srv, http.MethodPost, "/api/users", "", creds)
+	expectStatus(t, resp, body, http.StatusCreated)
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/login", "", creds)
+	expectStatus(t, resp, body, http.StatusOK)
+	return decodeBody[loginResponse](t, body)
+}
+
+// createStaff is createUser for a user given role, logged in after the
+// role is set so their access token carries it.
+func createStaff(t *testing.T, cfg *apiConfig, srv *httptest.Server, email, role string) loginResponse {
+	t.Helper()
+	user := createUser(t, srv, email, "pw")
+	_, err := cfg.dbQueries.SetUserRole(context.Background(), database.SetUserRoleParams{ID: user.ID, Role: role})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, body := doRequest(t, srv, http.MethodPost, "/api/login", "", map[string]string{"email": email, "password": "pw"})
+	expectStatus(t, resp, body, http.StatusOK)
+	return decodeBody[loginResponse](t, body)
+}
+
+// emailedTokens returns the one-time tokens in the emails with subject
+// sent to address, oldest first, once background sends are done.
+func emailedTokens(t *testing.T, cfg *apiConfig, address, subject string) []string {
+	t.Helper()
+	cfg.background.Wait()
+	paths, err := filepath.Glob(filepath.Join(cfg.mailer.(*mailer.FileMailer).Dir(), "*.eml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tokens []string
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(data), "\r\nTo: "+address+"\r\nSubject: "+subject+"\r\n") {
+			tokens = append(tokens, oneTimeTokenPattern.FindString(string(data)))
+		}
+	}
+	return tokens
+}
+
+var oneTimeTokenPattern = regexp.MustCompile(`\b[0-9a-f]{64}\b`)
+
+func bearer(token string) string {
+	return "Bearer " + token
+}
+
+func createChirp(t *testing.T, srv *httptest.Server, token, text string) Chirp {
+	t.Helper()
+	resp, body := doRequest(t, srv, http.MethodPost, "/api/chirps", bearer(token), map[string]string{"body": text})
+	expectStatus(t, resp, body, http.StatusCreated)
+	return decodeBody[Chirp](t, body)
+}
+
+func TestReadinessAndMetrics(t *testing.T) {
+	cfg, srv := newTestServer(t)
+	admin := createStaff(t, cfg, srv, "metrics@example.com", internal.RoleAdmin)
+
+	resp, body := doRequest(t, srv, http.MethodGet, "/api/healthz", "", nil)
+	expectStatus(t, resp, body, http.StatusOK)
+	if string(body) != "OK" {
+		t.Errorf("healthz body = %q, want OK", body)
+	}
+
+	for range 2 {
+		resp, body = doRequest(t, srv, http.MethodGet, "/app/", "", nil)
+		expectStatus(t, resp, body, http.StatusOK)
+	}
+	resp, body = doRequest(t, srv, http.MethodGet, "/admin/metrics", bearer(admin.Token), nil)
+	expectStatus(t, resp, body, http.StatusOK)
+	if !strings.Contains(string(body), "visited 2 times") {
+		t.Errorf("metrics body = %q, want 2 visits", body)
+	}
+}
+
+func TestReset(t *testing.T) {
+	cfg, srv := newTestServer(t)
+	user := createUser(t, srv, "reset@example.com", "pw")
+	admin := createStaff(t, cfg, srv, "reset-admin@example.com", internal.RoleAdmin)
+
+	resp, body := doRequest(t, srv, http.MethodPost, "/admin/reset", bearer(user.Token), nil)
+	expectStatus(t, resp, body, http.StatusForbidden)
+	resp, body = doRequest(t, srv, http.MethodPost, "/admin/reset", bearer(admin.Token), nil)
+	expectStatus(t, resp, body, http.StatusOK)
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/login", "", map[string]string{"email": user.Email, "password": "pw"})
+	expectStatus(t, resp, body, http.StatusForbidden)
+
+	cfg.platform = "prod"
+	resp, body = doRequest(t, srv, http.MethodPost, "/admin/reset", bearer(admin.Token), nil)
+	expectStatus(t, resp, body, http.StatusForbidden)
+}
+
+func TestCreateUserAndLogin(t *testing.T) {
+	_, srv := newTestServer(t)
+	user := createUser(t, srv, "login@example.com", "hunter2")
+	if user.Token == "" || user.RefreshToken == "" {
+		t.Fatalf("login returned empty tokens: %+v", user)
+	}
+	if user.Email != "login@example.com" || user.Is_chirpy_red {
+		t.Errorf("unexpected user in login response: %+v", user.User)
+	}
+
+	resp, body := doRequest(t, srv, http.MethodPost, "/api/users", "", map[string]string{"email": "login@example.com", "password": "x"})
+	expectStatus(t, resp, body, http.StatusBadRequest)
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		want     int
+	}{
+		{name: "Wrong password", email: "login@example.com", password: "wrong", want: http.StatusUnauthorized},
+		{name: "Unknown email", email: "nobody@example.com", password: "hunter2", want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := doRequest(t, srv, http.MethodPost, "/api/login", "", map[string]string{"email": tt.email, "password": tt.password})
+			expectStatus(t, resp, body, tt.want)
+		})
+	}
+}
+
+func TestRefreshAndRevoke(t *testing.T) {
+	_, srv := newTestServer(t)
+	user := createUser(t, srv, "refresh@example.com", "pw")
+
+	resp, body := doRequest(t, srv, http.MethodPost, "/api/refresh", bearer(user.RefreshToken), nil)
+	expectStatus(t, resp, body, http.StatusOK)
+	refreshed := decodeBody[struct {
+		Token string `json:"token"`
+	}](t, body)
+	if refreshed.Token == "" {
+		t.Fatal("refresh returned an empty access token")
+	}
+
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/revoke", bearer(user.RefreshToken), nil)
+	expectStatus(t, resp, body, http.StatusNoContent)
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/refresh", bearer(user.RefreshToken), nil)
+	expectStatus(t, resp, body, http.StatusUnauthorized)
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/refresh", "", nil)
+	expectStatus(t, resp, body, http.StatusBadRequest)
+}
+
+func TestRefreshTokensStoredHashed(t *testing.T) {
+	cfg, srv := newTestServer(t)
+	user := createUser(t, srv, "hashed@example.com", "pw")
+
+	_, err := cfg.dbQueries.GetRefreshToken(context.Background(), user.RefreshToken)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("lookup by the raw token: err = %v, want sql.ErrNoRows", err)
+	}
+	stored, err := cfg.dbQueries.GetRefreshToken(context.Background(), internal.HashToken(user.RefreshToken))
+	if err != nil || stored.UserID != user.ID {
+		t.Errorf("lookup by digest = %+v, %v", stored, err)
+	}
+}
+
+func TestRefreshRotation(t *testing.T) {
+	_, srv := newTestServer(t)
+	user := createUser(t, srv, "rotate@example.com", "pw")
+	refresh := func(token string, want int) loginResponse {
+		t.Helper()
+		resp, body := doRequest(t, srv, http.MethodPost, "/api/refresh", bearer(token), nil)
+		expectStatus(t, resp, body, want)
+		if want != http.StatusOK {
+			return loginResponse{}
+		}
+		return decodeBody[loginResponse](t, body)
+	}
+
+	first := refresh(user.RefreshToken, http.StatusOK)
+	if first.RefreshToken == "" || first.RefreshToken == user.RefreshToken {
+		t.Fatalf("refresh returned refresh token %q, want a new one", first.RefreshToken)
+	}
+	second := refresh(first.RefreshToken, http.StatusOK)
+
+	// replaying a rotated token revokes the whole family
+	refresh(user.RefreshToken, http.StatusUnauthorized)
+	refresh(second.RefreshToken, http.StatusUnauthorized)
+
+	// another login is a separate family and keeps working
+	resp, body := doRequest(t, srv, http.MethodPost, "/api/login", "", map[string]string{"email": "rotate@example.com", "password": "pw"})
+	expectStatus(t, resp, body, http.StatusOK)
+	refresh(decodeBody[loginResponse](t, body).RefreshToken, http.StatusOK)
+}
+
+func TestUpdateUser(t *testing.T) {
+	_, srv := newTestServer(t)
+	user := createUser(t, srv, "old@example.com", "old-pw")
+
+	update := map[string]string{"email": "new@example.com", "password": "new-pw"}
+	resp, body := doRequest(t, srv, http.MethodPut, "/api/users", "", update)
+	expectStatus(t, resp, body, http.StatusUnauthorized)
+	resp, body = doRequest(t, srv, http.MethodPut, "/api/users", bearer(user.Token), update)
+	expectStatus(t, resp, body, http.StatusOK)
+	// the new email is pending until it is verified
+	if got := decodeBody[User](t, body); got.Email != "old@example.com" || got.PendingEmail == nil || *got.PendingEmail != "new@example.com" || got.ID != user.ID {
+		t.Errorf("updated user = %+v", got)
+	}
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/login", "", map[string]string{"email": "old@example.com", "password": "new-pw"})
+	expectStatus(t, resp, body, http.StatusOK)
+}
+
+func TestPasswordChangeRevokesTokens(t *testing.T) {
+	_, srv := newTestServer(t)
+	user := createUser(t, srv, "revoke-change@example.com", "old-pw")
+	creds := map[string]string{"email": "revoke-change@example.com", "password": "old-pw"}
+	resp, body := doRequest(t, srv, http.MethodPost, "/api/login", "", creds)
+	expectStatus(t, resp, body, http.StatusOK)
+	otherDevice := decodeBody[loginResponse](t, body)
+
+	update := map[string]string{"email": "revoke-change@example.com", "password": "new-pw"}
+	resp, body = doRequest(t, srv, http.MethodPut, "/api/users", bearer(user.Token), update)
+	expectStatus(t, resp, body, http.StatusOK)
+	for _, token := range []string{user.Token, otherDevice.Token} {
+		resp, body = doRequest(t, srv, http.MethodGet, "/api/timeline", bearer(token), nil)
+		expectStatus(t, resp, body, http.StatusUnauthorized)
+	}
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/refresh", bearer(otherDevice.RefreshToken), nil)
+	expectStatus(t, resp, body, http.StatusUnauthorized)
+
+	// the device that changed the password refreshes and carries on
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/refresh", bearer(user.RefreshToken), nil)
+	expectStatus(t, resp, body, http.StatusOK)
+	resp, body = doRequest(t, srv, http.MethodGet, "/api/timeline", bearer(decodeBody[loginResponse](t, body).Token), nil)
+	expectStatus(t, resp, body, http.StatusOK)
+}
+
+func TestChirpsCreate(t *testing.T) {
+	_, srv := newTestServer(t)
+	user := createUser(t, srv, "chirper@example.com", "pw")
+
+	chirp := createChirp(t, srv, user.Token, "I had a kerfuffle with Sharbert today")
+	if chirp.Body != "I had a **** with **** today" {
+		t.Errorf("chirp body = %q, want profanity redacted", chirp.Body)
+	}
+	if chirp.UserID != user.ID {
+		t.Errorf("chirp user_id = %v, want %v", chirp.UserID, user.ID)
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+		body          string
+		want          int
+	}{
+		{name: "Too long", authorization: bearer(user.Token), body: strings.Repeat("a", 141), want: http.StatusBadRequest},
+		{name: "Missing token", authorization: "", body: "hello", want: http.StatusUnauthorized},
+		{name: "Invalid token", authorization: bearer("not-a-jwt"), body: "hello", want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := doRequest(t, srv, http.MethodPost, "/api/chirps", tt.authorization, map[string]string{"body": tt.body})
+			expectStatus(t, resp, body, tt.want)
+		})
+	}
+
+	// length is counted in characters, not bytes
+	createChirp(t, srv, user.Token, strings.Repeat("🐦", 140))
+	resp, body := doRequest(t, srv, http.MethodPost, "/api/chirps", bearer(user.Token), map[string]string{"body": strings.Repeat("é", 141)})
+	expectStatus(t, resp, body, http.StatusBadRequest)
+	tooLong := decodeBody[struct {
+		Error  string `json:"error"`
+		Length int    `json:"length"`
+		Limit  int    `json:"limit"`
+	}](t, body)
+	if tooLong.Length != 141 || tooLong.Limit != 140 || tooLong.Error == "" {
+		t.Errorf("too long response = %+v", tooLong)
+	}
+}
+
+func TestChirpsCreateSizeLimits(t *testing.T) {
+	_, srv := newTestServer(t)
+	user := createUser(t, srv, "oversized@example.com", "pw")
+	chirp := createChirp(t, srv, user.Token, "small")
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		// each counts as a handful of characters
+		{name: "Long URL", body: "https://example.com/" + strings.Repeat("a", chirplen.MaxURLBytes), want: http.StatusBadRequest},
+		{name: "Stacked combining marks", body: "e" + strings.Repeat("\u0301", chirplen.MaxBytes/2), want: http.StatusBadRequest},
+		{name: "Huge URL", body: "https://example.com/" + strings.Repeat("a", 1<<20), want: http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := doRequest(t, srv, http.MethodPost, "/api/chirps", bearer(user.Token), map[string]string{"body": tt.body})
+			expectStatus(t, resp, body, tt.want)
+			resp, body = doRequest(t, srv, http.MethodPatch, "/api/chirps/"+chirp.ID.String(), bearer(user.Token), map[string]string{"body": tt.body})
+			expectStatus(t, resp, body, tt.want)
+		})
+	}
+	createChirp(t, srv, user.Token, "https://example.com/"+strings.Repeat("a", chirplen.MaxURLBytes-len("https://example.com/")))
+}
+
+func TestChirpsGet(t *testing.T) {
+	_, srv := newTestServer(t)
+	alice := createUser(t, srv, "alice@example.com", "pw")
+	bob := createUser(t, srv, "bob@example.com", "pw")
+	first := createChirp(t, srv, alice.Token, "first")
+	second := createChirp(t, srv, bob.Token, "second")
+	third := createChirp(t, srv, alice.Token, "third")
+
+	tests := []struct {
+		name  string
+		query string
+		want  []uuid.UUID
+	}{
+		{name: "All ascending", query: "", want: []uuid.UUID{first.ID, second.ID, third.ID}},
+		{name: "All descending", query: "?sort=desc", want: []uuid.UUID{third.ID, second.ID, first.ID}},
+		{name: "By author", query: "?author_id=" + alice.ID.String(), want: []uuid.UUID{first.ID, third.ID}},
+		{name: "By author descending", query: "?sort=desc&author_id=" + alice.ID.String(), want: []uuid.UUID{third.ID, first.ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := doRequest(t, srv, http.MethodGet, "/api/chirps"+tt.query, "", nil)
+			expectStatus(t, resp, body, http.StatusOK)
+			chirps := decodeBody[chirpsPage](t, body).Chirps
+			if len(chirps) != len(tt.want) {
+				t.Fatalf("got %d chirps, want %d", len(chirps), len(tt.want))
+			}
+			for i, c := range chirps {
+				if c.ID != tt.want[i] {
+					t.Errorf("chirps[%d] = %v, want %v", i, c.ID, tt.want[i])
+				}
+			}
+		})
+	}
+
+	for _, query := range []string{"?author_id=nope", "?sort=sideways", "?limit=0", "?cursor=bogus"} {
+		resp, body := doRequest(t, srv, http.MethodGet, "/api/chirps"+query, "", nil)
+		expectStatus(t, resp, body, http.StatusBadRequest)
+	}
+}
+
+func TestChirpsGetPagination(t *testing.T) {
+	_, srv := newTestServer(t)
+	alice := createUser(t, srv, "pages@example.com", "pw")
+	bob := createUser(t, srv, "other-pages@example.com", "pw")
+	var want []uuid.UUID
+	for i := range 5 {
+		want = append(want, createChirp(t, srv, alice.Token, fmt.Sprintf("chirp %d", i)).ID)
+		createChirp(t, srv, bob.Token, "noise")
+	}
+	slices.Reverse(want)
+
+	var got []uuid.UUID
+	path := "/api/chirps?sort=desc&limit=2&author_id=" + alice.ID.String()
+	for pages := 0; path != ""; pages++ {
+		if pages > 3 {
+			t.Fatal("pagination did not terminate")
+		}
+		resp, body := doRequest(t, srv, http.MethodGet, path, "", nil)
+		expectStatus(t, resp, body, http.StatusOK)
+		page := decodeBody[chirpsPage](t, body)
+		for _, c := range page.Chirps {
+			got = append(got, c.ID)
+		}
+		link := resp.Header.Get("Link")
+		if (page.NextCursor == "") != (link == "") {
+			t.Fatalf("next_cursor %q and Link %q disagree", page.NextCursor, link)
+		}
+		path = ""
+		if link != "" {
+			path = strings.TrimSuffix(strings.TrimPrefix(link, "<"), `>; rel="next"`)
+		}
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("paged chirps = %v, want %v", got, want)
+	}
+}
+
+func TestChirpsGetByID(t *testing.T) {
+	_, srv := newTestServer(t)
+	user := createUser(t, srv, "single@example.com", "pw")
+	chirp := createChirp(t, srv, user.Token, "just one")
+
+	resp, body := doRequest(t, srv, http.MethodGet, "/api/chirps/"+chirp.ID.String(), "", nil)
+	expectStatus(t, resp, body, http.StatusOK)
+	if got := decodeBody[Chirp](t, body); got.Body != "just one" {
+		t.Errorf("chirp body = %q", got.Body)
+	}
+	resp, body = doRequest(t, srv, http.MethodGet, "/api/chirps/"+uuid.NewString(), "", nil)
+	expectStatus(t, resp, body, http.StatusNotFound)
+	resp, body = doRequest(t, srv, http.MethodGet, "/api/chirps/not-a-uuid", "", nil)
+	expectStatus(t, resp, body, http.StatusBadRequest)
+}
+
+func TestDeleteChirp(t *testing.T) {
+	_, srv := newTestServer(t)
+	owner := createUser(t, srv, "owner@example.com", "pw")
+	other := createUser(t, srv, "other@example.com", "pw")
+	chirp := createChirp(t, srv, owner.Token, "delete me")
+	path := "/api/chirps/" + chirp.ID.String()
+
+	resp, body := doRequest(t, srv, http.MethodDelete, path, bearer(other.Token), nil)
+	expectStatus(t, resp, body, http.StatusForbidden)
+	resp, body = doRequest(t, srv, http.MethodDelete, path, bearer(owner.Token), nil)
+	expectStatus(t, resp, body, http.StatusNoContent)
+	resp, body = doRequest(t, srv, http.MethodGet, path, "", nil)
+	expectStatus(t, resp, body, http.StatusNotFound)
+}
+
+func TestUpgradeChirpyRed(t *testing.T) {
+	_, srv := newTestServer(t)
+	user := createUser(t, srv, "red@example.com", "pw")
+
+	upgrade := func(userID uuid.UUID) map[string]any {
+		return map[string]any{"event": "user.upgraded", "data": map[string]any{"user_id": userID}}
+	}
+	resp, body := doRequest(t, srv, http.MethodPost, "/api/polka/webhooks", "ApiKey wrong", upgrade(user.ID))
+	expectStatus(t, resp, body, http.StatusUnauthorized)
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/polka/webhooks", "ApiKey "+testPolkaKey, upgrade(uuid.New()))
+	expectStatus(t, resp, body, http.StatusNotFound)
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/polka/webhooks", "ApiKey "+testPolkaKey, map[string]any{"event": "user.other"})
+	expectStatus(t, resp, body, http.StatusNoContent)
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/polka/webhooks", "ApiKey "+testPolkaKey, upgrade(user.ID))
+	expectStatus(t, resp, body, http.StatusNoContent)
+
+	resp, body = doRequest(t, srv, http.MethodPost, "/api/login", "", map[string]string{"email": user.Email, "password": "pw"})
+	expectStatus(t, resp, body, http.StatusOK)
+	if !decodeBody[loginResponse](t, body).Is_chirpy_red {
+		t.Error("user was not upgraded to Chirpy Red")
+	}
+}
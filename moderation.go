@@ -0,0 +1,251 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/natretsel/chirpy/internal/database"
+)
+
+// Moderation rule kinds and actions, as stored in moderation_rules.
+const (
+	ruleKindWord   = "word"
+	ruleKindPhrase = "phrase"
+	ruleKindRegex  = "regex"
+
+	ruleActionRedact = "redact"
+	ruleActionReject = "reject"
+	ruleActionHold   = "hold"
+
+	redaction                 = "****"
+	moderationRefreshInterval = time.Minute
+	defaultRejectReason       = "it breaks the content rules"
+	defaultHoldReason         = "held for review"
+)
+
+// errChirpRejected wraps the reason a reject rule gives.
+var errChirpRejected = errors.New("Chirp rejected")
+
+// moderationRule is a stored rule compiled for matching. Words and
+// phrases are matched as whole words against the folded body (see
+// foldForMatching); regexes match the body as written.
+type moderationRule struct {
+	database.ModerationRule
+	patterns []*regexp.Regexp
+	folded   bool
+}
+
+// compileModerationRule checks a rule and compiles its patterns.
+func compileModerationRule(rule database.ModerationRule) (moderationRule, error) {
+	compiled := moderationRule{ModerationRule: rule}
+	switch rule.Action {
+	case ruleActionRedact, ruleActionReject, ruleActionHold:
+	default:
+		return moderationRule{}, fmt.Errorf("action must be %s, %s or %s", ruleActionRedact, ruleActionReject, ruleActionHold)
+	}
+	if len(rule.Patterns) == 0 {
+		return moderationRule{}, errors.New("a rule needs at least one pattern")
+	}
+	for _, pattern := range rule.Patterns {
+		var expr string
+		switch rule.Kind {
+		case ruleKindWord:
+			word := foldForMatching(strings.TrimSpace(pattern)).text
+			if len(strings.Fields(word)) != 1 {
+				return moderationRule{}, fmt.Errorf("%q is not a single word; use a phrase rule", pattern)
+			}
+			expr = regexp.QuoteMeta(word)
+			compiled.folded = true
+		case ruleKindPhrase:
+			words := strings.Fields(foldForMatching(pattern).text)
+			if len(words) == 0 {
+				return moderationRule{}, errors.New("phrases can't be blank")
+			}
+			for i, word := range words {
+				words[i] = regexp.QuoteMeta(word)
+			}
+			expr = strings.Join(words, `\s+`)
+			compiled.folded = true
+		case ruleKindRegex:
+			expr = pattern
+		default:
+			return moderationRule{}, fmt.Errorf("kind must be %s, %s or %s", ruleKindWord, ruleKindPhrase, ruleKindRegex)
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return moderationRule{}, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		compiled.patterns = append(compiled.patterns, re)
+	}
+	return compiled, nil
+}
+
+// matches returns the spans of body the rule matches, as byte offsets
+// into body. folded is body folded for matching.
+func (rule moderationRule) matches(body string, folded foldedText) [][2]int {
+	text := body
+	if rule.folded {
+		text = folded.text
+	}
+	var spans [][2]int
+	for _, re := range rule.patterns {
+		pos := 0
+		for pos <= len(text) {
+			loc := re.FindStringIndex(text[pos:])
+			if loc == nil {
+				break
+			}
+			start, end := pos+loc[0], pos+loc[1]
+			if start == end || (rule.folded && !isWholeWord(text, start, end)) {
+				// try again from the next character
+				_, size := utf8.DecodeRuneInString(text[start:])
+				pos = start + max(size, 1)
+				continue
+			}
+			if rule.folded {
+				spans = append(spans, folded.span(start, end))
+			} else {
+				spans = append(spans, [2]int{start, end})
+			}
+			pos = end
+		}
+	}
+	return spans
+}
+
+// isWholeWord reports whether body[start:end] is not part of a longer
+// word.
+func isWholeWord(body string, start, end int) bool {
+	before, _ := utf8.DecodeLastRuneInString(body[:start])
+	after, _ := utf8.DecodeRuneInString(body[end:])
+	return (start == 0 || !isTagRune(before)) && (end == len(body) || !isTagRune(after))
+}
+
+// chirpVerdict is the outcome of moderating a chirp body that wasn't
+// rejected: the body with redactions applied, and whether it must be
+// held for review.
+type chirpVerdict struct {
+	Body       string
+	Hold       bool
+	HoldReason string
+}
+
+// moderate applies every enabled rule to body. All rules see the
+// original body, so a redaction can't hide text from a reject or hold
+// rule. Any reject rule match rejects the chirp with that rule's
+// reason.
+func moderate(body string, rules []moderationRule) (chirpVerdict, error) {
+	verdict := chirpVerdict{}
+	folded := foldForMatching(body)
+	var redact [][2]int
+	for _, rule := range rules {
+		if !rule.Enabled {
+			continue
+		}
+		spans := rule.matches(body, folded)
+		if len(spans) == 0 {
+			continue
+		}
+		switch rule.Action {
+		case ruleActionReject:
+			return chirpVerdict{}, fmt.Errorf("%w: %s", errChirpRejected, cmp.Or(rule.Reason, defaultRejectReason))
+		case ruleActionHold:
+			if !verdict.Hold {
+				verdict.Hold = true
+				verdict.HoldReason = cmp.Or(rule.Reason, defaultHoldReason)
+			}
+		case ruleActionRedact:
+			redact = append(redact, spans...)
+		}
+	}
+	verdict.Body = redactSpans(body, redact)
+	return verdict, nil
+}
+
+// redactSpans replaces each span of body, merging overlapping ones,
+// with the redaction marker.
+func redactSpans(body string, spans [][2]int) string {
+	if len(spans) == 0 {
+		return body
+	}
+	slices.SortFunc(spans, func(a, b [2]int) int { return a[0] - b[0] })
+	var sb strings.Builder
+	last := 0
+	for i := 0; i < len(spans); i++ {
+		start, end := spans[i][0], spans[i][1]
+		for i+1 < len(spans) && spans[i+1][0] < end {
+			i++
+			end = max(end, spans[i][1])
+		}
+		if start < last {
+			start = last
+		}
+		sb.WriteString(body[last:start])
+		sb.WriteString(redaction)
+		last = end
+	}
+	sb.WriteString(body[last:])
+	return sb.String()
+}
+
+// moderationRules caches the compiled rules so validating a chirp
+// doesn't hit the database. Admin changes reload it immediately, and a
+// background refresh picks up changes made by other instances.
+type moderationRules struct {
+	mu    sync.RWMutex
+	rules []moderationRule
+}
+
+func (m *moderationRules) get() []moderationRule {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.rules
+}
+
+// load replaces the cached rules with those in store. Rules that no
+// longer compile are skipped and logged rather than failing the load.
+func (m *moderationRules) load(ctx context.Context, store database.Store) error {
+	rows, err := store.ListModerationRules(ctx)
+	if err != nil {
+		return err
+	}
+	rules := make([]moderationRule, 0, len(rows))
+	for _, row := range rows {
+		rule, err := compileModerationRule(row)
+		if err != nil {
+			log.Printf("skipping moderation rule %s: %v", row.ID, err)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rules = rules
+	return nil
+}
+
+// runModerationRefresher reloads the rules every interval until ctx is
+// done.
+func (cfg *apiConfig) runModerationRefresher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := cfg.moderation.load(ctx, cfg.dbQueries); err != nil {
+			log.Printf("couldn't refresh moderation rules: %v", err)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// leetspeak maps the usual letter substitutions back to the letter
+// they stand in for.
+var leetspeak = map[rune]rune{
+	'0': 'o',
+	'1': 'i',
+	'3': 'e',
+	'4': 'a',
+	'5': 's',
+	'7': 't',
+	'8': 'b',
+	'@': 'a',
+	'$': 's',
+}
+
+// isInvisible reports whether r is dropped before matching: zero-width
+// characters and other formatting controls that can be slipped inside
+// a word without changing how it looks.
+func isInvisible(r rune) bool {
+	return unicode.Is(unicode.Cf, r)
+}
+
+// foldedText is a chirp body folded for matching word and phrase
+// rules, with each of its bytes mapped back to the span of the
+// original body it came from.
+type foldedText struct {
+	text  string
+	start []int
+	end   []int
+}
+
+// foldForMatching applies NFKC normalization, Unicode case folding and
+// leetspeak substitution to s, and drops invisible characters, so that
+// look-alike spellings of a word all fold to the same text.
+func foldForMatching(s string) foldedText {
+	folder := cases.Fold()
+	var sb strings.Builder
+	f := foldedText{}
+	var it norm.Iter
+	it.InitString(norm.NFKC, s)
+	for !it.Done() {
+		from := it.Pos()
+		segment := it.Next()
+		to := it.Pos()
+		for _, r := range folder.String(string(segment)) {
+			if isInvisible(r) {
+				continue
+			}
+			if sub, ok := leetspeak[r]; ok {
+				r = sub
+			}
+			n, _ := sb.WriteRune(r)
+			for range n {
+				f.start = append(f.start, from)
+				f.end = append(f.end, to)
+			}
+		}
+	}
+	f.text = sb.String()
+	return f
+}
+
+// span maps the folded bytes text[start:end] back to the original.
+func (f foldedText) span(start, end int) [2]int {
+	return [2]int{f.start[start], f.end[end-1]}
+}
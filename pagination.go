@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 100
+)
+
+// pageCursor is the keyset position of the last row on a page. Clients
+// only ever see it base64 encoded and hand it back unchanged. Rank is
+// only set by ranked listings such as search.
+type pageCursor struct {
+	Rank      float32   `json:"r,omitempty"`
+	CreatedAt time.Time `json:"t"`
+	ID        uuid.UUID `json:"id"`
+}
+
+func (c pageCursor) encode() string {
+	c.CreatedAt = c.CreatedAt.UTC()
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func encodeCursor(createdAt time.Time, id uuid.UUID) string {
+	return pageCursor{CreatedAt: createdAt, ID: id}.encode()
+}
+
+func decodeCursor(s string) (pageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return pageCursor{}, errors.New("invalid cursor")
+	}
+	cursor := pageCursor{}
+	if err := json.Unmarshal(raw, &cursor); err != nil || cursor.ID == uuid.Nil {
+		return pageCursor{}, errors.New("invalid cursor")
+	}
+	return cursor, nil
+}
+
+// parseLimit reads the optional limit query param, defaulting to
+// defaultPageLimit and capping at maxPageLimit.
+func parseLimit(query url.Values) (int, error) {
+	limitStr := query.Get("limit")
+	if limitStr == "" {
+		return defaultPageLimit, nil
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit < 1 {
+		return 0, errors.New("limit must be a positive integer")
+	}
+	return min(limit, maxPageLimit), nil
+}
+
+// parsePage reads the limit and cursor query params shared by every
+// paginated endpoint. The cursor is nil when the first page is wanted.
+func parsePage(query url.Values) (int, *pageCursor, error) {
+	limit, err := parseLimit(query)
+	if err != nil {
+		return 0, nil, err
+	}
+	cursorStr := query.Get("cursor")
+	if cursorStr == "" {
+		return limit, nil, nil
+	}
+	cursor, err := decodeCursor(cursorStr)
+	if err != nil {
+		return 0, nil, err
+	}
+	return limit, &cursor, nil
+}
+
+// setNextLink sets a Link header pointing at the next page: the
+// request URL with its cursor replaced by nextCursor.
+func setNextLink(w http.ResponseWriter, r *http.Request, nextCursor string) {
+	if nextCursor == "" {
+		return
+	}
+	query := r.URL.Query()
+	query.Set("cursor", nextCursor)
+	next := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+	w.Header().Set("Link", "<"+next.String()+`>; rel="next"`)
+}
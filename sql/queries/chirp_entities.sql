@@ -0,0 +1,83 @@
+-- name: AddChirpTag :exec
+INSERT INTO chirp_tags (chirp_id, tag, start_offset, end_offset)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+);
+
+-- name: AddChirpMention :exec
+INSERT INTO chirp_mentions (chirp_id, user_id, start_offset, end_offset)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+);
+
+-- name: GetChirpTags :many
+SELECT *
+FROM chirp_tags
+WHERE chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+ORDER BY chirp_id, start_offset;
+
+-- name: GetChirpMentions :many
+SELECT *
+FROM chirp_mentions
+WHERE chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+ORDER BY chirp_id, start_offset;
+
+-- name: ListChirpsByTag :many
+SELECT *
+FROM chirps
+WHERE tombstoned_at IS NULL
+AND deleted_at IS NULL
+AND held_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM users AS authors
+    WHERE authors.id = chirps.user_id
+    AND authors.suspension_hides_chirps
+    AND (authors.suspended_until IS NULL OR authors.suspended_until > NOW())
+)
+AND id IN (
+    SELECT chirp_id FROM chirp_tags WHERE tag = $1
+)
+AND (
+    sqlc.narg('before_created_at')::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg('before_created_at')::timestamp, sqlc.narg('before_id')::uuid)
+)
+ORDER BY created_at DESC, id DESC
+LIMIT $2;
+
+-- name: ListMentions :many
+-- Chirps mentioning the user, newest first.
+SELECT *
+FROM chirps
+WHERE tombstoned_at IS NULL
+AND deleted_at IS NULL
+AND held_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM users AS authors
+    WHERE authors.id = chirps.user_id
+    AND authors.suspension_hides_chirps
+    AND (authors.suspended_until IS NULL OR authors.suspended_until > NOW())
+)
+AND id IN (
+    SELECT chirp_id FROM chirp_mentions WHERE chirp_mentions.user_id = $1
+)
+AND (
+    sqlc.narg('before_created_at')::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg('before_created_at')::timestamp, sqlc.narg('before_id')::uuid)
+)
+ORDER BY created_at DESC, id DESC
+LIMIT $2;
+
+-- name: DeleteChirpEntities :exec
+-- Clears a chirp's tags and mentions so an edited body can be parsed
+-- again.
+WITH deleted_tags AS (
+    DELETE FROM chirp_tags WHERE chirp_tags.chirp_id = $1
+)
+DELETE FROM chirp_mentions
+WHERE chirp_mentions.chirp_id = $1;
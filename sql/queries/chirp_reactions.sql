@@ -0,0 +1,32 @@
+-- name: AddReaction :exec
+INSERT INTO chirp_reactions (chirp_id, user_id, emoji, created_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    NOW()
+)
+ON CONFLICT (chirp_id, user_id, emoji) DO NOTHING;
+
+-- name: RemoveReaction :exec
+DELETE FROM chirp_reactions
+WHERE chirp_id = $1
+AND user_id = $2
+AND emoji = $3;
+
+-- name: GetReactionCounts :many
+-- Per-emoji totals for each chirp, and whether viewer_id is among the
+-- reactors. viewer_id may be NULL for anonymous callers.
+SELECT
+    chirp_id,
+    emoji,
+    COUNT(*)::int AS count,
+    COALESCE(bool_or(user_id = sqlc.narg('viewer_id')::uuid), FALSE)::bool AS reacted
+FROM chirp_reactions
+WHERE chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+GROUP BY chirp_id, emoji
+ORDER BY chirp_id, count DESC, emoji;
+
+-- name: DeleteReactionsByChirpID :exec
+DELETE FROM chirp_reactions
+WHERE chirp_id = $1;
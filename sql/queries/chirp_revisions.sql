@@ -0,0 +1,16 @@
+-- name: CreateChirpRevision :exec
+-- Keeps a chirp's current body before an edit replaces it.
+INSERT INTO chirp_revisions (id, chirp_id, body, created_at, replaced_at)
+SELECT gen_random_uuid(), chirps.id, chirps.body, chirps.updated_at, NOW()
+FROM chirps
+WHERE chirps.id = $1;
+
+-- name: GetChirpRevisions :many
+SELECT *
+FROM chirp_revisions
+WHERE chirp_id = $1
+ORDER BY replaced_at ASC, id ASC;
+
+-- name: DeleteChirpRevisions :exec
+DELETE FROM chirp_revisions
+WHERE chirp_id = $1;
@@ -1,30 +1,336 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+-- A held chirp stays hidden until a moderator reviews it.
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to, rechirp_of, quote_of, held_at, hold_reason)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
-    $1,
-    $2
+    sqlc.arg('body'),
+    sqlc.arg('user_id'),
+    sqlc.narg('in_reply_to'),
+    sqlc.narg('rechirp_of'),
+    sqlc.narg('quote_of'),
+    CASE WHEN sqlc.arg('held')::bool THEN NOW() END,
+    sqlc.arg('hold_reason')
 )
 RETURNING *;
 
 -- name: GetChirps :many
 SELECT * 
 FROM chirps
+WHERE tombstoned_at IS NULL
+AND deleted_at IS NULL
+AND held_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM users AS authors
+    WHERE authors.id = chirps.user_id
+    AND authors.suspension_hides_chirps
+    AND (authors.suspended_until IS NULL OR authors.suspended_until > NOW())
+)
 ORDER BY created_at ASC;
 
 -- name: GetChirpsByUserID :many
 SELECT *
 FROM chirps
 WHERE user_id = $1
+AND tombstoned_at IS NULL
+AND deleted_at IS NULL
+AND held_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM users AS authors
+    WHERE authors.id = chirps.user_id
+    AND authors.suspension_hides_chirps
+    AND (authors.suspended_until IS NULL OR authors.suspended_until > NOW())
+)
 ORDER BY created_at ASC;
 
 -- name: GetChirpByID :one
 SELECT *
 FROM chirps
-WHERE id = $1;
+WHERE chirps.id = $1
+AND tombstoned_at IS NULL
+AND deleted_at IS NULL
+AND held_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM users AS authors
+    WHERE authors.id = chirps.user_id
+    AND authors.suspension_hides_chirps
+    AND (authors.suspended_until IS NULL OR authors.suspended_until > NOW())
+);
 
 -- name: DeleteChirpByID :exec
 DELETE FROM chirps
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: ListChirpsAsc :many
+SELECT *
+FROM chirps
+WHERE tombstoned_at IS NULL
+AND deleted_at IS NULL
+AND held_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM users AS authors
+    WHERE authors.id = chirps.user_id
+    AND authors.suspension_hides_chirps
+    AND (authors.suspended_until IS NULL OR authors.suspended_until > NOW())
+)
+AND (sqlc.narg('user_id')::uuid IS NULL OR user_id = sqlc.narg('user_id')::uuid)
+AND (
+    sqlc.narg('after_created_at')::timestamp IS NULL
+    OR (created_at, id) > (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid)
+)
+ORDER BY created_at ASC, id ASC
+LIMIT $1;
+
+-- name: ListChirpsDesc :many
+SELECT *
+FROM chirps
+WHERE tombstoned_at IS NULL
+AND deleted_at IS NULL
+AND held_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM users AS authors
+    WHERE authors.id = chirps.user_id
+    AND authors.suspension_hides_chirps
+    AND (authors.suspended_until IS NULL OR authors.suspended_until > NOW())
+)
+AND (sqlc.narg('user_id')::uuid IS NULL OR user_id = sqlc.narg('user_id')::uuid)
+AND (
+    sqlc.narg('before_created_at')::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg('before_created_at')::timestamp, sqlc.narg('before_id')::uuid)
+)
+ORDER BY created_at DESC, id DESC
+LIMIT $1;
+
+-- name: SearchChirps :many
+-- Ranked full-text search, best match first. The body is HTML escaped
+-- before ts_headline so the highlight is safe to render as markup.
+SELECT
+    sqlc.embed(chirps),
+    ts_rank(chirps.search_vector, websearch_to_tsquery('english', sqlc.arg('query')))::real AS rank,
+    ts_headline(
+        'english',
+        replace(replace(replace(chirps.body, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+        websearch_to_tsquery('english', sqlc.arg('query')),
+        'StartSel=<mark>, StopSel=</mark>, HighlightAll=true'
+    )::text AS highlight
+FROM chirps
+WHERE chirps.tombstoned_at IS NULL
+AND chirps.deleted_at IS NULL
+AND chirps.held_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM users AS authors
+    WHERE authors.id = chirps.user_id
+    AND authors.suspension_hides_chirps
+    AND (authors.suspended_until IS NULL OR authors.suspended_until > NOW())
+)
+AND chirps.search_vector @@ websearch_to_tsquery('english', sqlc.arg('query'))
+AND (sqlc.narg('user_id')::uuid IS NULL OR chirps.user_id = sqlc.narg('user_id')::uuid)
+AND (sqlc.narg('since')::timestamp IS NULL OR chirps.created_at >= sqlc.narg('since')::timestamp)
+AND (sqlc.narg('until')::timestamp IS NULL OR chirps.created_at < sqlc.narg('until')::timestamp)
+AND (
+    sqlc.narg('after_rank')::real IS NULL
+    OR (
+        ts_rank(chirps.search_vector, websearch_to_tsquery('english', sqlc.arg('query')))::real,
+        chirps.created_at,
+        chirps.id
+    ) < (sqlc.narg('after_rank')::real, sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid)
+)
+ORDER BY rank DESC, chirps.created_at DESC, chirps.id DESC
+LIMIT $1;
+
+-- name: TombstoneChirp :exec
+UPDATE chirps
+SET body = '', tombstoned_at = NOW(), updated_at = NOW()
+WHERE id = $1;
+
+-- name: UpdateChirpBody :one
+-- An edit can put a chirp on hold, but never releases one.
+UPDATE chirps
+SET body = sqlc.arg('body'), updated_at = NOW(),
+    held_at = CASE WHEN sqlc.arg('held')::bool THEN NOW() ELSE held_at END,
+    hold_reason = CASE WHEN sqlc.arg('held')::bool THEN sqlc.arg('hold_reason') ELSE hold_reason END
+WHERE chirps.id = sqlc.arg('id')
+AND tombstoned_at IS NULL
+AND deleted_at IS NULL
+AND held_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM users AS authors
+    WHERE authors.id = chirps.user_id
+    AND authors.suspension_hides_chirps
+    AND (authors.suspended_until IS NULL OR authors.suspended_until > NOW())
+)
+RETURNING *;
+
+-- name: DeleteRechirpsOf :exec
+DELETE FROM chirps
+WHERE rechirp_of = sqlc.arg('id')::uuid;
+
+-- name: DetachQuotesOf :exec
+UPDATE chirps
+SET quote_of = NULL
+WHERE quote_of = sqlc.arg('id')::uuid;
+
+-- name: GetRechirp :one
+SELECT *
+FROM chirps
+WHERE user_id = $1
+AND rechirp_of = sqlc.arg('rechirp_of')::uuid;
+
+-- name: GetChirpsByIDs :many
+SELECT *
+FROM chirps
+WHERE id = ANY(sqlc.arg('ids')::uuid[])
+AND tombstoned_at IS NULL
+AND deleted_at IS NULL
+AND held_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM users AS authors
+    WHERE authors.id = chirps.user_id
+    AND authors.suspension_hides_chirps
+    AND (authors.suspended_until IS NULL OR authors.suspended_until > NOW())
+);
+
+-- name: GetShareCounts :many
+-- How many times each chirp has been rechirped and quoted. Chirps that
+-- were never shared are left out.
+SELECT
+    chirps.id AS chirp_id,
+    (COUNT(shares.id) FILTER (WHERE shares.rechirp_of = chirps.id))::int AS rechirps,
+    (COUNT(shares.id) FILTER (WHERE shares.quote_of = chirps.id))::int AS quotes
+FROM chirps
+JOIN chirps AS shares ON shares.rechirp_of = chirps.id OR shares.quote_of = chirps.id
+WHERE chirps.id = ANY(sqlc.arg('chirp_ids')::uuid[])
+AND shares.tombstoned_at IS NULL
+AND shares.deleted_at IS NULL
+AND shares.held_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM users AS authors
+    WHERE authors.id = shares.user_id
+    AND authors.suspension_hides_chirps
+    AND (authors.suspended_until IS NULL OR authors.suspended_until > NOW())
+)
+GROUP BY chirps.id;
+
+-- name: ChirpHasReplies :one
+SELECT EXISTS (
+    SELECT 1 FROM chirps WHERE in_reply_to = sqlc.arg('id')::uuid
+);
+
+-- name: GetThreadChirpByID :one
+-- Like GetChirpByID, but tombstones and trashed chirps are returned too.
+-- author_hidden is set while the author serves a suspension that hides
+-- their chirps.
+SELECT sqlc.embed(chirps),
+    (
+        authors.suspension_hides_chirps
+        AND (authors.suspended_until IS NULL OR authors.suspended_until > NOW())
+    )::bool AS author_hidden
+FROM chirps
+JOIN users AS authors ON authors.id = chirps.user_id
+WHERE chirps.id = $1;
+
+-- name: GetChirpAncestors :many
+-- The reply chain above a chirp, root first, tombstones and trashed
+-- chirps included, with author_hidden as in GetThreadChirpByID.
+WITH RECURSIVE ancestors AS (
+    SELECT parent.id, parent.in_reply_to, 1 AS depth
+    FROM chirps AS child
+    JOIN chirps AS parent ON parent.id = child.in_reply_to
+    WHERE child.id = $1
+    UNION ALL
+    SELECT parent.id, parent.in_reply_to, ancestors.depth + 1
+    FROM ancestors
+    JOIN chirps AS parent ON parent.id = ancestors.in_reply_to
+)
+SELECT sqlc.embed(chirps),
+    (
+        authors.suspension_hides_chirps
+        AND (authors.suspended_until IS NULL OR authors.suspended_until > NOW())
+    )::bool AS author_hidden
+FROM ancestors
+JOIN chirps ON chirps.id = ancestors.id
+JOIN users AS authors ON authors.id = chirps.user_id
+ORDER BY ancestors.depth DESC;
+
+-- name: GetChirpDescendants :many
+-- Replies below a chirp, breadth first, at most max_depth levels deep
+-- and row_limit rows in total. Tombstones and trashed chirps are
+-- included, with author_hidden as in GetThreadChirpByID.
+WITH RECURSIVE descendants AS (
+    SELECT chirps.id, 1 AS depth
+    FROM chirps
+    WHERE chirps.in_reply_to = sqlc.arg('id')::uuid
+    UNION ALL
+    SELECT chirps.id, descendants.depth + 1
+    FROM descendants
+    JOIN chirps ON chirps.in_reply_to = descendants.id
+    WHERE descendants.depth < sqlc.arg('max_depth')::int
+)
+SELECT sqlc.embed(chirps), descendants.depth::int AS depth,
+    (
+        authors.suspension_hides_chirps
+        AND (authors.suspended_until IS NULL OR authors.suspended_until > NOW())
+    )::bool AS author_hidden
+FROM descendants
+JOIN chirps ON chirps.id = descendants.id
+JOIN users AS authors ON authors.id = chirps.user_id
+ORDER BY descendants.depth, chirps.created_at, chirps.id
+LIMIT sqlc.arg('row_limit');
+
+-- name: SoftDeleteChirp :exec
+-- Moves a chirp to its author's trash. Its rechirps are hidden with it,
+-- sharing its deleted_at so they come back when it is restored.
+UPDATE chirps
+SET deleted_at = NOW()
+WHERE (id = sqlc.arg('id')::uuid OR rechirp_of = sqlc.arg('id')::uuid)
+AND deleted_at IS NULL
+AND held_at IS NULL;
+
+-- name: RestoreChirp :exec
+-- Takes a chirp, and the rechirps trashed along with it, out of the
+-- trash.
+UPDATE chirps
+SET deleted_at = NULL
+WHERE (id = sqlc.arg('id')::uuid OR rechirp_of = sqlc.arg('id')::uuid)
+AND deleted_at = (
+    SELECT trashed.deleted_at FROM chirps AS trashed WHERE trashed.id = sqlc.arg('id')::uuid
+);
+
+-- name: GetTrashedChirp :one
+SELECT *
+FROM chirps
+WHERE id = $1
+AND deleted_at IS NOT NULL
+AND tombstoned_at IS NULL;
+
+-- name: ListTrash :many
+-- A user's trashed chirps that are not yet due for purging, most
+-- recently deleted first.
+SELECT *
+FROM chirps
+WHERE user_id = $1
+AND rechirp_of IS NULL
+AND tombstoned_at IS NULL
+AND deleted_at > sqlc.arg('deleted_after')::timestamp
+AND (
+    sqlc.narg('before_deleted_at')::timestamp IS NULL
+    OR (deleted_at, id) < (sqlc.narg('before_deleted_at')::timestamp, sqlc.narg('before_id')::uuid)
+)
+ORDER BY deleted_at DESC, id DESC
+LIMIT $2;
+
+-- name: ListExpiredTrash :many
+-- Trashed chirps deleted at or before cutoff, oldest first.
+SELECT id
+FROM chirps
+WHERE deleted_at <= sqlc.arg('cutoff')::timestamp
+AND tombstoned_at IS NULL
+ORDER BY deleted_at, id
+LIMIT $1;
+
+-- name: ReleaseChirpHold :exec
+UPDATE chirps
+SET held_at = NULL, hold_reason = ''
+WHERE id = $1
+AND held_at IS NOT NULL;
@@ -0,0 +1,61 @@
+-- name: FollowUser :exec
+INSERT INTO follows (follower_id, followee_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT (follower_id, followee_id) DO NOTHING;
+
+-- name: UnfollowUser :exec
+DELETE FROM follows
+WHERE follower_id = $1
+AND followee_id = $2;
+
+-- name: ListFollowers :many
+SELECT *
+FROM follows
+WHERE followee_id = $1
+AND (
+    sqlc.narg('before_created_at')::timestamp IS NULL
+    OR (created_at, follower_id) < (sqlc.narg('before_created_at')::timestamp, sqlc.narg('before_id')::uuid)
+)
+ORDER BY created_at DESC, follower_id DESC
+LIMIT $2;
+
+-- name: ListFollowing :many
+SELECT *
+FROM follows
+WHERE follower_id = $1
+AND (
+    sqlc.narg('before_created_at')::timestamp IS NULL
+    OR (created_at, followee_id) < (sqlc.narg('before_created_at')::timestamp, sqlc.narg('before_id')::uuid)
+)
+ORDER BY created_at DESC, followee_id DESC
+LIMIT $2;
+
+-- name: GetTimeline :many
+-- Chirps by the user and everyone they follow, newest first.
+SELECT chirps.*
+FROM chirps
+WHERE chirps.tombstoned_at IS NULL
+AND chirps.deleted_at IS NULL
+AND chirps.held_at IS NULL
+AND NOT EXISTS (
+    SELECT 1 FROM users AS authors
+    WHERE authors.id = chirps.user_id
+    AND authors.suspension_hides_chirps
+    AND (authors.suspended_until IS NULL OR authors.suspended_until > NOW())
+)
+AND (
+    chirps.user_id = $1
+    OR chirps.user_id IN (
+        SELECT followee_id FROM follows WHERE follower_id = $1
+    )
+)
+AND (
+    sqlc.narg('before_created_at')::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) < (sqlc.narg('before_created_at')::timestamp, sqlc.narg('before_id')::uuid)
+)
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT $2;
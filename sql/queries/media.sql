@@ -0,0 +1,36 @@
+-- name: CreateMedia :one
+INSERT INTO media (id, created_at, user_id, content_type, size_bytes)
+VALUES (
+    $1,
+    NOW(),
+    $2,
+    $3,
+    $4
+)
+RETURNING *;
+
+-- name: GetMediaByID :one
+SELECT *
+FROM media
+WHERE id = $1;
+
+-- name: AttachChirpMedia :exec
+INSERT INTO chirp_media (chirp_id, media_id, position, alt_text)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+);
+
+-- name: GetChirpMedia :many
+SELECT chirp_media.chirp_id, chirp_media.position, chirp_media.alt_text, sqlc.embed(media)
+FROM chirp_media
+JOIN media ON media.id = chirp_media.media_id
+WHERE chirp_media.chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+ORDER BY chirp_media.chirp_id, chirp_media.position;
+
+-- name: DetachChirpMedia :exec
+-- Removes a chirp's attachments, leaving the uploads themselves.
+DELETE FROM chirp_media
+WHERE chirp_id = $1;
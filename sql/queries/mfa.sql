@@ -0,0 +1,41 @@
+-- name: CreateRecoveryCode :exec
+INSERT INTO mfa_recovery_codes (code_hash, user_id, created_at)
+VALUES ($1, $2, NOW());
+
+-- name: DeleteUserRecoveryCodes :exec
+DELETE FROM mfa_recovery_codes
+WHERE user_id = $1;
+
+-- name: UseRecoveryCode :execrows
+-- Marks one of the user's unused codes used. A code can only be used
+-- once, even by concurrent requests.
+UPDATE mfa_recovery_codes
+SET used_at = NOW()
+WHERE user_id = $1
+AND code_hash = $2
+AND used_at IS NULL;
+
+-- name: CreateMFAChallenge :one
+INSERT INTO mfa_challenges (token_hash, user_id, created_at, expires_at)
+VALUES ($1, $2, NOW(), $3)
+RETURNING *;
+
+-- name: GetMFAChallenge :one
+-- Returns a challenge that is unused, unexpired and has had fewer than
+-- the given number of wrong codes.
+SELECT * FROM mfa_challenges
+WHERE token_hash = $1
+AND used_at IS NULL
+AND expires_at > NOW()
+AND failed_attempts < $2;
+
+-- name: RecordMFAChallengeFailure :exec
+UPDATE mfa_challenges
+SET failed_attempts = failed_attempts + 1
+WHERE token_hash = $1;
+
+-- name: UseMFAChallenge :execrows
+UPDATE mfa_challenges
+SET used_at = NOW()
+WHERE token_hash = $1
+AND used_at IS NULL;
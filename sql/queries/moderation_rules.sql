@@ -0,0 +1,33 @@
+-- name: CreateModerationRule :one
+INSERT INTO moderation_rules (id, created_at, updated_at, kind, patterns, action, reason, enabled)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+RETURNING *;
+
+-- name: ListModerationRules :many
+SELECT *
+FROM moderation_rules
+ORDER BY created_at, id;
+
+-- name: GetModerationRule :one
+SELECT *
+FROM moderation_rules
+WHERE id = $1;
+
+-- name: UpdateModerationRule :one
+UPDATE moderation_rules
+SET kind = $2, patterns = $3, action = $4, reason = $5, enabled = $6, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteModerationRule :exec
+DELETE FROM moderation_rules
+WHERE id = $1;
@@ -0,0 +1,67 @@
+-- name: CreateReport :one
+INSERT INTO reports (id, created_at, reporter_id, chirp_id, user_id, category, details)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    sqlc.narg('reporter_id'),
+    sqlc.narg('chirp_id'),
+    sqlc.arg('user_id'),
+    sqlc.arg('category'),
+    sqlc.arg('details')
+)
+RETURNING *;
+
+-- name: GetReport :one
+SELECT *
+FROM reports
+WHERE id = $1;
+
+-- name: ListOpenReportGroups :many
+-- Open reports grouped by what they report, longest waiting first. A
+-- group is keyset paginated on its first report.
+SELECT
+    reports.user_id,
+    reports.chirp_id,
+    COUNT(*)::int AS report_count,
+    COUNT(DISTINCT reports.reporter_id)::int AS reporter_count,
+    MIN(reports.created_at)::timestamp AS first_reported_at,
+    MAX(reports.created_at)::timestamp AS last_reported_at,
+    (array_agg(reports.id ORDER BY reports.created_at, reports.id))[1]::uuid AS first_report_id
+FROM reports
+WHERE reports.resolution_id IS NULL
+GROUP BY reports.user_id, reports.chirp_id
+HAVING sqlc.narg('after_reported_at')::timestamp IS NULL
+OR (
+    MIN(reports.created_at),
+    (array_agg(reports.id ORDER BY reports.created_at, reports.id))[1]
+) > (sqlc.narg('after_reported_at')::timestamp, sqlc.narg('after_id')::uuid)
+ORDER BY first_reported_at, first_report_id
+LIMIT $1;
+
+-- name: ListOpenReportsByUsers :many
+SELECT *
+FROM reports
+WHERE resolution_id IS NULL
+AND user_id = ANY(sqlc.arg('user_ids')::uuid[])
+ORDER BY created_at, id;
+
+-- name: CreateReportResolution :one
+INSERT INTO report_resolutions (id, created_at, action, note, chirp_id, user_id, resolved_by)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    sqlc.arg('action'),
+    sqlc.arg('note'),
+    sqlc.narg('chirp_id'),
+    sqlc.arg('user_id'),
+    sqlc.narg('resolved_by')
+)
+RETURNING *;
+
+-- name: ResolveReports :exec
+-- Closes every open report in a group.
+UPDATE reports
+SET resolution_id = sqlc.arg('resolution_id')::uuid
+WHERE resolution_id IS NULL
+AND user_id = sqlc.arg('user_id')
+AND chirp_id IS NOT DISTINCT FROM sqlc.narg('chirp_id')::uuid;
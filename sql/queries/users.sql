@@ -1,11 +1,12 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3
 )
 RETURNING *;
 
@@ -19,8 +20,9 @@ DELETE FROM users;
 
 -- name: UpdateLoginDetailsByID :one
 UPDATE users
-SET hashed_password = $1, email=$2, updated_at = NOW()
-WHERE id = $3
+SET hashed_password = sqlc.arg('hashed_password'), email = sqlc.arg('email'),
+    handle = COALESCE(sqlc.narg('handle'), handle), updated_at = NOW()
+WHERE id = sqlc.arg('id')
 RETURNING *;
 
 
@@ -29,8 +31,133 @@ SELECT *
 FROM users
 WHERE id = $1;
 
+-- name: GetUsersByHandles :many
+SELECT *
+FROM users
+WHERE handle = ANY(sqlc.arg('handles')::text[]);
+
 -- name: UpgradeChirpyRedByID :one
 UPDATE users
 SET is_chirpy_red = TRUE
 WHERE id = $1
-RETURNING *;
\ No newline at end of file
+RETURNING *;
+
+-- name: SuspendUser :one
+-- Suspends a user until suspended_until, or for good when it is NULL.
+UPDATE users
+SET suspended_at = NOW(),
+    suspended_until = sqlc.narg('suspended_until'),
+    suspension_reason = sqlc.arg('suspension_reason'),
+    suspension_hides_chirps = sqlc.arg('suspension_hides_chirps'),
+    updated_at = NOW()
+WHERE id = sqlc.arg('id')
+RETURNING *;
+
+-- name: LiftSuspension :one
+UPDATE users
+SET suspended_at = NULL,
+    suspended_until = NULL,
+    suspension_reason = '',
+    suspension_hides_chirps = FALSE,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SetUserRole :one
+UPDATE users
+SET role = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: CountAdmins :one
+SELECT COUNT(*)
+FROM users
+WHERE role = 'admin';
+
+-- name: GetTokensValidAfter :one
+SELECT tokens_valid_after
+FROM users
+WHERE id = $1;
+
+-- name: RevokeUserAccessTokens :exec
+-- The cutoff comes from the server's clock, which also stamps the
+-- tokens it is compared with.
+UPDATE users
+SET tokens_valid_after = $2
+WHERE id = $1;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET hashed_password = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: SetPendingEmail :one
+UPDATE users
+SET pending_email = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: VerifyUserEmail :one
+-- Verifies the account's current email, if it is still the one the
+-- token was sent to.
+UPDATE users
+SET verified_at = NOW(), updated_at = NOW()
+WHERE id = $1
+AND email = $2
+RETURNING *;
+
+-- name: ApplyPendingEmail :one
+-- Replaces the account's email with the pending one the token was sent
+-- to, which is verified by that.
+UPDATE users
+SET email = pending_email, pending_email = NULL, verified_at = NOW(), updated_at = NOW()
+WHERE id = $1
+AND pending_email = $2
+RETURNING *;
+
+-- name: StartTOTPEnrollment :one
+-- Stores a new secret for a user who hasn't enabled TOTP yet, replacing
+-- the one from an enrollment they didn't finish.
+UPDATE users
+SET totp_secret = $2, totp_last_step = NULL, updated_at = NOW()
+WHERE id = $1
+AND totp_enabled_at IS NULL
+RETURNING *;
+
+-- name: EnableTOTP :one
+UPDATE users
+SET totp_enabled_at = NOW(), totp_last_step = $2, updated_at = NOW()
+WHERE id = $1
+AND totp_secret IS NOT NULL
+AND totp_enabled_at IS NULL
+RETURNING *;
+
+-- name: DisableTOTP :exec
+UPDATE users
+SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL, updated_at = NOW()
+WHERE id = $1;
+
+-- name: UseTOTPStep :execrows
+-- Records the time step of an accepted code. Nothing is updated if a
+-- code from that step or a later one was already used.
+UPDATE users
+SET totp_last_step = $2
+WHERE id = $1
+AND totp_enabled_at IS NOT NULL
+AND (totp_last_step IS NULL OR totp_last_step < $2);
+
+-- name: RecordMFAFailure :exec
+-- Counts a wrong second factor. Once the count reaches max_failures,
+-- each wrong one locks the user's second factor until locked_until.
+UPDATE users
+SET mfa_failed_attempts = mfa_failed_attempts + 1,
+    mfa_locked_until = CASE
+        WHEN mfa_failed_attempts + 1 >= sqlc.arg('max_failures')::integer THEN sqlc.arg('locked_until')::timestamp
+        ELSE mfa_locked_until
+    END
+WHERE id = sqlc.arg('id');
+
+-- name: ResetMFAFailures :exec
+UPDATE users
+SET mfa_failed_attempts = 0, mfa_locked_until = NULL
+WHERE id = $1;
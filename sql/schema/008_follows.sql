@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE follows (
+    follower_id UUID NOT NULL,
+    followee_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (follower_id, followee_id),
+    CONSTRAINT fk_follower_id
+        FOREIGN KEY (follower_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_followee_id
+        FOREIGN KEY (followee_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE,
+    CONSTRAINT chk_no_self_follow
+        CHECK (follower_id <> followee_id)
+);
+
+CREATE INDEX idx_follows_follower_id_created_at ON follows (follower_id, created_at, followee_id);
+CREATE INDEX idx_follows_followee_id_created_at ON follows (followee_id, created_at, follower_id);
+
+-- +goose Down
+DROP TABLE follows;
@@ -0,0 +1,22 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN in_reply_to UUID
+CONSTRAINT fk_in_reply_to
+    REFERENCES chirps(id)
+    ON DELETE SET NULL;
+
+-- A deleted chirp that still has replies keeps its row, with the body
+-- cleared, so the conversation around it stays intact.
+ALTER TABLE chirps
+ADD COLUMN tombstoned_at TIMESTAMP;
+
+CREATE INDEX idx_chirps_in_reply_to ON chirps (in_reply_to, created_at, id);
+
+-- +goose Down
+DROP INDEX idx_chirps_in_reply_to;
+
+ALTER TABLE chirps
+DROP COLUMN tombstoned_at;
+
+ALTER TABLE chirps
+DROP COLUMN in_reply_to;
@@ -0,0 +1,28 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN rechirp_of UUID
+CONSTRAINT fk_rechirp_of
+    REFERENCES chirps(id)
+    ON DELETE CASCADE;
+
+ALTER TABLE chirps
+ADD COLUMN quote_of UUID
+CONSTRAINT fk_quote_of
+    REFERENCES chirps(id)
+    ON DELETE SET NULL;
+
+CREATE UNIQUE INDEX idx_chirps_user_id_rechirp_of ON chirps (user_id, rechirp_of)
+WHERE rechirp_of IS NOT NULL;
+CREATE INDEX idx_chirps_rechirp_of ON chirps (rechirp_of);
+CREATE INDEX idx_chirps_quote_of ON chirps (quote_of);
+
+-- +goose Down
+DROP INDEX idx_chirps_quote_of;
+DROP INDEX idx_chirps_rechirp_of;
+DROP INDEX idx_chirps_user_id_rechirp_of;
+
+ALTER TABLE chirps
+DROP COLUMN quote_of;
+
+ALTER TABLE chirps
+DROP COLUMN rechirp_of;
@@ -0,0 +1,34 @@
+-- +goose Up
+CREATE TABLE media (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    content_type TEXT NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    CONSTRAINT fk_user_id
+        FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+CREATE TABLE chirp_media (
+    chirp_id UUID NOT NULL,
+    media_id UUID NOT NULL,
+    position INTEGER NOT NULL,
+    alt_text TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (chirp_id, position),
+    CONSTRAINT fk_chirp_id
+        FOREIGN KEY (chirp_id)
+        REFERENCES chirps(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_media_id
+        FOREIGN KEY (media_id)
+        REFERENCES media(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX idx_chirp_media_media_id ON chirp_media (media_id);
+
+-- +goose Down
+DROP TABLE chirp_media;
+DROP TABLE media;
@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX idx_chirps_user_id_deleted_at ON chirps (user_id, deleted_at, id)
+WHERE deleted_at IS NOT NULL;
+
+-- +goose Down
+DROP INDEX idx_chirps_user_id_deleted_at;
+ALTER TABLE chirps
+DROP COLUMN deleted_at;
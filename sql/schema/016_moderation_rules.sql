@@ -0,0 +1,26 @@
+-- +goose Up
+CREATE TABLE moderation_rules (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('word', 'phrase', 'regex')),
+    patterns TEXT[] NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('redact', 'reject', 'hold')),
+    reason TEXT NOT NULL DEFAULT '',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE
+);
+
+-- the words validateChirp used to hardcode
+INSERT INTO moderation_rules (id, created_at, updated_at, kind, patterns, action)
+VALUES (gen_random_uuid(), NOW(), NOW(), 'word', ARRAY['kerfuffle', 'sharbert', 'fornax'], 'redact');
+
+ALTER TABLE chirps
+ADD COLUMN held_at TIMESTAMP,
+ADD COLUMN hold_reason TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE chirps
+DROP COLUMN hold_reason,
+DROP COLUMN held_at;
+
+DROP TABLE moderation_rules;
@@ -0,0 +1,40 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN suspended_at TIMESTAMP,
+ADD COLUMN suspension_reason TEXT NOT NULL DEFAULT '';
+
+-- chirp_id has no foreign key so the record outlives a deleted chirp
+CREATE TABLE report_resolutions (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('dismiss', 'delete_chirp', 'suspend_user')),
+    note TEXT NOT NULL DEFAULT '',
+    chirp_id UUID,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- A report is against a user, and against one of their chirps when
+-- chirp_id is set. Reports without a reporter were raised by a hold
+-- rule.
+CREATE TABLE reports (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    reporter_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    chirp_id UUID REFERENCES chirps(id) ON DELETE SET NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    category TEXT NOT NULL CHECK (
+        category IN ('spam', 'harassment', 'hate', 'violence', 'sexual', 'misinformation', 'other', 'held')
+    ),
+    details TEXT NOT NULL DEFAULT '',
+    resolution_id UUID REFERENCES report_resolutions(id) ON DELETE SET NULL
+);
+
+CREATE INDEX reports_open_idx ON reports (user_id, chirp_id) WHERE resolution_id IS NULL;
+
+-- +goose Down
+DROP TABLE reports;
+DROP TABLE report_resolutions;
+
+ALTER TABLE users
+DROP COLUMN suspension_reason,
+DROP COLUMN suspended_at;
@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN role TEXT NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'moderator', 'admin'));
+
+ALTER TABLE report_resolutions
+ADD COLUMN resolved_by UUID REFERENCES users(id) ON DELETE SET NULL;
+
+-- +goose Down
+ALTER TABLE report_resolutions
+DROP COLUMN resolved_by;
+
+ALTER TABLE users
+DROP COLUMN role;
@@ -0,0 +1,10 @@
+-- +goose Up
+-- A suspension with no end date is permanent.
+ALTER TABLE users
+ADD COLUMN suspended_until TIMESTAMP,
+ADD COLUMN suspension_hides_chirps BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN suspension_hides_chirps,
+DROP COLUMN suspended_until;
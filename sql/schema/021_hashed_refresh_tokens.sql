@@ -0,0 +1,18 @@
+-- +goose Up
+-- Refresh tokens are stored as the hex SHA-256 of the token handed to
+-- the client, so a copy of the table can't be used to refresh.
+ALTER TABLE refresh_tokens
+RENAME COLUMN token TO token_hash;
+
+UPDATE refresh_tokens
+SET token_hash = encode(sha256(convert_to(token_hash, 'UTF8')), 'hex'),
+    replaced_by = encode(sha256(convert_to(replaced_by, 'UTF8')), 'hex');
+
+-- +goose Down
+-- Digests can't be turned back into tokens, so every session ends.
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE revoked_at IS NULL;
+
+ALTER TABLE refresh_tokens
+RENAME COLUMN token_hash TO token;
@@ -0,0 +1,25 @@
+-- +goose Up
+-- Accounts from before verification existed count as verified.
+ALTER TABLE users
+ADD COLUMN verified_at TIMESTAMP,
+ADD COLUMN pending_email TEXT;
+
+UPDATE users SET verified_at = created_at;
+
+-- A token verifies the address it was sent to: the account's email, or
+-- the pending email it is changing to.
+CREATE TABLE email_verification_tokens (
+    token_hash TEXT PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    email TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE email_verification_tokens;
+
+ALTER TABLE users
+DROP COLUMN pending_email,
+DROP COLUMN verified_at;
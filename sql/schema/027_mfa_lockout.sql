@@ -0,0 +1,12 @@
+-- +goose Up
+-- mfa_failed_attempts counts wrong second factors since the last right
+-- one, across every login. Past the limit each wrong one sets
+-- mfa_locked_until, and no code is accepted until it passes.
+ALTER TABLE users
+ADD COLUMN mfa_failed_attempts INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN mfa_locked_until TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN mfa_locked_until,
+DROP COLUMN mfa_failed_attempts;